@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contextPackage = protogen.GoImportPath("context")
+	grpcPackage    = protogen.GoImportPath("google.golang.org/grpc")
+	natsPackage    = protogen.GoImportPath("github.com/nats-io/nats.go")
+	rpcPackage     = protogen.GoImportPath("github.com/synternet/data-layer-sdk/pkg/rpc")
+	servicePackage = protogen.GoImportPath("github.com/synternet/data-layer-sdk/pkg/service")
+)
+
+// methodInfo is a method together with its validated subject.
+type methodInfo struct {
+	*protogen.Method
+	tokens [][]rpc.TemplatePart
+	// vars are the subject variables, and params are their Go parameter names
+	vars   []string
+	params []string
+	// bound are the variables bound to the request fields of a unary method, which are not passed as parameters
+	bound map[string]bool
+	pure  bool
+}
+
+// generateFile generates a _datalayer.pb.go file containing Data Layer clients of the services in the file.
+func generateFile(gen *protogen.Plugin, file *protogen.File) error {
+	services := make([][]*methodInfo, len(file.Services))
+	for i, svc := range file.Services {
+		for _, method := range svc.Methods {
+			info, err := newMethodInfo(svc, method)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file.Desc.Path(), err)
+			}
+			services[i] = append(services[i], info)
+		}
+	}
+
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_datalayer.pb.go", file.GoImportPath)
+	g.P("// Code generated by protoc-gen-go-datalayer. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P("// - protoc-gen-go-datalayer v", version)
+	g.P("// - protoc                  ", protocVersion(gen))
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+	for i, svc := range file.Services {
+		generateService(g, svc, services[i])
+	}
+	return nil
+}
+
+func newMethodInfo(svc *protogen.Service, method *protogen.Method) (*methodInfo, error) {
+	tokens, err := methodSubject(svc, method)
+	if err != nil {
+		return nil, err
+	}
+	info := &methodInfo{
+		Method: method,
+		tokens: tokens,
+		pure:   proto.GetExtension(method.Desc.Options(), rpctypes.E_DisableInputs).(bool),
+	}
+	if info.pure && method.Desc.IsStreamingClient() {
+		return nil, fmt.Errorf("%s: disable_inputs cannot be used with client streaming", method.Desc.FullName())
+	}
+	seen := make(map[string]string)
+	for _, tok := range tokens {
+		for _, part := range tok {
+			if part.Variable == "" {
+				continue
+			}
+			param := paramName(part.Variable)
+			if other, ok := seen[param]; ok {
+				return nil, fmt.Errorf("%s: variables %q and %q have the same parameter name %q", method.Desc.FullName(), other, part.Variable, param)
+			}
+			seen[param] = part.Variable
+			info.vars = append(info.vars, part.Variable)
+			info.params = append(info.params, param)
+		}
+	}
+	bound, err := boundVariables(method.Input, info.vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method.Desc.FullName(), err)
+	}
+	if !method.Desc.IsStreamingClient() && !method.Desc.IsStreamingServer() {
+		info.bound = bound
+	}
+	return info, nil
+}
+
+func protocVersion(gen *protogen.Plugin) string {
+	v := gen.Request.GetCompilerVersion()
+	if v == nil {
+		return "(unknown)"
+	}
+	var suffix string
+	if s := v.GetSuffix(); s != "" {
+		suffix = "-" + s
+	}
+	return fmt.Sprintf("v%d.%d.%d%s", v.GetMajor(), v.GetMinor(), v.GetPatch(), suffix)
+}
+
+func generateService(g *protogen.GeneratedFile, svc *protogen.Service, methods []*methodInfo) {
+	g.P("// Subject templates of ", svc.GoName, " methods relative to the publisher prefix. Variables are written as `{name}`.")
+	g.P("const (")
+	for _, m := range methods {
+		g.P(templateName(svc, m), " = ", strconv.Quote(templateString(m.tokens)))
+	}
+	g.P(")")
+	g.P()
+
+	for _, m := range methods {
+		generateSubjectBuilder(g, svc, m)
+	}
+
+	clientName := svc.GoName + "DataLayerClient"
+	g.P("// ", clientName, " is a typed Data Layer client of ", svc.GoName, ". The subject variables are passed explicitly to every call,")
+	g.P("// unless they are bound to the request fields of unary methods with the bind_variable option.")
+	g.P("type ", clientName, " struct {")
+	g.P("conn   *", rpcPackage.Ident("ClientConn"))
+	g.P("pub    ", rpcPackage.Ident("Publisher"))
+	g.P("prefix string")
+	g.P("}")
+	g.P()
+	g.P("// New", clientName, " returns a client of ", svc.GoName, " served by the publisher with the remote prefix.")
+	g.P("func New", clientName, "(ctx ", contextPackage.Ident("Context"), ", pub ", rpcPackage.Ident("Publisher"), ", remotePrefix string, opts ...", rpcPackage.Ident("ClientOption"), ") *", clientName, " {")
+	g.P("return &", clientName, "{conn: ", rpcPackage.Ident("NewClientConn"), "(ctx, pub, remotePrefix, nil, opts...), pub: pub, prefix: remotePrefix}")
+	g.P("}")
+	g.P()
+
+	for _, m := range methods {
+		if m.pure {
+			generateSubscribe(g, svc, clientName, m)
+		} else {
+			generateCall(g, svc, clientName, m)
+		}
+	}
+}
+
+func templateName(svc *protogen.Service, m *methodInfo) string {
+	return fmt.Sprintf("%s_%s_SubjectTemplate", svc.GoName, m.GoName)
+}
+
+func subjectName(svc *protogen.Service, m *methodInfo) string {
+	return fmt.Sprintf("%s_%s_Subject", svc.GoName, m.GoName)
+}
+
+func templateString(tokens [][]rpc.TemplatePart) string {
+	ret := make([]string, len(tokens))
+	for i, tok := range tokens {
+		for _, part := range tok {
+			if part.Variable != "" {
+				ret[i] += "{" + part.Variable + "}"
+			} else {
+				ret[i] += part.Literal
+			}
+		}
+	}
+	return strings.Join(ret, ".")
+}
+
+// varParams returns the parameter list of the subject variables that are not bound to the request, e.g. `id string, `.
+func varParams(m *methodInfo) string {
+	var b strings.Builder
+	for i, p := range m.params {
+		if !m.bound[m.vars[i]] {
+			b.WriteString(p + " string, ")
+		}
+	}
+	return b.String()
+}
+
+// varArgs returns the argument list of the subject variables, e.g. `, id`.
+func varArgs(m *methodInfo) string {
+	var b strings.Builder
+	for _, p := range m.params {
+		b.WriteString(", " + p)
+	}
+	return b.String()
+}
+
+func generateSubjectBuilder(g *protogen.GeneratedFile, svc *protogen.Service, m *methodInfo) {
+	// Consecutive literal tokens are joined into a single argument, and partial tokens are joined with rpc.JoinToken,
+	// e.g. `rpc.JoinToken("block-", id)`, so that `*` values turn the whole token into a wildcard
+	var args []string
+	var literal []string
+	param := 0
+	for _, tok := range m.tokens {
+		if len(tok) == 1 && tok[0].Variable == "" {
+			literal = append(literal, tok[0].Literal)
+			continue
+		}
+		if len(literal) != 0 {
+			args = append(args, strconv.Quote(strings.Join(literal, ".")))
+			literal = nil
+		}
+		var parts []string
+		for _, part := range tok {
+			if part.Variable == "" {
+				parts = append(parts, strconv.Quote(part.Literal))
+				continue
+			}
+			parts = append(parts, m.params[param])
+			param++
+		}
+		if len(parts) == 1 {
+			args = append(args, parts[0])
+			continue
+		}
+		args = append(args, g.QualifiedGoIdent(rpcPackage.Ident("JoinToken"))+"("+strings.Join(parts, ", ")+")")
+	}
+	if len(literal) != 0 {
+		args = append(args, strconv.Quote(strings.Join(literal, ".")))
+	}
+
+	name := subjectName(svc, m)
+	g.P("// ", name, " returns the subject of ", svc.GoName, ".", m.GoName, " served by the publisher with the prefix.")
+	g.P("func ", name, "(prefix string", varArgsParams(m), ") string {")
+	g.P("return ", rpcPackage.Ident("JoinSubject"), "(prefix, ", strings.Join(args, ", "), ")")
+	g.P("}")
+	g.P()
+}
+
+// varArgsParams returns the parameter list of the subject variables following other parameters, e.g. `, id string`.
+func varArgsParams(m *methodInfo) string {
+	var b strings.Builder
+	for _, p := range m.params {
+		b.WriteString(", " + p + " string")
+	}
+	return b.String()
+}
+
+// conn returns the expression of the connection with the subject variables of the method that are not bound to the request.
+func conn(m *methodInfo) string {
+	var vars []string
+	for i, v := range m.vars {
+		if !m.bound[v] {
+			vars = append(vars, fmt.Sprintf("%q: %s", v, m.params[i]))
+		}
+	}
+	if len(vars) == 0 {
+		return "c.conn"
+	}
+	return fmt.Sprintf("c.conn.WithVars(map[string]string{%s})", strings.Join(vars, ", "))
+}
+
+func generateCall(g *protogen.GeneratedFile, svc *protogen.Service, clientName string, m *methodInfo) {
+	grpcClient := "New" + svc.GoName + "Client"
+	ctx := "ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context")) + ", "
+	callOpts := "opts ..." + g.QualifiedGoIdent(grpcPackage.Ident("CallOption"))
+	in := "in *" + g.QualifiedGoIdent(m.Input.GoIdent) + ", "
+	streamClient := svc.GoName + "_" + m.GoName + "Client"
+
+	leadingComments(g, m)
+	switch {
+	case m.Desc.IsStreamingClient():
+		g.P("func (c *", clientName, ") ", m.GoName, "(", ctx, varParams(m), callOpts, ") (", streamClient, ", error) {")
+		g.P("return ", grpcClient, "(", conn(m), ").", m.GoName, "(ctx, opts...)")
+	case m.Desc.IsStreamingServer():
+		g.P("func (c *", clientName, ") ", m.GoName, "(", ctx, varParams(m), in, callOpts, ") (", streamClient, ", error) {")
+		g.P("return ", grpcClient, "(", conn(m), ").", m.GoName, "(ctx, in, opts...)")
+	default:
+		g.P("func (c *", clientName, ") ", m.GoName, "(", ctx, varParams(m), in, callOpts, ") (*", m.Output.GoIdent, ", error) {")
+		g.P("return ", grpcClient, "(", conn(m), ").", m.GoName, "(ctx, in, opts...)")
+	}
+	g.P("}")
+	g.P()
+}
+
+func generateSubscribe(g *protogen.GeneratedFile, svc *protogen.Service, clientName string, m *methodInfo) {
+	g.P("// Subscribe", m.GoName, " subscribes to the pure stream of ", svc.GoName, ".", m.GoName, ".")
+	g.P("// The handler is called with every message, or with the error if the message could not be decoded.")
+	g.P("// The interest probes of the producer are dropped.")
+	if len(m.params) != 0 {
+		g.P("// Pass `*` to subscribe to all values of a variable.")
+	}
+	g.P("func (c *", clientName, ") Subscribe", m.GoName, "(", varParams(m), "handler func(*", m.Output.GoIdent, ", error)) (*", natsPackage.Ident("Subscription"), ", error) {")
+	g.P("return c.pub.SubscribeTo(func(msg ", servicePackage.Ident("Message"), ") {")
+	g.P("if ", rpcPackage.Ident("IsInterestProbe"), "(msg) {")
+	g.P("return")
+	g.P("}")
+	g.P("out := new(", m.Output.GoIdent, ")")
+	g.P("_, err := c.pub.Unmarshal(msg, out)")
+	g.P("handler(out, err)")
+	g.P("}, ", subjectName(svc, m), "(c.prefix", varArgs(m), "))")
+	g.P("}")
+	g.P()
+}
+
+// leadingComments copies the method comments, or describes the method if it has none.
+func leadingComments(g *protogen.GeneratedFile, m *methodInfo) {
+	if comments := m.Comments.Leading.String(); comments != "" {
+		g.P(strings.TrimSuffix(comments, "\n"))
+		return
+	}
+	g.P("// ", m.GoName, " calls ", m.Desc.FullName(), ".")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// generate runs the plugin on test_service.proto, modified by the function.
+func generate(t *testing.T, modify func(fd *descriptorpb.FileDescriptorProto)) *pluginpb.CodeGeneratorResponse {
+	testService := protodesc.ToFileDescriptorProto(rpctypes.File_synternet_rpc_test_service_proto)
+	if modify != nil {
+		modify(testService)
+	}
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{testService.GetName()},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			protodesc.ToFileDescriptorProto(rpctypes.File_synternet_rpc_options_proto),
+			protodesc.ToFileDescriptorProto(emptypb.File_google_protobuf_empty_proto),
+			testService,
+		},
+	}
+	gen, err := protogen.Options{}.New(req)
+	require.NoError(t, err)
+	for _, f := range gen.Files {
+		if !f.Generate {
+			continue
+		}
+		if err := generateFile(gen, f); err != nil {
+			gen.Error(err)
+		}
+	}
+	return gen.Response()
+}
+
+func TestGenerate(t *testing.T) {
+	resp := generate(t, nil)
+	require.Empty(t, resp.GetError())
+	require.Len(t, resp.GetFile(), 1)
+	file := resp.GetFile()[0]
+	assert.Equal(t, "github.com/synternet/data-layer-sdk/x/synternet/rpc/test_service_datalayer.pb.go", file.GetName())
+
+	_, err := parser.ParseFile(token.NewFileSet(), file.GetName(), file.GetContent(), parser.AllErrors)
+	require.NoError(t, err, file.GetContent())
+
+	for _, want := range []string{
+		`TestService_TestVars_SubjectTemplate                = "override.test.override.test.method.{variable}"`,
+		`func TestService_TestVars_Subject(prefix string, variable string) string {`,
+		`return rpc.JoinSubject(prefix, "override.test.override.test.method", variable)`,
+		`func NewTestServiceDataLayerClient(ctx context.Context, pub rpc.Publisher, remotePrefix string, opts ...rpc.ClientOption) *TestServiceDataLayerClient {`,
+		`func (c *TestServiceDataLayerClient) Test(ctx context.Context, in *TestRequest, opts ...grpc.CallOption) (*TestResponse, error) {`,
+		// The variable is bound to the request field
+		`func (c *TestServiceDataLayerClient) TestVars(ctx context.Context, in *TestRequest, opts ...grpc.CallOption) (*TestResponse, error) {`,
+		`return NewTestServiceClient(c.conn).TestVars(ctx, in, opts...)`,
+		`func (c *TestServiceDataLayerClient) TestStream(ctx context.Context, in *TestRequest, opts ...grpc.CallOption) (TestService_TestStreamClient, error) {`,
+		`func (c *TestServiceDataLayerClient) TestStreamBidirectional(ctx context.Context, opts ...grpc.CallOption) (TestService_TestStreamBidirectionalClient, error) {`,
+		`func (c *TestServiceDataLayerClient) SubscribeTestStreamOnly(handler func(*TestResponse, error)) (*nats_go.Subscription, error) {`,
+		`if rpc.IsInterestProbe(msg) {`,
+	} {
+		assert.Contains(t, file.GetContent(), want)
+	}
+}
+
+func TestGeneratePartialTokens(t *testing.T) {
+	resp := generate(t, func(fd *descriptorpb.FileDescriptorProto) {
+		proto.SetExtension(fd.GetService()[0].GetMethod()[0].GetOptions(), rpctypes.E_SubjectSuffix, "block-{id}.{a}-{b}.x")
+	})
+	require.Empty(t, resp.GetError())
+	content := resp.GetFile()[0].GetContent()
+	assert.Contains(t, content, `TestService_Test_SubjectTemplate                    = "override.test.block-{id}.{a}-{b}.x"`)
+	assert.Contains(t, content, `func TestService_Test_Subject(prefix string, id string, a string, b string) string {`)
+	assert.Contains(t, content, `return rpc.JoinSubject(prefix, "override.test", rpc.JoinToken("block-", id), rpc.JoinToken(a, "-", b), "x")`)
+	assert.Contains(t, content, `func (c *TestServiceDataLayerClient) Test(ctx context.Context, id string, a string, b string, in *TestRequest, opts ...grpc.CallOption) (*TestResponse, error) {`)
+	assert.Contains(t, content, `c.conn.WithVars(map[string]string{"id": id, "a": a, "b": b})`)
+}
+
+func TestGenerateValidation(t *testing.T) {
+	setSuffix := func(suffix string) func(fd *descriptorpb.FileDescriptorProto) {
+		return func(fd *descriptorpb.FileDescriptorProto) {
+			proto.SetExtension(fd.GetService()[0].GetMethod()[0].GetOptions(), rpctypes.E_SubjectSuffix, suffix)
+		}
+	}
+	tests := []struct {
+		name    string
+		modify  func(fd *descriptorpb.FileDescriptorProto)
+		wantErr string
+	}{
+		{"empty token", setSuffix("a..b"), `synternet.rpc.TestService.Test: subject_suffix: subject template "a..b": empty token`},
+		{"wildcard", setSuffix("a.*"), `token "*" contains wildcards`},
+		{"full wildcard", setSuffix("a.>"), `token ">" contains wildcards`},
+		{"unclosed variable", setSuffix("a.{id"), `token "{id" has unmatched '{'`},
+		{"unopened variable", setSuffix("a.id}"), `token "id}" has unmatched '}'`},
+		{"invalid variable name", setSuffix("a.{1d}"), `token "{1d}" has invalid variable name "1d"`},
+		{"whitespace", setSuffix("a.b c"), `token "b c" contains whitespace`},
+		{"repeated variable", setSuffix("{id}.{id}"), `variable "id" is used more than once`},
+		{"parameter name collision", setSuffix("{block_id}.{blockId}"), `variables "block_id" and "blockId" have the same parameter name "blockId"`},
+		{"invalid prefix", func(fd *descriptorpb.FileDescriptorProto) {
+			proto.SetExtension(fd.GetService()[0].GetOptions(), rpctypes.E_SubjectPrefix, "override.test.")
+		}, `synternet.rpc.TestService: subject_prefix: subject template "override.test.": empty token`},
+		{"bound float field", func(fd *descriptorpb.FileDescriptorProto) {
+			field := fd.GetMessageType()[0].GetField()[0]
+			require.Equal(t, "a", field.GetName())
+			field.Options = &descriptorpb.FieldOptions{}
+			proto.SetExtension(field.GetOptions(), rpctypes.E_BindVariable, "variable")
+		}, `field synternet.rpc.TestRequest.a: cannot bind float field to variable variable`},
+		{"pure client stream", func(fd *descriptorpb.FileDescriptorProto) {
+			method := fd.GetService()[0].GetMethod()[4]
+			require.Equal(t, "TestStreamBidirectional", method.GetName())
+			proto.SetExtension(method.GetOptions(), rpctypes.E_DisableInputs, true)
+		}, `disable_inputs cannot be used with client streaming`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := generate(t, tt.modify)
+			assert.Contains(t, resp.GetError(), "synternet/rpc/test_service.proto: ")
+			assert.Contains(t, resp.GetError(), tt.wantErr)
+		})
+	}
+}
+
+func TestParamName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"id", "id"},
+		{"ID", "id"},
+		{"block_id", "blockId"},
+		{"chainId", "chainId"},
+		{"_private", "private"},
+		{"type", "typeVar"},
+		{"ctx", "ctxVar"},
+		{"_1", "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, paramName(tt.name))
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// protoc-gen-go-datalayer is a protoc plugin that generates typed Data Layer clients for services.
+// It complements protoc-gen-go and protoc-gen-go-grpc output, and must be generated into the same package.
+//
+// For every service it generates:
+//
+//   - subject template constants and builder functions with explicit variable parameters;
+//   - a typed client, which takes the subject variables as parameters of every call;
+//   - typed subscribe helpers for pure streams, i.e. methods with `disable_inputs` option.
+//
+// Subject templates set with `subject_prefix` and `subject_suffix` options are validated during generation,
+// so that malformed subjects and variables are reported by protoc instead of failing at runtime.
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const version = "0.1.0"
+
+func main() {
+	showVersion := flag.Bool("version", false, "Print the version and exit.")
+	flag.Parse()
+	if *showVersion {
+		fmt.Printf("protoc-gen-go-datalayer %v\n", version)
+		return
+	}
+
+	protogen.Options{}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		for _, f := range gen.Files {
+			if !f.Generate || len(f.Services) == 0 {
+				continue
+			}
+			if err := generateFile(gen, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
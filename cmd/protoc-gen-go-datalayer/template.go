@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"slices"
+	"strings"
+
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+)
+
+// validateOption checks the subject template set with the option.
+func validateOption(option, value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := rpc.ParseSubjectTemplate(value); err != nil {
+		return fmt.Errorf("%s: %w", option, err)
+	}
+	return nil
+}
+
+// methodSubject validates the subject options of the method and returns the tokens of its subject template.
+func methodSubject(svc *protogen.Service, method *protogen.Method) ([][]rpc.TemplatePart, error) {
+	prefix := proto.GetExtension(svc.Desc.Options(), rpctypes.E_SubjectPrefix).(string)
+	if err := validateOption("subject_prefix", prefix); err != nil {
+		return nil, fmt.Errorf("%s: %w", svc.Desc.FullName(), err)
+	}
+	suffix := proto.GetExtension(method.Desc.Options(), rpctypes.E_SubjectSuffix).(string)
+	if err := validateOption("subject_suffix", suffix); err != nil {
+		return nil, fmt.Errorf("%s: %w", method.Desc.FullName(), err)
+	}
+
+	tokens, err := rpc.ParseSubjectTemplate(rpc.SubjectTemplate(svc.Desc, method.Desc))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method.Desc.FullName(), err)
+	}
+	seen := make(map[string]bool)
+	for _, tok := range tokens {
+		for _, part := range tok {
+			if part.Variable == "" {
+				continue
+			}
+			if seen[part.Variable] {
+				return nil, fmt.Errorf("%s: variable %q is used more than once", method.Desc.FullName(), part.Variable)
+			}
+			seen[part.Variable] = true
+		}
+	}
+	return tokens, nil
+}
+
+// boundVariables validates the request fields bound to subject variables with the bind_variable option,
+// and returns the bound variables that are used in the subject.
+func boundVariables(input *protogen.Message, vars []string) (map[string]bool, error) {
+	ret := make(map[string]bool)
+	for _, field := range input.Fields {
+		name, err := rpc.BoundVariable(field.Desc)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" && slices.Contains(vars, name) {
+			ret[name] = true
+		}
+	}
+	return ret, nil
+}
+
+// reservedParams are the parameter names used by the generated methods.
+var reservedParams = map[string]bool{"c": true, "ctx": true, "in": true, "opts": true, "handler": true, "prefix": true}
+
+// paramName converts a subject variable into a Go parameter name, e.g. `block_id` into `blockId`.
+func paramName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		switch {
+		case b.Len() > 0:
+			part = strings.ToUpper(part[:1]) + part[1:]
+		case part == strings.ToUpper(part):
+			part = strings.ToLower(part)
+		default:
+			part = strings.ToLower(part[:1]) + part[1:]
+		}
+		b.WriteString(part)
+	}
+	ret := b.String()
+	if ret == "" || ret[0] >= '0' && ret[0] <= '9' {
+		ret = "v" + ret
+	}
+	if token.IsKeyword(ret) || reservedParams[ret] {
+		ret += "Var"
+	}
+	return ret
+}
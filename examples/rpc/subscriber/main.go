@@ -11,7 +11,6 @@ import (
 	v1 "github.com/synternet/data-layer-sdk/examples/rpc/types/example/v1"
 	"github.com/synternet/data-layer-sdk/pkg/codec"
 	_ "github.com/synternet/data-layer-sdk/pkg/dotenv"
-	"github.com/synternet/data-layer-sdk/pkg/rpc"
 
 	"github.com/synternet/data-layer-sdk/pkg/options"
 	"github.com/synternet/data-layer-sdk/pkg/service"
@@ -55,16 +54,14 @@ func main() {
 	if err != nil {
 		panic(fmt.Errorf("Failed creating the consumer: %w", err))
 	}
-	users := v1.NewUserServiceClient(rpc.NewClientConn(ctx, &subscriber, *source, nil))
+	users := v1.NewUserServiceDataLayerClient(ctx, &subscriber, *source)
 
-	subscriber.SubscribeTo(func(msg service.Message) {
-		var user v1.RegistrationsResponse
-		_, err := subscriber.Unmarshal(msg, &user)
+	users.SubscribeRegistrations(func(user *v1.RegistrationsResponse, err error) {
 		if err != nil {
 			panic(err)
 		}
-		slog.Info("New user just registered", "user", &user)
-	}, *source, "users.registrations")
+		slog.Info("New user just registered", "user", user)
+	})
 
 	pubCtx := subscriber.Start()
 	defer subscriber.Close()
@@ -0,0 +1,74 @@
+// Code generated by protoc-gen-go-datalayer. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-datalayer v0.1.0
+// - protoc                  (unknown)
+// source: example/v1/example.proto
+
+package v1
+
+import (
+	context "context"
+	nats_go "github.com/nats-io/nats.go"
+	rpc "github.com/synternet/data-layer-sdk/pkg/rpc"
+	service "github.com/synternet/data-layer-sdk/pkg/service"
+	grpc "google.golang.org/grpc"
+)
+
+// Subject templates of UserService methods relative to the publisher prefix. Variables are written as `{name}`.
+const (
+	UserService_Add_SubjectTemplate           = "users.service.add"
+	UserService_Get_SubjectTemplate           = "users.service.get"
+	UserService_Registrations_SubjectTemplate = "users.registrations"
+)
+
+// UserService_Add_Subject returns the subject of UserService.Add served by the publisher with the prefix.
+func UserService_Add_Subject(prefix string) string {
+	return rpc.JoinSubject(prefix, "users.service.add")
+}
+
+// UserService_Get_Subject returns the subject of UserService.Get served by the publisher with the prefix.
+func UserService_Get_Subject(prefix string) string {
+	return rpc.JoinSubject(prefix, "users.service.get")
+}
+
+// UserService_Registrations_Subject returns the subject of UserService.Registrations served by the publisher with the prefix.
+func UserService_Registrations_Subject(prefix string) string {
+	return rpc.JoinSubject(prefix, "users.registrations")
+}
+
+// UserServiceDataLayerClient is a typed Data Layer client of UserService. The subject variables are passed explicitly to every call,
+// unless they are bound to the request fields of unary methods with the bind_variable option.
+type UserServiceDataLayerClient struct {
+	conn   *rpc.ClientConn
+	pub    rpc.Publisher
+	prefix string
+}
+
+// NewUserServiceDataLayerClient returns a client of UserService served by the publisher with the remote prefix.
+func NewUserServiceDataLayerClient(ctx context.Context, pub rpc.Publisher, remotePrefix string, opts ...rpc.ClientOption) *UserServiceDataLayerClient {
+	return &UserServiceDataLayerClient{conn: rpc.NewClientConn(ctx, pub, remotePrefix, nil, opts...), pub: pub, prefix: remotePrefix}
+}
+
+// Add calls example.v1.UserService.Add.
+func (c *UserServiceDataLayerClient) Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*AddResponse, error) {
+	return NewUserServiceClient(c.conn).Add(ctx, in, opts...)
+}
+
+// Get calls example.v1.UserService.Get.
+func (c *UserServiceDataLayerClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	return NewUserServiceClient(c.conn).Get(ctx, in, opts...)
+}
+
+// SubscribeRegistrations subscribes to the pure stream of UserService.Registrations.
+// The handler is called with every message, or with the error if the message could not be decoded.
+// The interest probes of the producer are dropped.
+func (c *UserServiceDataLayerClient) SubscribeRegistrations(handler func(*RegistrationsResponse, error)) (*nats_go.Subscription, error) {
+	return c.pub.SubscribeTo(func(msg service.Message) {
+		if rpc.IsInterestProbe(msg) {
+			return
+		}
+		out := new(RegistrationsResponse)
+		_, err := c.pub.Unmarshal(msg, out)
+		handler(out, err)
+	}, UserService_Registrations_Subject(c.prefix))
+}
@@ -13,6 +13,6 @@ func init() {
 	if err := godotenv.Load(); err == nil {
 		slog.Info(".env file detected.")
 	} else if !errors.Is(err, os.ErrNotExist) {
-		slog.Warn(".env file failed: ", err)
+		slog.Warn(".env file failed", "err", err)
 	}
 }
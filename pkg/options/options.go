@@ -87,6 +87,12 @@ type Options struct {
 	QueueName string
 	// Name of JetStream stream. If an empty string is used, then stream name will be "{prefix}-{name}"
 	StreamName string
+	// Payloads larger than this threshold will be stored in a JetStream object store. Zero disables it.
+	ObjectThreshold int
+	// Name of JetStream object store bucket. If an empty string is used, then bucket name will be "{prefix}-{name}"
+	ObjectBucket string
+	// Maximum age of objects stored in the object store bucket.
+	ObjectTTL time.Duration
 	// Name of the publisher
 	Name string
 	// Codec is used to marshal published messages to whire format.
@@ -0,0 +1,127 @@
+package rpc
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CircuitBreaker configures circuit breakers of unary calls. Every subject has its own circuit breaker.
+//
+// The breaker opens after FailureThreshold consecutive failures, and the calls fail immediately with codes.Unavailable.
+// Once OpenTimeout passes, a single trial call is let through: success closes the breaker, while failure opens it again.
+// Every attempt of a retried or hedged call is counted separately.
+type CircuitBreaker struct {
+	// FailureThreshold is 5 by default.
+	FailureThreshold int
+	// OpenTimeout is 30s by default.
+	OpenTimeout time.Duration
+	// FailureCodes lists the codes counted as failures, codes.Unavailable and codes.DeadlineExceeded by default.
+	// Other errors mean the subject is served, so they count as successes.
+	FailureCodes []codes.Code
+}
+
+// WithCircuitBreaker enables the circuit breakers.
+func WithCircuitBreaker(cfg CircuitBreaker) ClientOption {
+	return func(c *ClientConn) {
+		if cfg.FailureThreshold <= 0 {
+			cfg.FailureThreshold = 5
+		}
+		if cfg.OpenTimeout <= 0 {
+			cfg.OpenTimeout = 30 * time.Second
+		}
+		if len(cfg.FailureCodes) == 0 {
+			cfg.FailureCodes = []codes.Code{codes.Unavailable, codes.DeadlineExceeded}
+		}
+		c.breakers = &circuitBreakers{
+			cfg:      cfg,
+			breakers: make(map[string]*circuitBreaker),
+			now:      time.Now,
+		}
+	}
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type circuitBreaker struct {
+	state    breakerState
+	failures int
+	openedAt time.Time
+	// trial is set while the trial call of a half-open breaker is in flight
+	trial bool
+}
+
+// circuitBreakers keeps circuit breakers per subject.
+type circuitBreakers struct {
+	cfg CircuitBreaker
+	now func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// allow returns an error if the call to the subject must not be made.
+func (b *circuitBreakers) allow(subject string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb, ok := b.breakers[subject]
+	if !ok {
+		return nil
+	}
+	switch cb.state {
+	case breakerOpen:
+		if b.now().Sub(cb.openedAt) < b.cfg.OpenTimeout {
+			return status.Errorf(codes.Unavailable, "circuit breaker is open: %s", subject)
+		}
+		cb.state = breakerHalfOpen
+		cb.trial = true
+	case breakerHalfOpen:
+		if cb.trial {
+			return status.Errorf(codes.Unavailable, "circuit breaker is half-open: %s", subject)
+		}
+		cb.trial = true
+	}
+	return nil
+}
+
+// report records the outcome of the call to the subject.
+func (b *circuitBreakers) report(subject string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb, ok := b.breakers[subject]
+	code := status.Code(err)
+	if code == codes.Canceled {
+		// The caller gave up, so nothing is known about the subject
+		if ok {
+			cb.trial = false
+		}
+		return
+	}
+	if err == nil || !slices.Contains(b.cfg.FailureCodes, code) {
+		// Closed breakers without failures are not kept, since every call may use a new subject
+		delete(b.breakers, subject)
+		return
+	}
+	if !ok {
+		cb = &circuitBreaker{}
+		b.breakers[subject] = cb
+	}
+
+	cb.failures++
+	if cb.state == breakerHalfOpen || cb.failures >= b.cfg.FailureThreshold {
+		cb.state = breakerOpen
+		cb.openedAt = b.now()
+		cb.trial = false
+	}
+}
@@ -0,0 +1,82 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_circuitBreakers(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := &ClientConn{}
+	WithCircuitBreaker(CircuitBreaker{FailureThreshold: 2, OpenTimeout: time.Second})(c)
+	b := c.breakers
+	b.now = func() time.Time { return now }
+
+	unavailable := status.Error(codes.Unavailable, "no responders")
+	const subject = "foo.bar"
+
+	// Errors that are not failures reset the counter
+	assert.NoError(t, b.allow(subject))
+	b.report(subject, unavailable)
+	b.report(subject, status.Error(codes.InvalidArgument, "bad request"))
+	b.report(subject, unavailable)
+	assert.NoError(t, b.allow(subject))
+
+	// Opens after consecutive failures
+	b.report(subject, unavailable)
+	assert.Equal(t, codes.Unavailable, status.Code(b.allow(subject)))
+	assert.NoError(t, b.allow("foo.other"), "breakers are per subject")
+
+	// Half-open lets a single trial through, and failure opens it again
+	now = now.Add(time.Second)
+	assert.NoError(t, b.allow(subject))
+	assert.Error(t, b.allow(subject))
+	b.report(subject, status.Error(codes.DeadlineExceeded, "timeout"))
+	assert.Error(t, b.allow(subject))
+
+	// Cancelled trial releases the slot
+	now = now.Add(time.Second)
+	assert.NoError(t, b.allow(subject))
+	b.report(subject, status.Error(codes.Canceled, "cancelled"))
+	assert.NoError(t, b.allow(subject))
+
+	// Successful trial closes the breaker
+	b.report(subject, nil)
+	assert.NoError(t, b.allow(subject))
+	b.report(subject, errors.New("unknown"))
+	assert.NoError(t, b.allow(subject))
+
+	// Only the subjects with failures are kept
+	assert.Empty(t, b.breakers)
+	b.report("foo.1", nil)
+	b.report("foo.2", status.Error(codes.Canceled, "cancelled"))
+	b.report("foo.3", unavailable)
+	assert.Len(t, b.breakers, 1)
+	b.report("foo.3", nil)
+	assert.Empty(t, b.breakers)
+}
+
+func TestWithCircuitBreakerDefaults(t *testing.T) {
+	c := &ClientConn{}
+	WithCircuitBreaker(CircuitBreaker{})(c)
+	assert.Equal(t, 5, c.breakers.cfg.FailureThreshold)
+	assert.Equal(t, 30*time.Second, c.breakers.cfg.OpenTimeout)
+
+	// A zero-value config does not open on the first failure
+	unavailable := status.Error(codes.Unavailable, "no responders")
+	for i := 0; i < 4; i++ {
+		c.breakers.report("foo.bar", unavailable)
+	}
+	assert.NoError(t, c.breakers.allow("foo.bar"))
+	c.breakers.report("foo.bar", unavailable)
+	assert.Error(t, c.breakers.allow("foo.bar"))
+
+	WithCircuitBreaker(CircuitBreaker{FailureThreshold: -1, OpenTimeout: -time.Second})(c)
+	assert.Equal(t, 5, c.breakers.cfg.FailureThreshold)
+	assert.Equal(t, 30*time.Second, c.breakers.cfg.OpenTimeout)
+}
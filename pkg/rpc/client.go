@@ -2,7 +2,9 @@ package rpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"strings"
 	"sync"
@@ -10,12 +12,14 @@ import (
 
 	"github.com/nats-io/nats.go"
 	service "github.com/synternet/data-layer-sdk/pkg/service"
+	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
-	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 // ClientConn implements grpc.ClientConnInterface for NATS
@@ -24,6 +28,19 @@ type ClientConn struct {
 	sub    Publisher
 	prefix string
 	vars   map[string]string
+
+	unaryInterceptors  []grpc.UnaryClientInterceptor
+	streamInterceptors []grpc.StreamClientInterceptor
+	unaryInterceptor   grpc.UnaryClientInterceptor
+	streamInterceptor  grpc.StreamClientInterceptor
+	// files resolves service descriptors, protoregistry.GlobalFiles by default
+	files *protoregistry.Files
+	// methodConfigs are keyed by full method name, service name or empty name for all services
+	methodConfigs map[string]MethodConfig
+	// breakers is nil unless the circuit breaker is enabled
+	breakers *circuitBreakers
+	// configErr is set if WithMethodConfig got an invalid configuration
+	configErr error
 }
 
 // NewClientConn returns a client connector that functions as a layer between Protobuf Auto-generated gRPC client code
@@ -31,21 +48,51 @@ type ClientConn struct {
 //
 // It automatically derives the correct subjects based on the Protobuf schema, however, you may need to specify the
 // remote prefix that the publisher is configured with. Also, you may need to pass vars. The subjects derived if contain
-// any tokens such as `{id}`, will be substituted by the string stored under key "id". Otherwise such
-// subject token will be replaced with `*`.
+// any tokens such as `{id}`, will be substituted by the string stored under key "id".
+//
+// The variables can also be set per call with the WithVars call option, or taken from the request fields annotated
+// with the `bind_variable` option in unary calls, so that a single connection can address any parametrized subject.
+// Calls fail with VariablesError if a variable is missing. Pure streams are consumed with `*` in place of the missing variables.
 //
-// Naturally such subjects are invalid for a rpc call, so you must always supply variables for a client. Consuming pure streams
-// will work just fine.
-func NewClientConn(ctx context.Context, sub Publisher, remotePrefix string, vars map[string]string) *ClientConn {
-	return &ClientConn{
+// Standard gRPC client interceptors can be installed with WithUnaryClientInterceptors and WithStreamClientInterceptors options.
+// Interceptors receive nil *grpc.ClientConn, since there is no underlying gRPC connection.
+//
+// Unary calls can be retried or hedged using WithMethodConfig or ParseServiceConfig options, and guarded with WithCircuitBreaker.
+// Retries and hedged requests happen below the interceptors, so the interceptors see a single call.
+// The Gather call option sends a unary request to all responders and collects their responses.
+func NewClientConn(ctx context.Context, sub Publisher, remotePrefix string, vars map[string]string, opts ...ClientOption) *ClientConn {
+	ret := &ClientConn{
 		sub:    sub,
 		ctx:    ctx,
 		prefix: remotePrefix,
 		vars:   vars,
+		files:  protoregistry.GlobalFiles,
+	}
+	for _, opt := range opts {
+		opt(ret)
 	}
+	ret.unaryInterceptor = chainUnaryClientInterceptors(ret.unaryInterceptors)
+	ret.streamInterceptor = chainStreamClientInterceptors(ret.streamInterceptors)
+	return ret
 }
 
-func parseServiceMethod(m string) (protoreflect.ServiceDescriptor, protoreflect.MethodDescriptor, error) {
+// WithFiles sets the registry used to resolve service descriptors instead of protoregistry.GlobalFiles.
+// It allows calling services that are not compiled into the client, e.g. resolved with Resolver.
+func WithFiles(files *protoregistry.Files) ClientOption {
+	return func(c *ClientConn) {
+		c.files = files
+	}
+}
+
+// WithVars returns a copy of the connection that substitutes the given variables in the subjects.
+// The copy shares the options and the circuit breakers with the original connection.
+func (c *ClientConn) WithVars(vars map[string]string) *ClientConn {
+	ret := *c
+	ret.vars = vars
+	return &ret
+}
+
+func parseServiceMethod(files *protoregistry.Files, m string) (protoreflect.ServiceDescriptor, protoreflect.MethodDescriptor, error) {
 	tmp := strings.TrimPrefix(m, "/")
 	parts := strings.Split(tmp, "/")
 	if len(parts) != 2 {
@@ -54,48 +101,163 @@ func parseServiceMethod(m string) (protoreflect.ServiceDescriptor, protoreflect.
 	service := protoreflect.FullName(parts[0])
 	method := protoreflect.Name(parts[1])
 
-	svcDescriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(service)
+	svcDescriptor, err := files.FindDescriptorByName(service)
 	if err != nil {
 		return nil, nil, fmt.Errorf("service descriptor: %w", err)
 	}
 
-	svcDesc := svcDescriptor.(protoreflect.ServiceDescriptor)
-	return svcDesc, svcDesc.Methods().ByName(method), nil
+	svcDesc, ok := svcDescriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, nil, fmt.Errorf("not a service: %s", service)
+	}
+	methodDesc := svcDesc.Methods().ByName(method)
+	if methodDesc == nil {
+		return nil, nil, fmt.Errorf("method not found: %s", m)
+	}
+	return svcDesc, methodDesc, nil
 }
 
+// Invoke performs a unary call. Errors are returned as grpc/status errors, preserving the remote codes and details.
 func (c *ClientConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
-	svcDesc, methodDesc, err := parseServiceMethod(method)
+	if c.unaryInterceptor == nil {
+		return c.invoke(ctx, method, args, reply, nil, opts...)
+	}
+	return c.unaryInterceptor(ctx, method, args, reply, nil, c.invoke, opts...)
+}
+
+func (c *ClientConn) invoke(ctx context.Context, method string, args interface{}, reply interface{}, _ *grpc.ClientConn, opts ...grpc.CallOption) error {
+	svcDesc, methodDesc, err := parseServiceMethod(c.files, method)
 	if err != nil {
 		return fmt.Errorf("parse method: %w", err)
 	}
-	tokens := deriveSubject(c.prefix, svcDesc, methodDesc, c.vars)
-	slog.Warn("tokens", "svc", svcDesc.FullName(), "method", methodDesc.FullName(), "vars", c.vars, "tokens", tokens)
-	if tokens == nil {
-		return fmt.Errorf("invalid subject: %s@%s", methodDesc.FullName(), svcDesc.FullName())
+	vars, err := c.callVars(args.(proto.Message), opts)
+	if err != nil {
+		return fmt.Errorf("request variables: %s@%s: %w", methodDesc.FullName(), svcDesc.FullName(), err)
+	}
+	tokens, err := deriveSubject(c.prefix, svcDesc, methodDesc, vars, false)
+	if err != nil {
+		return fmt.Errorf("invalid subject: %s@%s: %w", methodDesc.FullName(), svcDesc.FullName(), err)
 	}
 	slog.Debug("ClientConn.Invoke", "service", svcDesc.FullName(), "method", methodDesc.FullName(), "subject", strings.Join(tokens, "."))
 	if disableSubscription(methodDesc) {
 		return fmt.Errorf("calling disabled: %s@%s", methodDesc.FullName(), svcDesc.FullName())
 	}
-	_, err = c.sub.RequestFrom(ctx, args.(proto.Message), reply.(proto.Message), tokens...)
-	return err
+
+	if c.configErr != nil {
+		return c.configErr
+	}
+	cfg := c.methodConfig(method)
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	if g, ok := gatherOption(opts); ok {
+		return c.gather(ctx, tokens, args.(proto.Message), reply.(proto.Message), g, opts)
+	}
+
+	subject := strings.Join(tokens, ".")
+	call := func(ctx context.Context) (*attemptResult, error) {
+		res := &attemptResult{reply: reply.(proto.Message)}
+		if cfg.HedgingPolicy != nil {
+			// Hedged attempts run concurrently, so each of them decodes into its own message
+			res.reply = res.reply.ProtoReflect().New().Interface()
+		}
+		var err error
+		res.msg, err = c.attempt(ctx, subject, tokens, args.(proto.Message), res.reply)
+		return res, err
+	}
+
+	var res *attemptResult
+	switch {
+	case cfg.HedgingPolicy != nil:
+		res, err = hedge(ctx, cfg.HedgingPolicy, call)
+	case cfg.RetryPolicy != nil:
+		res, err = retry(ctx, cfg.RetryPolicy, call)
+	default:
+		res, err = call(ctx)
+	}
+	if res != nil && res.msg != nil {
+		applyCallOptions(opts, metadataFromHeader(res.msg.Header(), ResponseHeaderPrefix), metadataFromHeader(res.msg.Header(), TrailerHeaderPrefix))
+	}
+	if err != nil {
+		return err
+	}
+	if res.reply != reply {
+		proto.Reset(reply.(proto.Message))
+		proto.Merge(reply.(proto.Message), res.reply)
+	}
+	return nil
+}
+
+// attemptResult is the outcome of a single attempt of a unary call.
+type attemptResult struct {
+	msg   service.Message
+	reply proto.Message
+}
+
+// attempt performs a single request of a unary call and decodes the response into reply.
+// The returned message is set whenever a response was received, even if the response is an error.
+func (c *ClientConn) attempt(ctx context.Context, subject string, tokens []string, args, reply proto.Message) (msg service.Message, err error) {
+	if c.breakers != nil {
+		if err := c.breakers.allow(subject); err != nil {
+			return nil, err
+		}
+		defer func() { c.breakers.report(subject, err) }()
+	}
+
+	msg, err = c.sub.RequestFromWithHeader(ctx, args, nil, outgoingHeader(ctx), tokens...)
+	if _, ok := status.FromError(err); ok && err != nil {
+		return msg, err
+	}
+	switch {
+	case errors.Is(err, nats.ErrNoResponders):
+		return msg, status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, nats.ErrTimeout):
+		return msg, status.Error(codes.DeadlineExceeded, err.Error())
+	case err != nil:
+		return msg, status.FromContextError(err).Err()
+	}
+	if msg.Header().Get(service.ErrorHeader) != "" {
+		var rpcErr rpc.Error
+		if _, err := c.sub.Unmarshal(msg, &rpcErr); err != nil {
+			return msg, status.Errorf(codes.Internal, "error response: %v", err)
+		}
+		return msg, service.StatusFromRpcError(&rpcErr).Err()
+	}
+	if _, err := c.sub.Unmarshal(msg, reply); err != nil {
+		return msg, status.Errorf(codes.Internal, "response: %v", err)
+	}
+	return msg, nil
 }
 
+// NewStream opens a stream. Client-streaming, server-streaming and bidirectional methods run over a streaming session,
+// while pure streams subscribe to the method subject directly.
 func (c *ClientConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-	svcDesc, methodDesc, err := parseServiceMethod(method)
+	if c.streamInterceptor == nil {
+		return c.newStream(ctx, desc, nil, method, opts...)
+	}
+	return c.streamInterceptor(ctx, desc, nil, method, c.newStream, opts...)
+}
+
+func (c *ClientConn) newStream(ctx context.Context, desc *grpc.StreamDesc, _ *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	svcDesc, methodDesc, err := parseServiceMethod(c.files, method)
 	if err != nil {
 		return nil, fmt.Errorf("parse method: %v", err)
 	}
-	tokens := deriveSubject(c.prefix, svcDesc, methodDesc, c.vars)
-	if tokens == nil {
-		return nil, fmt.Errorf("invalid subject: %s@%s", methodDesc.FullName(), svcDesc.FullName())
+	// Stream requests are sent after the subject is derived, so only WithVars options override the connection variables
+	vars, err := c.callVars(nil, opts)
+	if err != nil {
+		return nil, fmt.Errorf("variables: %s@%s: %w", methodDesc.FullName(), svcDesc.FullName(), err)
+	}
+	// Pure streams subscribe to the subject, so missing variables subscribe to all of their values
+	tokens, err := deriveSubject(c.prefix, svcDesc, methodDesc, vars, disableSubscription(methodDesc))
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject: %s@%s: %w", methodDesc.FullName(), svcDesc.FullName(), err)
 	}
 	slog.Debug("ClientConn.NewStream", "service", svcDesc.FullName(), "method", methodDesc.FullName(), "subject", strings.Join(tokens, "."))
-	// if disableSubscription(methodDesc) {
-	// 	return nil, fmt.Errorf("calling disabled: %s@%s", methodDesc.FullName(), svcDesc.FullName())
-	// }
 
-	stream, err := newClientStream(ctx, c.sub, tokens)
+	stream, err := newClientStream(ctx, c.sub, tokens, disableSubscription(methodDesc), opts)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create client stream for %s: %w", strings.Join(tokens, "."), err)
 	}
@@ -105,44 +267,106 @@ func (c *ClientConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, metho
 
 // clientStream implements grpc.ClientStream
 type clientStream struct {
-	ctx          context.Context
-	cancel       context.CancelCauseFunc
-	pub          Publisher
-	tokens       []string
-	replySubject string
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	pub    Publisher
+	tokens []string
+
+	// session is nil for pure streams
+	session *streamSession
+	// ready is closed once the server accepts the session
+	ready chan struct{}
+	// headerReady is closed once the header metadata is received
+	headerReady chan struct{}
+	headerOnce  sync.Once
+	opts        []grpc.CallOption
 
 	mu         sync.Mutex
 	sub        *nats.Subscription
+	header     metadata.MD
+	trailer    metadata.MD
 	recvChan   chan service.Message
 	closedSend atomic.Bool
 	closedRecv atomic.Bool
 	once       sync.Once
 }
 
-func newClientStream(ctx context.Context, pub Publisher, tokens []string) (*clientStream, error) {
+func newClientStream(ctx context.Context, pub Publisher, tokens []string, pure bool, opts []grpc.CallOption) (*clientStream, error) {
+	header := outgoingHeader(ctx)
 	ctx, cancel := context.WithCancelCause(ctx)
 	stream := &clientStream{
-		ctx:          ctx,
-		cancel:       cancel,
-		pub:          pub,
-		tokens:       tokens,
-		replySubject: pub.RpcInbox(),
-		recvChan:     make(chan service.Message, 1000),
+		ctx:         ctx,
+		cancel:      cancel,
+		pub:         pub,
+		tokens:      tokens,
+		ready:       make(chan struct{}),
+		headerReady: make(chan struct{}),
+		opts:        opts,
+		recvChan:    make(chan service.Message, streamRecvBuffer),
+	}
+	if !pure {
+		stream.session = newStreamSession(pub, newStreamId(), strings.Join(tokens, "."))
+	}
+	context.AfterFunc(ctx, stream.abort)
+
+	if pure {
+		return stream, nil
+	}
+
+	if err := stream.subscribe(stream.session.inbox); err != nil {
+		stream.Close()
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+	if err := stream.session.sendWithHeader(frameOpen, nil, header); err != nil {
+		stream.Close()
+		return nil, fmt.Errorf("open frame: %w", err)
 	}
 
 	return stream, nil
 }
 
+// Header returns the header metadata sent by the server. It blocks until the metadata is received.
+// Pure streams have no header metadata.
 func (s *clientStream) Header() (metadata.MD, error) {
-	return nil, nil
+	if s.session == nil {
+		return nil, nil
+	}
+	select {
+	case <-s.ctx.Done():
+		return nil, contextError(s.ctx)
+	case <-s.headerReady:
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.header, nil
 }
 
+// receiveHeader stores the header metadata from the first response frame.
+func (s *clientStream) receiveHeader(msg service.Message) {
+	s.headerOnce.Do(func() {
+		s.mu.Lock()
+		s.header = metadataFromHeader(msg.Header(), ResponseHeaderPrefix)
+		s.mu.Unlock()
+		close(s.headerReady)
+	})
+}
+
+// Trailer returns the trailer metadata sent by the server. It is available only after RecvMsg has returned a non-nil error.
 func (s *clientStream) Trailer() metadata.MD {
-	return nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.trailer
 }
 
+// CloseSend half-closes the stream. The server will receive io.EOF after all the sent messages.
 func (s *clientStream) CloseSend() error {
-	return nil
+	if s.session == nil || s.closedSend.Swap(true) {
+		return nil
+	}
+	if err := s.waitReady(); err != nil {
+		return err
+	}
+	return s.session.send(frameCloseSend, nil)
 }
 
 func (s *clientStream) Context() context.Context {
@@ -157,8 +381,12 @@ func (s *clientStream) subscribe(tokens ...string) error {
 	}
 
 	handler := func(msg service.Message) {
-		s.mu.Lock()
-		defer s.mu.Unlock()
+		if s.session == nil && IsInterestProbe(msg) {
+			return
+		}
+		if s.session != nil && !s.handleFrame(msg) {
+			return
+		}
 		select {
 		case <-s.ctx.Done():
 			return
@@ -170,52 +398,139 @@ func (s *clientStream) subscribe(tokens ...string) error {
 	return err
 }
 
+// handleFrame processes session control frames. It returns true if the frame must be passed to RecvMsg.
+func (s *clientStream) handleFrame(msg service.Message) bool {
+	frame, err := s.session.accept(msg)
+	if errors.Is(err, ErrForeignFrame) {
+		return false
+	}
+	if err != nil {
+		s.cancel(status.Error(codes.DataLoss, err.Error()))
+		return false
+	}
+
+	switch frame {
+	case frameHeader:
+		s.session.setRemote(msg.Reply())
+		close(s.ready)
+		return false
+	case frameMetadata:
+		s.receiveHeader(msg)
+		return false
+	case frameMsg, frameEOS, frameError:
+		s.receiveHeader(msg)
+		return true
+	default:
+		slog.Debug("unexpected stream frame", "frame", frame, "stream_id", s.session.id)
+		return false
+	}
+}
+
+// waitReady waits until the server accepts the session.
+func (s *clientStream) waitReady() error {
+	select {
+	case <-s.ctx.Done():
+		return contextError(s.ctx)
+	case <-s.ready:
+		return nil
+	}
+}
+
+// abort is called once the stream context is done. If the stream has not finished yet, the server is notified
+// with a cancel frame so that it stops producing messages.
+func (s *clientStream) abort() {
+	if s.session != nil && !s.closedRecv.Load() {
+		select {
+		case <-s.ready:
+			header := statusHeader(status.Convert(contextError(s.ctx)), nil)
+			if err := s.session.sendWithHeader(frameCancel, nil, header); err != nil {
+				slog.Debug("sending cancel frame", "err", err, "stream_id", s.session.id)
+			}
+		default:
+		}
+	}
+	s.Close()
+}
+
 func (s *clientStream) SendMsg(m interface{}) error {
 	if s.closedSend.Load() {
 		return fmt.Errorf("send closed")
 	}
 
-	if _, ok := m.(*emptypb.Empty); ok {
+	if s.session == nil {
 		return s.subscribe(s.tokens...)
 	}
 
-	err := s.subscribe(s.replySubject)
-	if err != nil {
-		return fmt.Errorf("subscribe: %w", err)
+	if err := s.waitReady(); err != nil {
+		return err
 	}
-
-	return s.pub.PublishToRpc(m.(proto.Message), s.replySubject, s.tokens...)
+	return s.session.send(frameMsg, m.(proto.Message))
 }
 
+// RecvMsg receives the next message from the server. It returns io.EOF once the server has finished the stream.
 func (s *clientStream) RecvMsg(m interface{}) error {
 	if s.closedRecv.Load() {
-		return fmt.Errorf("recv closed")
+		return io.EOF
+	}
+	// Buffered messages are dropped once the stream is cancelled
+	if s.ctx.Err() != nil {
+		return contextError(s.ctx)
 	}
 
 	select {
 	case <-s.ctx.Done():
-		return s.ctx.Err()
-	case msg, ok := <-s.recvChan:
-		if !ok {
-			return fmt.Errorf("closed")
+		return contextError(s.ctx)
+	case msg := <-s.recvChan:
+		if s.session == nil {
+			_, err := s.pub.Unmarshal(msg, m.(proto.Message))
+			return err
+		}
+
+		switch getFrameType(msg.Header()) {
+		case frameEOS, frameError:
+			return s.finish(msg)
 		}
 		_, err := s.pub.Unmarshal(msg, m.(proto.Message))
 		return err
 	}
 }
 
+// finish processes a termination frame. It returns io.EOF for OK status, and a status error otherwise.
+// The stream is closed, so the reply inbox is unsubscribed without waiting for the context to be done.
+func (s *clientStream) finish(msg service.Message) error {
+	s.closedRecv.Store(true)
+	s.Close()
+
+	st, trailer := statusFromHeader(msg.Header())
+	s.mu.Lock()
+	s.trailer = trailer
+	header := s.header
+	s.mu.Unlock()
+	applyCallOptions(s.opts, header, trailer)
+
+	if getFrameType(msg.Header()) == frameEOS && st.Code() == codes.OK {
+		return io.EOF
+	}
+	var rpcErr rpc.Error
+	if _, err := s.pub.Unmarshal(msg, &rpcErr); err != nil {
+		return status.Errorf(codes.Internal, "error frame: %v", err)
+	}
+	if rpcErr.GetCode() == int32(codes.OK) {
+		rpcErr.Code = int32(st.Code())
+	}
+	return service.StatusFromRpcError(&rpcErr).Err()
+}
+
 func (s *clientStream) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.once.Do(func() {
 		s.closedSend.Store(true)
-		s.closedRecv.Store(true)
 		s.cancel(nil)
 		if s.sub != nil {
 			s.sub.Unsubscribe()
 		}
-		close(s.recvChan)
 	})
 
 	return nil
@@ -0,0 +1,100 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/grpc/status"
+)
+
+// TimeoutHeader carries the remaining time of the client deadline in the same format as the `grpc-timeout` header does,
+// e.g. `100m` for 100 milliseconds. The server derives a per-request context with that deadline.
+const TimeoutHeader = "rpc-timeout"
+
+// timeoutUnits are ordered from the finest to the coarsest unit.
+var timeoutUnits = []struct {
+	unit byte
+	d    time.Duration
+}{
+	{'n', time.Nanosecond},
+	{'u', time.Microsecond},
+	{'m', time.Millisecond},
+	{'S', time.Second},
+	{'M', time.Minute},
+	{'H', time.Hour},
+}
+
+// maxTimeoutValue is the maximum value of the timeout header, which is 8 digits long.
+const maxTimeoutValue = 100_000_000 - 1
+
+// encodeTimeout encodes the duration using the finest unit that fits into 8 digits.
+// Non-positive durations are encoded as the smallest possible timeout.
+func encodeTimeout(d time.Duration) string {
+	if d <= 0 {
+		return "1n"
+	}
+	for _, u := range timeoutUnits {
+		// Round up, so that the server never has a longer deadline than the client
+		v := (d + u.d - 1) / u.d
+		if v <= maxTimeoutValue {
+			return strconv.FormatInt(int64(v), 10) + string(u.unit)
+		}
+	}
+	return strconv.Itoa(maxTimeoutValue) + "H"
+}
+
+// decodeTimeout decodes the timeout header.
+func decodeTimeout(s string) (time.Duration, error) {
+	if len(s) < 2 || len(s) > 9 {
+		return 0, fmt.Errorf("invalid timeout: %q", s)
+	}
+	v, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid timeout: %q", s)
+	}
+	for _, u := range timeoutUnits {
+		if u.unit != s[len(s)-1] {
+			continue
+		}
+		if v > math.MaxInt64/int64(u.d) {
+			return math.MaxInt64, nil
+		}
+		return time.Duration(v) * u.d, nil
+	}
+	return 0, fmt.Errorf("invalid timeout unit: %q", s)
+}
+
+// setTimeoutHeader adds the timeout header if the context has a deadline.
+func setTimeoutHeader(ctx context.Context, header nats.Header) {
+	if deadline, ok := ctx.Deadline(); ok {
+		header.Set(TimeoutHeader, encodeTimeout(time.Until(deadline)))
+	}
+}
+
+// withTimeoutHeader derives a context with the deadline from the timeout header.
+// Missing or invalid headers do not set any deadline.
+func withTimeoutHeader(ctx context.Context, header nats.Header) (context.Context, context.CancelFunc) {
+	v := header.Get(TimeoutHeader)
+	if v == "" {
+		return context.WithCancel(ctx)
+	}
+	timeout, err := decodeTimeout(v)
+	if err != nil {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// contextError returns the cause of the context cancellation as a status error,
+// i.e. codes.Canceled or codes.DeadlineExceeded unless the cause is already a status error.
+func contextError(ctx context.Context) error {
+	err := context.Cause(ctx)
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.FromContextError(err).Err()
+}
@@ -0,0 +1,85 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_encodeTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    string
+	}{
+		{"negative", -time.Second, "1n"},
+		{"zero", 0, "1n"},
+		{"nanoseconds", 123 * time.Nanosecond, "123n"},
+		{"microseconds", 150 * time.Millisecond, "150000u"},
+		{"milliseconds", 5 * time.Minute, "300000m"},
+		{"rounds up", 100*time.Second + time.Nanosecond, "100001m"},
+		{"minutes", 200_000 * time.Hour, "12000000M"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, encodeTimeout(tt.timeout))
+		})
+	}
+}
+
+func Test_decodeTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"nanoseconds", "123n", 123 * time.Nanosecond, false},
+		{"microseconds", "150000u", 150 * time.Millisecond, false},
+		{"milliseconds", "100m", 100 * time.Millisecond, false},
+		{"seconds", "5S", 5 * time.Second, false},
+		{"minutes", "2M", 2 * time.Minute, false},
+		{"hours", "1H", time.Hour, false},
+		{"empty", "", 0, true},
+		{"no value", "m", 0, true},
+		{"too long", "123456789m", 0, true},
+		{"negative", "-1m", 0, true},
+		{"bad unit", "10x", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeTimeout(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_withTimeoutHeader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	header := nats.Header{}
+	setTimeoutHeader(ctx, header)
+	require.NotEmpty(t, header.Get(TimeoutHeader))
+
+	ctx1, cancel1 := withTimeoutHeader(context.Background(), header)
+	defer cancel1()
+	deadline, ok := ctx1.Deadline()
+	require.True(t, ok)
+	expected, _ := ctx.Deadline()
+	assert.WithinDuration(t, expected, deadline, 50*time.Millisecond)
+
+	ctx2, cancel2 := withTimeoutHeader(context.Background(), nats.Header{})
+	defer cancel2()
+	_, ok = ctx2.Deadline()
+	assert.False(t, ok)
+}
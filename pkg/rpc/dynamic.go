@@ -0,0 +1,150 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// DynamicClient calls rpc methods without generated code. Methods are described by protobuf descriptors,
+// requests and responses are JSON encoded. The messages are sent using the codec configured in the Publisher.
+type DynamicClient struct {
+	conn  *ClientConn
+	files *protoregistry.Files
+	types *dynamicpb.Types
+}
+
+// NewDynamicClient returns a client for services registered in protoregistry.GlobalFiles.
+// The remote prefix and vars have the same meaning as in NewClientConn.
+func NewDynamicClient(ctx context.Context, sub Publisher, remotePrefix string, vars map[string]string, opts ...ClientOption) *DynamicClient {
+	return newDynamicClient(ctx, sub, remotePrefix, vars, protoregistry.GlobalFiles, opts)
+}
+
+// NewDynamicClientFromReflection returns a client for services described by the reflection service of the remote publisher.
+func NewDynamicClientFromReflection(ctx context.Context, sub Publisher, remotePrefix string, vars map[string]string, opts ...ClientOption) (*DynamicClient, error) {
+	resp, err := NewResolver(ctx, sub, remotePrefix, opts...).ListServices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reflection: %w", err)
+	}
+	files, err := FilesFromReflection(resp)
+	if err != nil {
+		return nil, err
+	}
+	return newDynamicClient(ctx, sub, remotePrefix, vars, files, opts), nil
+}
+
+func newDynamicClient(ctx context.Context, sub Publisher, remotePrefix string, vars map[string]string, files *protoregistry.Files, opts []ClientOption) *DynamicClient {
+	opts = append(opts[:len(opts):len(opts)], WithFiles(files))
+	return &DynamicClient{
+		conn:  NewClientConn(ctx, sub, remotePrefix, vars, opts...),
+		files: files,
+		types: dynamicpb.NewTypes(files),
+	}
+}
+
+// Method returns the descriptor of the service method.
+func (c *DynamicClient) Method(serviceName, method string) (protoreflect.MethodDescriptor, error) {
+	_, methodDesc, err := parseServiceMethod(c.files, fullMethodName(serviceName, method))
+	return methodDesc, err
+}
+
+// Call performs a unary call. The input is a JSON encoded request, and the output is a JSON encoded response.
+func (c *DynamicClient) Call(ctx context.Context, serviceName, method string, input []byte, opts ...grpc.CallOption) ([]byte, error) {
+	methodDesc, err := c.Method(serviceName, method)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if methodDesc.IsStreamingClient() || methodDesc.IsStreamingServer() {
+		return nil, status.Errorf(codes.InvalidArgument, "streaming method: %s", methodDesc.FullName())
+	}
+	req, err := c.decode(methodDesc.Input(), input)
+	if err != nil {
+		return nil, err
+	}
+	resp := dynamicpb.NewMessage(methodDesc.Output())
+	if err := c.conn.Invoke(ctx, fullMethodName(serviceName, method), req, resp, opts...); err != nil {
+		return nil, err
+	}
+	return c.encode(resp)
+}
+
+// Stream performs a server-streaming call, including pure streams. The input is a JSON encoded request,
+// and handler is called with every JSON encoded response until the stream ends or the handler returns an error.
+// Stream returns nil once the server ends the stream successfully.
+func (c *DynamicClient) Stream(ctx context.Context, serviceName, method string, input []byte, handler func([]byte) error, opts ...grpc.CallOption) error {
+	methodDesc, err := c.Method(serviceName, method)
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if methodDesc.IsStreamingClient() || !methodDesc.IsStreamingServer() {
+		return status.Errorf(codes.InvalidArgument, "not a server-streaming method: %s", methodDesc.FullName())
+	}
+	req, err := c.decode(methodDesc.Input(), input)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	desc := &grpc.StreamDesc{StreamName: string(methodDesc.Name()), ServerStreams: true}
+	stream, err := c.conn.NewStream(ctx, desc, fullMethodName(serviceName, method), opts...)
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for {
+		resp := dynamicpb.NewMessage(methodDesc.Output())
+		err := stream.RecvMsg(resp)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		out, err := c.encode(resp)
+		if err != nil {
+			return err
+		}
+		if err := handler(out); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *DynamicClient) decode(desc protoreflect.MessageDescriptor, input []byte) (*dynamicpb.Message, error) {
+	msg := dynamicpb.NewMessage(desc)
+	if len(input) == 0 {
+		return msg, nil
+	}
+	if err := (protojson.UnmarshalOptions{Resolver: c.types}).Unmarshal(input, msg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "request %s: %v", desc.FullName(), err)
+	}
+	return msg, nil
+}
+
+func (c *DynamicClient) encode(msg *dynamicpb.Message) ([]byte, error) {
+	out, err := (protojson.MarshalOptions{Resolver: c.types}).Marshal(msg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "response %s: %v", msg.Descriptor().FullName(), err)
+	}
+	return out, nil
+}
+
+func fullMethodName(serviceName, method string) string {
+	return fmt.Sprintf("/%s/%s", serviceName, method)
+}
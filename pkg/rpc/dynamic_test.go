@@ -0,0 +1,113 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type dynamicResponse struct {
+	Ab      float32 `json:"ab"`
+	Subject string  `json:"subject"`
+}
+
+func TestDynamicClientCall(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil)
+	client := rpc.NewDynamicClient(ctx, sub, "test_prefix", nil)
+
+	out, err := client.Call(ctx, "synternet.rpc.TestService", "Test", []byte(`{"a": 123, "b": 321}`))
+	require.NoError(t, err)
+	var resp dynamicResponse
+	require.NoError(t, json.Unmarshal(out, &resp))
+	assert.Equal(t, float32(123.0+321.0), resp.Ab)
+	assert.Equal(t, "test_prefix.override.test.override.test.method", resp.Subject)
+
+	_, err = client.Call(ctx, "synternet.rpc.TestService", "Test", []byte(`{"a": 1, "b": -1}`))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Call(ctx, "synternet.rpc.TestService", "Test", []byte(`{"c": 1}`))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Call(ctx, "synternet.rpc.TestService", "Missing", nil)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Call(ctx, "synternet.rpc.TestService", "TestStream", nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestDynamicClientStream(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil)
+	client := rpc.NewDynamicClient(ctx, sub, "test_prefix", nil)
+
+	errStop := errors.New("stop")
+	tests := []struct {
+		method string
+		input  []byte
+		factor float32
+	}{
+		{"TestStream", []byte(`{"a": 123, "b": 321}`), 123.0 + 321.0},
+		{"TestStreamOnly", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			var received []float32
+			err := client.Stream(ctx, "synternet.rpc.TestService", tt.method, tt.input, func(out []byte) error {
+				var resp dynamicResponse
+				require.NoError(t, json.Unmarshal(out, &resp))
+				received = append(received, resp.Ab)
+				if len(received) == 5 {
+					return errStop
+				}
+				return nil
+			})
+			assert.ErrorIs(t, err, errStop)
+			require.Len(t, received, 5)
+			// Pure stream might have been running already, so only the consecutive values are checked
+			for i := 1; i < len(received); i++ {
+				assert.Equal(t, tt.factor, received[i]-received[i-1])
+			}
+		})
+	}
+
+	err := client.Stream(ctx, "synternet.rpc.TestService", "Test", nil, func([]byte) error { return nil })
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestDynamicClientFromReflection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, map[string]string{"variable": "123456"})
+	client, err := rpc.NewDynamicClientFromReflection(ctx, sub, "test_prefix", map[string]string{"variable": "123456"})
+	require.NoError(t, err)
+
+	out, err := client.Call(ctx, "synternet.rpc.TestService", "TestVars", []byte(`{"a": 1, "b": 2}`))
+	require.NoError(t, err)
+	var resp dynamicResponse
+	require.NoError(t, json.Unmarshal(out, &resp))
+	assert.Equal(t, float32(3), resp.Ab)
+	assert.Equal(t, "test_prefix.override.test.override.test.method.123456", resp.Subject)
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
@@ -0,0 +1,98 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	service "github.com/synternet/data-layer-sdk/pkg/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// GatherHandler is called with every response of a gathered unary call. The reply is nil if err is set.
+// Returning an error stops the call, and the error is returned from the call.
+type GatherHandler func(msg service.Message, reply proto.Message, err error) error
+
+// GatherCallOption is a grpc.CallOption that sends a unary request to all responders. See Gather.
+type GatherCallOption struct {
+	grpc.EmptyCallOption
+	Options service.RequestManyOptions
+	Handler GatherHandler
+}
+
+// Gather returns a call option that sends a unary request to all the responders of the method subject instead of one,
+// e.g. to ask every publisher whether it has a block. The stop conditions are set in the options.
+//
+// The handler is called with every response. The reply of the call is set to the first successful response.
+// The call fails with the first remote error if there are no successful responses, and with codes.Unavailable
+// if there are no responses at all. Retries, hedging and circuit breakers do not apply to gathered calls.
+func Gather(opts service.RequestManyOptions, handler GatherHandler) grpc.CallOption {
+	return GatherCallOption{Options: opts, Handler: handler}
+}
+
+// gatherOption returns the last Gather option.
+func gatherOption(opts []grpc.CallOption) (GatherCallOption, bool) {
+	var ret GatherCallOption
+	var ok bool
+	for _, opt := range opts {
+		if o, isGather := opt.(GatherCallOption); isGather {
+			ret, ok = o, true
+		}
+	}
+	return ret, ok
+}
+
+// gather performs a unary call that collects responses from all responders.
+func (c *ClientConn) gather(ctx context.Context, tokens []string, args, reply proto.Message, g GatherCallOption, opts []grpc.CallOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reqOpts := g.Options
+	reqOpts.Response = reply.ProtoReflect().New().Interface()
+	reqOpts.Header = outgoingHeader(ctx)
+	for k, v := range g.Options.Header {
+		reqOpts.Header[k] = v
+	}
+	responses, err := c.sub.RequestMany(ctx, args, reqOpts, tokens...)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.Unavailable, "request: %v", err)
+	}
+
+	var first service.Message
+	var firstErr error
+	for resp := range responses {
+		err := resp.Err
+		if _, ok := status.FromError(err); !ok {
+			err = status.Errorf(codes.Internal, "response: %v", err)
+		}
+		if g.Handler != nil {
+			if err := g.Handler(resp.Message, resp.Payload, err); err != nil {
+				return err
+			}
+		}
+		switch {
+		case err != nil && firstErr == nil:
+			firstErr = err
+		case err == nil && first == nil:
+			first = resp.Message
+			proto.Reset(reply)
+			proto.Merge(reply, resp.Payload)
+		}
+	}
+
+	switch {
+	case first != nil:
+		applyCallOptions(opts, metadataFromHeader(first.Header(), ResponseHeaderPrefix), metadataFromHeader(first.Header(), TrailerHeaderPrefix))
+		return nil
+	case firstErr != nil:
+		return firstErr
+	case ctx.Err() != nil:
+		return contextError(ctx)
+	}
+	return status.Error(codes.Unavailable, nats.ErrNoResponders.Error())
+}
@@ -0,0 +1,96 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	"github.com/synternet/data-layer-sdk/pkg/service"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGather(t *testing.T) {
+	errStop := errors.New("stop")
+	tests := []struct {
+		name        string
+		replicas    int
+		failing     int
+		opts        service.RequestManyOptions
+		handlerErr  error
+		wantCode    codes.Code
+		wantErr     error
+		wantReplies int
+		wantErrors  int
+	}{
+		{"all replicas", 3, 0, service.RequestManyOptions{}, nil, codes.OK, nil, 3, 0},
+		{"max responses", 3, 0, service.RequestManyOptions{MaxResponses: 2}, nil, codes.OK, nil, 2, 0},
+		{"partial failure", 3, 1, service.RequestManyOptions{}, nil, codes.OK, nil, 2, 1},
+		{"all failed", 2, 2, service.RequestManyOptions{}, nil, codes.NotFound, nil, 0, 2},
+		{"no responders", 0, 0, service.RequestManyOptions{}, nil, codes.Unavailable, nil, 0, 0},
+		{"handler stops", 3, 0, service.RequestManyOptions{}, errStop, codes.Unknown, errStop, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			// Replicas in a queue group would share the requests, but gathered calls reach all of them
+			grp, ctx := errgroup.WithContext(ctx)
+			pub := NewPublisher(ctx, t, "test_prefix")
+			for i := range tt.replicas {
+				srv := rpc.NewServiceRegistrar(grp, pub,
+					rpc.WithQueueGroup("", "workers"),
+					rpc.WithUnaryInterceptors(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+						if i < tt.failing {
+							return nil, status.Error(codes.NotFound, "no block")
+						}
+						return handler(ctx, req)
+					}),
+				)
+				rpctypes.RegisterTestServiceServer(srv, &Test{t: t})
+				require.NoError(t, srv.Start(ctx, nil))
+			}
+			time.Sleep(time.Millisecond * 10)
+
+			clt := rpc.NewClientConn(ctx, pub, "test_prefix", nil)
+			client := rpctypes.NewTestServiceClient(clt)
+
+			var replies, failures int
+			handler := func(msg service.Message, reply proto.Message, err error) error {
+				if err != nil {
+					assert.Equal(t, codes.NotFound, status.Code(err))
+					failures++
+					return nil
+				}
+				assert.Equal(t, float32(3), reply.(*rpctypes.TestResponse).Ab)
+				replies++
+				return tt.handlerErr
+			}
+			callCtx, callCancel := context.WithTimeout(ctx, time.Millisecond*100)
+			defer callCancel()
+			res, err := client.Test(callCtx, &rpctypes.TestRequest{A: 1, B: 2}, rpc.Gather(tt.opts, handler))
+			assert.Equal(t, tt.wantCode, status.Code(err), err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			if tt.wantCode == codes.OK {
+				require.NotNil(t, res)
+				assert.Equal(t, float32(3), res.Ab)
+			}
+			assert.Equal(t, tt.wantReplies, replies)
+			assert.Equal(t, tt.wantErrors, failures)
+
+			cancel()
+			time.Sleep(time.Millisecond * 10)
+		})
+	}
+}
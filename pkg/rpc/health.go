@@ -0,0 +1,50 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// WithoutHealth disables the grpc.health.v1.Health service that is otherwise registered by Start.
+func WithoutHealth() RegistrarOption {
+	return func(s *ServiceRegistrar) {
+		s.disableHealth = true
+	}
+}
+
+// Health returns the health server of the registrar. Applications can use it to set the serving status of
+// the registered services with SetServingStatus. The empty service name stands for the overall status.
+//
+// Start sets SERVING status for all registered services whose status was not set before, and all services are
+// switched to NOT_SERVING once the context passed to Start is done. Starting the registrar again after Stop
+// resumes the health server and sets SERVING status for all services.
+func (s *ServiceRegistrar) Health() *health.Server {
+	return s.health
+}
+
+// startHealth registers the health service and sets the initial serving status of the services.
+// The health server is shut down once ctx is done, unless the registrar is stopped before that.
+// It must be called with the registrar lock held.
+func (s *ServiceRegistrar) startHealth(ctx context.Context) {
+	if s.disableHealth {
+		return
+	}
+	if _, ok := s.services[healthpb.Health_ServiceDesc.ServiceName]; !ok {
+		s.register(&healthpb.Health_ServiceDesc, s.health)
+	}
+	// The health server ignores status changes after it was shut down by the context of the previous run
+	if s.stopped {
+		s.health.Resume()
+	}
+	for name := range s.services {
+		_, err := s.health.Check(ctx, &healthpb.HealthCheckRequest{Service: name})
+		if status.Code(err) == codes.NotFound || s.stopped {
+			s.health.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+		}
+	}
+	s.stopHealth = context.AfterFunc(ctx, s.health.Shutdown)
+}
@@ -0,0 +1,146 @@
+package rpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const testServiceName = "synternet.rpc.TestService"
+
+func TestHealthCheck(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub, srv := makeRegistrar(t, ctx, nil)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := healthpb.NewHealthClient(clt)
+
+	for _, name := range []string{"", testServiceName} {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: name})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status, name)
+	}
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "synternet.rpc.MissingService"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	srv.Health().SetServingStatus(testServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: testServiceName})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestHealthWatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub, srv := makeRegistrar(t, ctx, nil)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := healthpb.NewHealthClient(clt)
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	defer cancel1()
+	str, err := client.Watch(ctx1, &healthpb.HealthCheckRequest{Service: testServiceName})
+	require.NoError(t, err)
+
+	resp, err := str.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	srv.Health().SetServingStatus(testServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	resp, err = str.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestHealthShutdown(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	_, srv := makeRegistrar(t, ctx1, nil)
+
+	resp, err := srv.Health().Check(ctx, &healthpb.HealthCheckRequest{Service: testServiceName})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	cancel1()
+	time.Sleep(time.Millisecond * 10)
+
+	for _, name := range []string{"", testServiceName} {
+		resp, err := srv.Health().Check(ctx, &healthpb.HealthCheckRequest{Service: name})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status, name)
+	}
+}
+
+func TestWithoutHealth(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil, rpc.WithoutHealth())
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := healthpb.NewHealthClient(clt)
+
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel1()
+	_, err := client.Check(ctx1, &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestHealthRestart(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	_, srv := makeRegistrar(t, ctx1, nil)
+
+	// The health server is shut down with the context of the first run
+	cancel1()
+	time.Sleep(time.Millisecond * 10)
+	require.NoError(t, srv.Stop(ctx))
+
+	ctx2, cancel2 := context.WithCancel(ctx)
+	defer cancel2()
+	require.NoError(t, srv.Start(ctx2, nil))
+	resp, err := srv.Health().Check(ctx, &healthpb.HealthCheckRequest{Service: testServiceName})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	srv.Health().SetServingStatus(testServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	resp, err = srv.Health().Check(ctx, &healthpb.HealthCheckRequest{Service: testServiceName})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+	srv.Health().SetServingStatus(testServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	// The context of a stopped run does not shut the health server down
+	ctx3, cancel3 := context.WithCancel(ctx)
+	require.NoError(t, srv.Stop(ctx))
+	require.NoError(t, srv.Start(ctx3, nil))
+	require.NoError(t, srv.Stop(ctx))
+	require.NoError(t, srv.Start(ctx2, nil))
+	cancel3()
+	time.Sleep(time.Millisecond * 10)
+	resp, err = srv.Health().Check(ctx, &healthpb.HealthCheckRequest{Service: testServiceName})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	require.NoError(t, srv.Stop(ctx))
+}
@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// RegistrarOption configures ServiceRegistrar.
+type RegistrarOption func(*ServiceRegistrar)
+
+// ClientOption configures ClientConn.
+type ClientOption func(*ClientConn)
+
+// WithUnaryInterceptors appends interceptors to the chain of unary server interceptors.
+// The first interceptor is the outermost one.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) RegistrarOption {
+	return func(s *ServiceRegistrar) {
+		s.unaryInterceptors = append(s.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors appends interceptors to the chain of stream server interceptors.
+// The first interceptor is the outermost one.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) RegistrarOption {
+	return func(s *ServiceRegistrar) {
+		s.streamInterceptors = append(s.streamInterceptors, interceptors...)
+	}
+}
+
+// WithUnaryClientInterceptors appends interceptors to the chain of unary client interceptors.
+// The first interceptor is the outermost one.
+func WithUnaryClientInterceptors(interceptors ...grpc.UnaryClientInterceptor) ClientOption {
+	return func(c *ClientConn) {
+		c.unaryInterceptors = append(c.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamClientInterceptors appends interceptors to the chain of stream client interceptors.
+// The first interceptor is the outermost one.
+func WithStreamClientInterceptors(interceptors ...grpc.StreamClientInterceptor) ClientOption {
+	return func(c *ClientConn) {
+		c.streamInterceptors = append(c.streamInterceptors, interceptors...)
+	}
+}
+
+// chainUnaryServerInterceptors combines interceptors into one. It returns nil if there are no interceptors.
+func chainUnaryServerInterceptors(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return interceptors[0](ctx, req, info, chainUnaryHandler(interceptors, 0, info, handler))
+	}
+}
+
+func chainUnaryHandler(interceptors []grpc.UnaryServerInterceptor, curr int, info *grpc.UnaryServerInfo, final grpc.UnaryHandler) grpc.UnaryHandler {
+	if curr == len(interceptors)-1 {
+		return final
+	}
+	return func(ctx context.Context, req any) (any, error) {
+		return interceptors[curr+1](ctx, req, info, chainUnaryHandler(interceptors, curr+1, info, final))
+	}
+}
+
+// chainStreamServerInterceptors combines interceptors into one. It returns nil if there are no interceptors.
+func chainStreamServerInterceptors(interceptors []grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return interceptors[0](srv, ss, info, chainStreamHandler(interceptors, 0, info, handler))
+	}
+}
+
+func chainStreamHandler(interceptors []grpc.StreamServerInterceptor, curr int, info *grpc.StreamServerInfo, final grpc.StreamHandler) grpc.StreamHandler {
+	if curr == len(interceptors)-1 {
+		return final
+	}
+	return func(srv any, ss grpc.ServerStream) error {
+		return interceptors[curr+1](srv, ss, info, chainStreamHandler(interceptors, curr+1, info, final))
+	}
+}
+
+// chainUnaryClientInterceptors combines interceptors into one. It returns nil if there are no interceptors.
+func chainUnaryClientInterceptors(interceptors []grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return interceptors[0](ctx, method, req, reply, cc, chainUnaryInvoker(interceptors, 0, invoker), opts...)
+	}
+}
+
+func chainUnaryInvoker(interceptors []grpc.UnaryClientInterceptor, curr int, final grpc.UnaryInvoker) grpc.UnaryInvoker {
+	if curr == len(interceptors)-1 {
+		return final
+	}
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return interceptors[curr+1](ctx, method, req, reply, cc, chainUnaryInvoker(interceptors, curr+1, final), opts...)
+	}
+}
+
+// chainStreamClientInterceptors combines interceptors into one. It returns nil if there are no interceptors.
+func chainStreamClientInterceptors(interceptors []grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return interceptors[0](ctx, desc, cc, method, chainStreamer(interceptors, 0, streamer), opts...)
+	}
+}
+
+func chainStreamer(interceptors []grpc.StreamClientInterceptor, curr int, final grpc.Streamer) grpc.Streamer {
+	if curr == len(interceptors)-1 {
+		return final
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return interceptors[curr+1](ctx, desc, cc, method, chainStreamer(interceptors, curr+1, final), opts...)
+	}
+}
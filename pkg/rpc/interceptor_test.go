@@ -0,0 +1,156 @@
+package rpc_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recorder collects log messages and interceptor calls
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) add(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls)
+}
+
+func (r *recorder) logger(kind string) logging.Logger {
+	return logging.LoggerFunc(func(ctx context.Context, level logging.Level, msg string, fields ...any) {
+		iter := logging.Fields(fields).Iterator()
+		for iter.Next() {
+			if k, v := iter.At(); k == "grpc.method" {
+				r.add(fmt.Sprintf("%s: %s %v", kind, msg, v))
+			}
+		}
+	})
+}
+
+func (r *recorder) unary(name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		r.add(name + " " + info.FullMethod)
+		return handler(ctx, req)
+	}
+}
+
+func (r *recorder) stream(name string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		r.add(fmt.Sprintf("%s %s client=%v server=%v", name, info.FullMethod, info.IsClientStream, info.IsServerStream))
+		return handler(srv, ss)
+	}
+}
+
+// panicky panics on requests with a magic value
+func panicky(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if r, ok := req.(*rpctypes.TestRequest); ok && r.A == 13 {
+		panic("unlucky number")
+	}
+	return handler(ctx, req)
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var rec recorder
+	sub := makeServer(t, ctx, nil,
+		rpc.WithUnaryInterceptors(
+			recovery.UnaryServerInterceptor(recovery.WithRecoveryHandler(func(p any) error {
+				return status.Errorf(codes.Internal, "panic: %v", p)
+			})),
+			logging.UnaryServerInterceptor(rec.logger("server")),
+		),
+		rpc.WithUnaryInterceptors(rec.unary("first"), rec.unary("second"), panicky),
+	)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil,
+		rpc.WithUnaryClientInterceptors(logging.UnaryClientInterceptor(rec.logger("client"))),
+	)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	res, err := client.Test(ctx, &rpctypes.TestRequest{A: 123, B: 321})
+	require.NoError(t, err)
+	assert.Equal(t, float32(123.0+321.0), res.Ab)
+	assert.Equal(t, []string{
+		"client: started call Test",
+		"server: started call Test",
+		"first /synternet.rpc.TestService/Test",
+		"second /synternet.rpc.TestService/Test",
+		"server: finished call Test",
+		"client: finished call Test",
+	}, rec.list())
+
+	_, err = client.Test(ctx, &rpctypes.TestRequest{A: 13, B: 321})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "panic: unlucky number", status.Convert(err).Message())
+
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var rec recorder
+	sub := makeServer(t, ctx, nil,
+		rpc.WithStreamInterceptors(
+			recovery.StreamServerInterceptor(),
+			rec.stream("first"),
+			logging.StreamServerInterceptor(rec.logger("server")),
+		),
+	)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil,
+		rpc.WithStreamClientInterceptors(logging.StreamClientInterceptor(rec.logger("client"))),
+	)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*200)
+	defer cancel1()
+	str, err := client.TestStreamBidirectional(ctx1)
+	require.NoError(t, err)
+
+	require.NoError(t, str.Send(&rpctypes.TestRequest{A: 1, B: 2}))
+	require.NoError(t, str.CloseSend())
+	msg, err := str.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, float32(3), msg.Ab)
+	_, err = str.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// The server logs after the end-of-stream frame has been sent, and the pure stream runs concurrently
+	time.Sleep(time.Millisecond * 10)
+	assert.ElementsMatch(t, []string{
+		"first /synternet.rpc.TestService/TestStreamOnly client=false server=true",
+		"server: started call TestStreamOnly",
+		"first /synternet.rpc.TestService/TestStreamBidirectional client=true server=true",
+		"client: started call TestStreamBidirectional",
+		"server: started call TestStreamBidirectional",
+		"server: finished call TestStreamBidirectional",
+		"client: finished call TestStreamBidirectional",
+	}, rec.list())
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
@@ -0,0 +1,297 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// errClosed is returned to the requests of a stopped or unregistered service that are still delivered.
+var errClosed = status.Error(codes.Unavailable, "service is stopped")
+
+// binding is a method served on a subject. Bindings are swapped when the subject changes, see UpdateVars.
+type binding struct {
+	subject string
+	// sub is nil for pure streams and for unary methods with `disable_inputs` option
+	sub      *nats.Subscription
+	producer *producer
+	closed   atomic.Bool
+}
+
+// retire stops serving the method on the subject. The subscription is drained, so the messages already delivered
+// to it are still handled.
+func (b *binding) retire() {
+	if b.sub != nil {
+		if err := b.sub.Drain(); err != nil {
+			slog.Debug("ServiceRegistrar: draining a subscription", "err", err, "subject", b.subject)
+		}
+	}
+	if b.producer != nil {
+		b.producer.cancel()
+	}
+}
+
+// tracker counts the running handlers, so that Stop can wait for them.
+type tracker struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{}
+}
+
+func (t *tracker) add() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.n == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.n++
+}
+
+func (t *tracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.n--
+	if t.n == 0 {
+		close(t.idle)
+	}
+}
+
+// wait waits until no handlers are running, or the context is done.
+func (t *tracker) wait(ctx context.Context) error {
+	t.mu.Lock()
+	if t.n == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-idle:
+		return nil
+	}
+}
+
+// bindService binds every method of the service to the subjects derived from vars. The bindings whose subjects
+// have not changed are reused. If binding any method fails, the new bindings are retired.
+// It must be called with the registrar lock held.
+func (s *ServiceRegistrar) bindService(svc *serviceInfo, vars map[string]string) (map[string]*binding, error) {
+	serviceDesc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(svc.serviceDesc.ServiceName))
+	if err != nil {
+		return nil, fmt.Errorf("service desc: %w", err)
+	}
+	svcDesc := serviceDesc.(protoreflect.ServiceDescriptor)
+
+	bindings := make(map[string]*binding, len(svc.methods)+len(svc.streams))
+	for name := range svc.methods {
+		if bindings[name], err = s.bindMethod(svc, svcDesc, name, vars); err != nil {
+			s.retireNew(svc, bindings)
+			return nil, fmt.Errorf("unary methods: %w", err)
+		}
+	}
+	for name := range svc.streams {
+		if bindings[name], err = s.bindMethod(svc, svcDesc, name, vars); err != nil {
+			s.retireNew(svc, bindings)
+			return nil, fmt.Errorf("stream methods: %w", err)
+		}
+	}
+	return bindings, nil
+}
+
+// bindMethod serves the method on the subject derived from vars, unless it is already served there.
+func (s *ServiceRegistrar) bindMethod(svc *serviceInfo, svcDesc protoreflect.ServiceDescriptor, name string, vars map[string]string) (*binding, error) {
+	methodDesc := svcDesc.Methods().ByName(protoreflect.Name(name))
+	if methodDesc == nil {
+		return nil, fmt.Errorf("service desc nil: %s@%s", name, svc.serviceDesc.ServiceName)
+	}
+	tokens, err := s.subject(svcDesc, methodDesc, vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive subject: %w", err)
+	}
+	subject := s.pub.Subject(tokens...)
+	if b, ok := svc.bindings[name]; ok && b.subject == subject {
+		return b, nil
+	}
+	slog.Debug("ServiceRegistrar", "service", svcDesc.FullName(), "method", name, "subject", subject)
+
+	b := &binding{subject: subject}
+	if method, ok := svc.methods[name]; ok {
+		if disableSubscription(methodDesc) {
+			return b, nil
+		}
+		b.sub, err = s.serveUnary(svc, svcDesc, methodDesc, method, b, tokens)
+		return b, err
+	}
+	stream := svc.streams[name]
+	if disableSubscription(methodDesc) {
+		if s.schemas != nil {
+			if err := s.schemas.advertise(subject, methodDesc.Output()); err != nil {
+				return nil, fmt.Errorf("schema: %w", err)
+			}
+		}
+		b.producer = s.startProducer(svc.ctx, svc, stream, subject)
+		return b, nil
+	}
+	b.sub, err = s.serveStream(svc, svcDesc, methodDesc, stream, b)
+	return b, err
+}
+
+// retireNew retires the bindings that are not used by the service.
+func (s *ServiceRegistrar) retireNew(svc *serviceInfo, bindings map[string]*binding) {
+	for name, b := range bindings {
+		if b != nil && svc.bindings[name] != b {
+			s.retire(b)
+		}
+	}
+}
+
+// retire retires the binding, and forgets its producer and the schema of its subject.
+// It must be called with the registrar lock held.
+func (s *ServiceRegistrar) retire(b *binding) {
+	b.retire()
+	if b.producer != nil {
+		s.producers = slices.DeleteFunc(s.producers, func(p *producer) bool { return p == b.producer })
+		if s.schemas != nil {
+			s.schemas.Withdraw(b.subject)
+		}
+	}
+}
+
+// closeService retires all bindings of the service, and rejects the messages that are still delivered to them.
+// It must be called with the registrar lock held.
+func (s *ServiceRegistrar) closeService(svc *serviceInfo) {
+	for _, b := range svc.bindings {
+		b.closed.Store(true)
+		s.retire(b)
+	}
+	svc.bindings = nil
+}
+
+// UpdateVars re-derives the subjects of the started services from vars, the same way Start does, and moves
+// the methods whose subjects have changed to the new subjects. The new subjects are subscribed to before the old
+// subscriptions are drained, so that the requests are not lost during the swap. Methods whose subjects have not
+// changed keep their subscriptions, and pure-stream producers are restarted only if their subjects have changed.
+//
+// If any subject cannot be derived or subscribed to, UpdateVars fails and keeps serving the old subjects.
+func (s *ServiceRegistrar) UpdateVars(vars map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		return fmt.Errorf("registrar is not started")
+	}
+	if s.strictSubjects {
+		if err := s.checkSubjects(vars); err != nil {
+			return fmt.Errorf("subjects: %w", err)
+		}
+	}
+
+	updated := make(map[*serviceInfo]map[string]*binding, len(s.services))
+	for _, svc := range s.services {
+		if svc.ctx == nil {
+			// Registered after Start
+			continue
+		}
+		bindings, err := s.bindService(svc, extractServiceVars(svc.serviceDesc.ServiceName, vars))
+		if err != nil {
+			for svc, bindings := range updated {
+				s.retireNew(svc, bindings)
+			}
+			return fmt.Errorf("%s: %w", svc.serviceDesc.ServiceName, err)
+		}
+		updated[svc] = bindings
+	}
+
+	for svc, bindings := range updated {
+		for name, b := range svc.bindings {
+			if bindings[name] != b {
+				s.retire(b)
+			}
+		}
+		svc.bindings = bindings
+		svc.vars = extractServiceVars(svc.serviceDesc.ServiceName, vars)
+	}
+	return nil
+}
+
+// Unregister stops serving the service registered with RegisterService, and forgets it. The subscriptions of the service
+// are drained, its producers are stopped, and the running handlers are cancelled. The health status of the service
+// is set to NOT_SERVING.
+//
+// The service can be registered again. It is served once the registrar is stopped and started again.
+func (s *ServiceRegistrar) Unregister(serviceName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	svc, ok := s.services[serviceName]
+	if !ok {
+		return fmt.Errorf("service %q is not registered", serviceName)
+	}
+	s.closeService(svc)
+	if svc.cancel != nil {
+		svc.cancel()
+	}
+	delete(s.services, serviceName)
+	maps.DeleteFunc(s.metrics, func(method string, _ *methodMetrics) bool {
+		return strings.HasPrefix(method, "/"+serviceName+"/")
+	})
+	if !s.disableHealth {
+		s.health.SetServingStatus(serviceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	return nil
+}
+
+// Stop stops serving all services. The subscriptions are drained, the producers are stopped, and the health status
+// of the services is set to NOT_SERVING right away. Then Stop waits for the running handlers until ctx is done,
+// and cancels the handlers that are still running. It returns the context error if the handlers have not returned in time.
+//
+// The registered services are kept, so the registrar can be started again.
+func (s *ServiceRegistrar) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	cancels := s.halt()
+	s.mu.Unlock()
+
+	err := s.running.wait(ctx)
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return err
+}
+
+// halt stops serving all services and sets their health status to NOT_SERVING. It returns the functions cancelling
+// the contexts of the services. It must be called with the registrar lock held.
+func (s *ServiceRegistrar) halt() []context.CancelFunc {
+	s.started = false
+	s.stopped = true
+	if s.stopHealth != nil {
+		s.stopHealth()
+		s.stopHealth = nil
+	}
+	cancels := make([]context.CancelFunc, 0, len(s.services))
+	for name, svc := range s.services {
+		s.closeService(svc)
+		if svc.cancel != nil {
+			cancels = append(cancels, svc.cancel)
+		}
+		if !s.disableHealth {
+			s.health.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+		}
+	}
+	return cancels
+}
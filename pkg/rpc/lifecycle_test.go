@@ -0,0 +1,304 @@
+package rpc_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// lifecycleServer replies with the subject of the request. Test calls block until they are released, if release is set.
+type lifecycleServer struct {
+	rpctypes.UnimplementedTestServiceServer
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *lifecycleServer) Test(ctx context.Context, _ *rpctypes.TestRequest) (*rpctypes.TestResponse, error) {
+	if s.release != nil {
+		s.entered <- struct{}{}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.release:
+		}
+	}
+	return &rpctypes.TestResponse{Ab: 1}, nil
+}
+
+func (s *lifecycleServer) TestVars(ctx context.Context, _ *rpctypes.TestRequest) (*rpctypes.TestResponse, error) {
+	subject, _ := rpc.GetSubject(ctx)
+	return &rpctypes.TestResponse{Subject: subject.String()}, nil
+}
+
+func makeLifecycleRegistrar(t *testing.T, ctx context.Context, impl *lifecycleServer, vars map[string]string, opts ...rpc.RegistrarOption) (*Publisher, *rpc.ServiceRegistrar, *errgroup.Group) {
+	grp, ctx := errgroup.WithContext(ctx)
+	pub := NewPublisher(ctx, t, "test_prefix")
+	srv := rpc.NewServiceRegistrar(grp, pub, append(opts, rpc.WithoutReflection())...)
+	rpctypes.RegisterTestServiceServer(srv, impl)
+	require.NoError(t, srv.Start(ctx, vars))
+	return pub, srv, grp
+}
+
+func TestUpdateVars(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// The swap is exercised against a NATS server, which stops delivering to the drained subscriptions
+	grp, gctx := errgroup.WithContext(ctx)
+	pub := makeNatsService(t, gctx)
+	srv := rpc.NewServiceRegistrar(grp, pub, rpc.WithoutReflection(), rpc.WithoutHealth())
+	rpctypes.RegisterTestServiceServer(srv, &lifecycleServer{})
+	require.NoError(t, srv.Start(gctx, nil))
+	client := rpctypes.NewTestServiceClient(rpc.NewClientConn(ctx, pub, natsPrefix, nil))
+
+	// The requests are served by the wildcard subscription, and by the exact one once the variable is set,
+	// so they must succeed during every swap.
+	var calls, failures atomic.Int32
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*500)
+				_, err := client.TestVars(ctx1, &rpctypes.TestRequest{}, rpc.WithVars(map[string]string{"variable": "123456"}))
+				cancel1()
+				calls.Add(1)
+				if err != nil {
+					failures.Add(1)
+					t.Log("TestVars", "err=", err)
+				}
+			}
+		}()
+	}
+
+	for i := range 20 {
+		vars := map[string]string{"variable": "123456"}
+		if i%2 == 1 {
+			vars = nil
+		}
+		require.NoError(t, srv.UpdateVars(vars))
+		time.Sleep(time.Millisecond * 5)
+	}
+	close(done)
+	wg.Wait()
+
+	assert.NotZero(t, calls.Load())
+	assert.Zero(t, failures.Load())
+
+	require.NoError(t, srv.UpdateVars(map[string]string{"variable": "1"}))
+	resp, err := client.TestVars(ctx, &rpctypes.TestRequest{}, rpc.WithVars(map[string]string{"variable": "1"}))
+	require.NoError(t, err)
+	assert.Equal(t, natsPrefix+".override.test.override.test.method.1", resp.GetSubject())
+
+	// The old subject is no longer served
+	_, err = client.TestVars(ctx, &rpctypes.TestRequest{}, rpc.WithVars(map[string]string{"variable": "123456"}))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	require.NoError(t, srv.Stop(ctx))
+	cancel()
+	require.NoError(t, grp.Wait())
+}
+
+func TestUpdateVarsQueueGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	pub, srv, grp := makeLifecycleRegistrar(t, ctx, &lifecycleServer{}, nil, rpc.WithoutHealth())
+	require.NoError(t, srv.UpdateVars(map[string]string{"variable": "1"}))
+	queue, ok := pub.Queue("test_prefix.override.test.override.test.method.1")
+	assert.True(t, ok)
+	assert.Equal(t, "test-vars", queue)
+
+	require.NoError(t, srv.Stop(ctx))
+	cancel()
+	require.NoError(t, grp.Wait())
+}
+
+func TestUpdateVarsFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	grp, gctx := errgroup.WithContext(ctx)
+	pub := NewPublisher(gctx, t, "test_prefix")
+	srv := rpc.NewServiceRegistrar(grp, pub, rpc.WithStrictSubjects(), rpc.WithoutHealth(), rpc.WithoutReflection())
+	rpctypes.RegisterTestServiceServer(srv, &lifecycleServer{})
+	assert.ErrorContains(t, srv.UpdateVars(map[string]string{"variable": "1"}), "registrar is not started")
+	require.NoError(t, srv.Start(gctx, map[string]string{"variable": "1"}))
+	assert.ErrorContains(t, srv.Start(gctx, map[string]string{"variable": "1"}), "registrar is already started")
+
+	var verr *rpc.VariablesError
+	assert.ErrorAs(t, srv.UpdateVars(nil), &verr)
+	assert.ErrorContains(t, srv.UpdateVars(map[string]string{"variable": "abc"}), `value "abc" is not VARIABLE_TYPE_UINT`)
+
+	// The old subject is still served
+	client := rpctypes.NewTestServiceClient(rpc.NewClientConn(ctx, pub, "test_prefix", map[string]string{"variable": "1"}))
+	resp, err := client.TestVars(ctx, &rpctypes.TestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "test_prefix.override.test.override.test.method.1", resp.GetSubject())
+
+	cancel()
+	require.NoError(t, grp.Wait())
+}
+
+func TestStartFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	grp, gctx := errgroup.WithContext(ctx)
+	pub := makeNatsService(t, gctx)
+	nc := pub.SubNats.(*nats.Conn)
+	srv := rpc.NewServiceRegistrar(grp, pub)
+	rpctypes.RegisterTestServiceServer(srv, &lifecycleServer{})
+	// The service subscribes to telemetry pings in the background
+	require.Eventually(t, func() bool { return nc.NumSubscriptions() == 1 }, time.Second, time.Millisecond*10)
+	baseline := nc.NumSubscriptions()
+
+	// The services bound before the failing one are not left subscribed, whatever the order of the services
+	for i := 0; i < 5; i++ {
+		assert.ErrorContains(t, srv.Start(gctx, map[string]string{"variable": "abc"}), "VARIABLE_TYPE_UINT")
+		assert.Eventually(t, func() bool { return nc.NumSubscriptions() == baseline }, time.Second, time.Millisecond*10)
+		resp, err := srv.Health().Check(ctx, &healthpb.HealthCheckRequest{Service: testServiceName})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+	}
+
+	// Start can be retried
+	require.NoError(t, srv.Start(gctx, map[string]string{"variable": "1"}))
+	client := rpctypes.NewTestServiceClient(rpc.NewClientConn(ctx, pub, natsPrefix, nil))
+	_, err := client.Test(ctx, &rpctypes.TestRequest{})
+	assert.NoError(t, err)
+	resp, err := srv.Health().Check(ctx, &healthpb.HealthCheckRequest{Service: testServiceName})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	require.NoError(t, srv.Stop(ctx))
+
+	cancel()
+	require.NoError(t, grp.Wait())
+}
+
+func TestStop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	impl := &lifecycleServer{entered: make(chan struct{}), release: make(chan struct{})}
+	grp, gctx := errgroup.WithContext(ctx)
+	pub := makeNatsService(t, gctx)
+	srv := rpc.NewServiceRegistrar(grp, pub, rpc.WithoutReflection(), rpc.WithProducerPolicy(rpc.ProducerPolicy{InitialBackoff: time.Millisecond}))
+	rpctypes.RegisterTestServiceServer(srv, impl)
+	require.NoError(t, srv.Start(gctx, map[string]string{"variable": "1"}))
+	client := rpctypes.NewTestServiceClient(rpc.NewClientConn(ctx, pub, natsPrefix, nil))
+	require.Len(t, srv.Producers(), 1)
+
+	// Stop waits for the running handlers
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := client.Test(ctx, &rpctypes.TestRequest{})
+		callErr <- err
+	}()
+	<-impl.entered
+	stopErr := make(chan error, 1)
+	go func() {
+		stopErr <- srv.Stop(ctx)
+	}()
+	select {
+	case err := <-stopErr:
+		t.Fatalf("Stop returned before the handler: %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+	close(impl.release)
+	require.NoError(t, <-stopErr)
+	require.NoError(t, <-callErr)
+	assert.Empty(t, srv.Producers())
+	assert.NoError(t, srv.Stop(ctx))
+
+	_, err := client.Test(ctx, &rpctypes.TestRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// The registrar serves again once started
+	impl.release = nil
+	require.NoError(t, srv.Start(gctx, map[string]string{"variable": "1"}))
+	assert.Len(t, srv.Producers(), 1)
+	_, err = client.Test(ctx, &rpctypes.TestRequest{})
+	assert.NoError(t, err)
+	require.NoError(t, srv.UpdateVars(map[string]string{"variable": "2"}))
+	resp, err := client.TestVars(ctx, &rpctypes.TestRequest{}, rpc.WithVars(map[string]string{"variable": "2"}))
+	require.NoError(t, err)
+	assert.Equal(t, natsPrefix+".override.test.override.test.method.2", resp.GetSubject())
+
+	cancel()
+	require.NoError(t, grp.Wait())
+}
+
+func TestStopTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	impl := &lifecycleServer{entered: make(chan struct{}), release: make(chan struct{})}
+	pub, srv, grp := makeLifecycleRegistrar(t, ctx, impl, map[string]string{"variable": "1"}, rpc.WithoutHealth())
+	client := rpctypes.NewTestServiceClient(rpc.NewClientConn(ctx, pub, "test_prefix", nil))
+
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := client.Test(ctx, &rpctypes.TestRequest{})
+		callErr <- err
+	}()
+	<-impl.entered
+
+	// The handler that has not returned in time is cancelled
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*20)
+	defer cancel1()
+	assert.ErrorIs(t, srv.Stop(ctx1), context.DeadlineExceeded)
+	assert.Equal(t, codes.Canceled, status.Code(<-callErr))
+
+	cancel()
+	require.NoError(t, grp.Wait())
+}
+
+func TestUnregister(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	pub, srv, grp := makeLifecycleRegistrar(t, ctx, &lifecycleServer{}, map[string]string{"variable": "1"})
+	client := rpctypes.NewTestServiceClient(rpc.NewClientConn(ctx, pub, "test_prefix", nil))
+	health := healthpb.NewHealthClient(rpc.NewClientConn(ctx, pub, "test_prefix", nil))
+
+	_, err := client.Test(ctx, &rpctypes.TestRequest{})
+	require.NoError(t, err)
+
+	require.NoError(t, srv.Unregister("synternet.rpc.TestService"))
+	assert.ErrorContains(t, srv.Unregister("synternet.rpc.TestService"), `service "synternet.rpc.TestService" is not registered`)
+	assert.Empty(t, srv.Producers())
+
+	_, err = client.Test(ctx, &rpctypes.TestRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: "synternet.rpc.TestService"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+
+	// The other services are still served
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	cancel()
+	require.NoError(t, grp.Wait())
+}
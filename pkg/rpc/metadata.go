@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// gRPC metadata is carried in NATS headers with the following prefixes:
+//
+//   - request metadata (outgoing client metadata, incoming server metadata) - `rpc-md-`;
+//   - response header metadata (grpc.SetHeader, grpc.Header) - `rpc-header-`;
+//   - response trailer metadata (grpc.SetTrailer, grpc.Trailer) - `rpc-trailer-`.
+//
+// Values of binary keys (with `-bin` suffix) are base64 encoded.
+const (
+	MetadataHeaderPrefix = "rpc-md-"
+	ResponseHeaderPrefix = "rpc-header-"
+)
+
+// metadataToHeader adds metadata to the header using a prefix.
+func metadataToHeader(dst nats.Header, prefix string, md metadata.MD) {
+	for k, vals := range md {
+		k = strings.ToLower(k)
+		binary := strings.HasSuffix(k, "-bin")
+		for _, v := range vals {
+			if binary {
+				v = base64.RawStdEncoding.EncodeToString([]byte(v))
+			}
+			dst[prefix+k] = append(dst[prefix+k], v)
+		}
+	}
+}
+
+// metadataFromHeader extracts metadata stored in the header using a prefix.
+// Invalid binary values are skipped.
+func metadataFromHeader(header nats.Header, prefix string) metadata.MD {
+	md := metadata.MD{}
+	for k, vals := range header {
+		name, ok := strings.CutPrefix(k, prefix)
+		if !ok || name == "" {
+			continue
+		}
+		name = strings.ToLower(name)
+		binary := strings.HasSuffix(name, "-bin")
+		for _, v := range vals {
+			if binary {
+				b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(v, "="))
+				if err != nil {
+					continue
+				}
+				v = string(b)
+			}
+			md[name] = append(md[name], v)
+		}
+	}
+	return md
+}
+
+// outgoingHeader converts outgoing metadata and the deadline of the context into request headers.
+func outgoingHeader(ctx context.Context) nats.Header {
+	header := nats.Header{}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		metadataToHeader(header, MetadataHeaderPrefix, md)
+	}
+	setTimeoutHeader(ctx, header)
+	return header
+}
+
+// applyCallOptions sets response header and trailer metadata to the addresses passed in grpc.Header and grpc.Trailer call options.
+func applyCallOptions(opts []grpc.CallOption, header, trailer metadata.MD) {
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case grpc.HeaderCallOption:
+			*o.HeaderAddr = header
+		case grpc.TrailerCallOption:
+			*o.TrailerAddr = trailer
+		}
+	}
+}
+
+var _ grpc.ServerTransportStream = (*unaryTransportStream)(nil)
+
+// unaryTransportStream collects header and trailer metadata set by unary handlers using grpc.SetHeader and grpc.SetTrailer.
+type unaryTransportStream struct {
+	method string
+
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *unaryTransportStream) Method() string {
+	return s.method
+}
+
+func (s *unaryTransportStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+// SendHeader is the same as SetHeader, since the header is sent together with the response.
+func (s *unaryTransportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *unaryTransportStream) SetTrailer(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// responseHeader returns the response headers carrying the header and trailer metadata.
+func (s *unaryTransportStream) responseHeader() nats.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	header := nats.Header{}
+	metadataToHeader(header, ResponseHeaderPrefix, s.header)
+	metadataToHeader(header, TrailerHeaderPrefix, s.trailer)
+	return header
+}
@@ -0,0 +1,79 @@
+package rpc_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryMetadata(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	ctx1 := metadata.AppendToOutgoingContext(ctx, "authorization", "token", "trace-bin", "\x00\x01\n")
+	var header, trailer metadata.MD
+	res, err := client.Test(ctx1, &rpctypes.TestRequest{A: 1, B: 2}, grpc.Header(&header), grpc.Trailer(&trailer))
+	require.NoError(t, err)
+	assert.Equal(t, float32(3), res.Ab)
+	assert.Equal(t, []string{"token"}, header.Get("echo"))
+	assert.Equal(t, []string{"\x00\x01\n"}, trailer.Get("trace-bin"))
+
+	// Header and trailer are delivered with errors too
+	header, trailer = nil, nil
+	_, err = client.Test(ctx1, &rpctypes.TestRequest{A: 1, B: -2}, grpc.Header(&header), grpc.Trailer(&trailer))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, []string{"token"}, header.Get("echo"))
+	assert.Equal(t, []string{"\x00\x01\n"}, trailer.Get("trace-bin"))
+
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestStreamMetadata(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*200)
+	defer cancel1()
+	ctx1 = metadata.AppendToOutgoingContext(ctx1, "authorization", "token", "trace-bin", "\x00\x01\n")
+	var header, trailer metadata.MD
+	str, err := client.TestStreamBidirectional(ctx1, grpc.Header(&header), grpc.Trailer(&trailer))
+	require.NoError(t, err)
+
+	require.NoError(t, str.Send(&rpctypes.TestRequest{A: 1, B: 2}))
+	msg, err := str.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, float32(3), msg.Ab)
+
+	md, err := str.Header()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token"}, md.Get("echo"))
+
+	require.NoError(t, str.CloseSend())
+	_, err = str.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, []string{"\x00\x01\n"}, str.Trailer().Get("trace-bin"))
+	assert.Equal(t, []string{"1"}, str.Trailer().Get("count"))
+	assert.Equal(t, []string{"token"}, header.Get("echo"))
+	assert.Equal(t, str.Trailer(), trailer)
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
@@ -0,0 +1,132 @@
+package rpc
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// latencyWindow is the number of the latest call latencies the percentiles are computed from.
+const latencyWindow = 1024
+
+// methodMetrics counts the calls of a method. Like the message counters of service.Service, the counters and latencies
+// cover the period since the status was collected last time, while the number of calls in flight does not.
+type methodMetrics struct {
+	inFlight atomic.Int64
+	sent     atomic.Uint64
+	received atomic.Uint64
+
+	mu        sync.Mutex
+	requests  uint64
+	errors    map[codes.Code]uint64
+	latencies []time.Duration
+	next      int
+}
+
+func newMethodMetrics() *methodMetrics {
+	return &methodMetrics{
+		errors:    make(map[codes.Code]uint64),
+		latencies: make([]time.Duration, 0, latencyWindow),
+	}
+}
+
+// begin counts a call in flight and returns its start time.
+func (m *methodMetrics) begin() time.Time {
+	m.inFlight.Add(1)
+	return time.Now()
+}
+
+// end counts the call that has finished with the error.
+func (m *methodMetrics) end(started time.Time, err error) {
+	latency := time.Since(started)
+	m.inFlight.Add(-1)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+	if code := status.Code(err); code != codes.OK {
+		m.errors[code]++
+	}
+	if len(m.latencies) < latencyWindow {
+		m.latencies = append(m.latencies, latency)
+		return
+	}
+	m.latencies[m.next] = latency
+	m.next = (m.next + 1) % latencyWindow
+}
+
+// collect adds the metrics to ret under the key, and resets them.
+func (m *methodMetrics) collect(key string, ret map[string]string) {
+	m.mu.Lock()
+	requests := m.requests
+	errs := m.errors
+	latencies := m.latencies
+	m.requests = 0
+	m.errors = make(map[codes.Code]uint64)
+	m.latencies = make([]time.Duration, 0, latencyWindow)
+	m.next = 0
+	m.mu.Unlock()
+
+	ret[key+".requests"] = strconv.FormatUint(requests, 10)
+	ret[key+".in_flight"] = strconv.FormatInt(m.inFlight.Load(), 10)
+	ret[key+".stream.sent"] = strconv.FormatUint(m.sent.Swap(0), 10)
+	ret[key+".stream.received"] = strconv.FormatUint(m.received.Swap(0), 10)
+
+	var total uint64
+	for code, n := range errs {
+		ret[key+".errors."+code.String()] = strconv.FormatUint(n, 10)
+		total += n
+	}
+	ret[key+".errors"] = strconv.FormatUint(total, 10)
+
+	if len(latencies) == 0 {
+		return
+	}
+	slices.Sort(latencies)
+	ret[key+".latency.p50"] = percentile(latencies, 0.5).String()
+	ret[key+".latency.p90"] = percentile(latencies, 0.9).String()
+	ret[key+".latency.p99"] = percentile(latencies, 0.99).String()
+}
+
+// percentile returns the nearest-rank percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// metricsKey converts the full method name, e.g. `/pkg.Service/Method`, to the telemetry key `rpc.pkg.Service.Method`.
+func metricsKey(fullMethod string) string {
+	return "rpc." + strings.ReplaceAll(strings.TrimPrefix(fullMethod, "/"), "/", ".")
+}
+
+// methodMetrics returns the metrics of the method, and creates them if needed.
+// It must be called with the registrar lock held.
+func (s *ServiceRegistrar) methodMetrics(fullMethod string) *methodMetrics {
+	m, ok := s.metrics[fullMethod]
+	if !ok {
+		m = newMethodMetrics()
+		s.metrics[fullMethod] = m
+	}
+	return m
+}
+
+// metricsTelemetry returns the telemetry status of the methods, and resets their counters.
+func (s *ServiceRegistrar) metricsTelemetry() map[string]string {
+	s.mu.Lock()
+	metrics := maps.Clone(s.metrics)
+	s.mu.Unlock()
+
+	ret := make(map[string]string)
+	for method, m := range metrics {
+		m.collect(metricsKey(method), ret)
+	}
+	return ret
+}
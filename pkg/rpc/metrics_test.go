@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_percentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 0.5))
+	assert.Equal(t, 90*time.Millisecond, percentile(sorted, 0.9))
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 0.99))
+	assert.Equal(t, time.Millisecond, percentile(sorted[:1], 0.5))
+	assert.Equal(t, time.Millisecond, percentile(sorted, 0))
+}
+
+func Test_methodMetrics(t *testing.T) {
+	m := newMethodMetrics()
+	m.end(m.begin(), nil)
+	m.end(m.begin(), status.Error(codes.NotFound, "not found"))
+	m.end(m.begin(), status.Error(codes.NotFound, "not found"))
+	m.end(m.begin(), errors.New("plain"))
+	m.begin()
+	m.sent.Add(3)
+	m.received.Add(2)
+
+	got := make(map[string]string)
+	m.collect("rpc.pkg.Service.Method", got)
+	assert.Equal(t, "4", got["rpc.pkg.Service.Method.requests"])
+	assert.Equal(t, "1", got["rpc.pkg.Service.Method.in_flight"])
+	assert.Equal(t, "3", got["rpc.pkg.Service.Method.errors"])
+	assert.Equal(t, "2", got["rpc.pkg.Service.Method.errors.NotFound"])
+	assert.Equal(t, "1", got["rpc.pkg.Service.Method.errors.Unknown"])
+	assert.Equal(t, "3", got["rpc.pkg.Service.Method.stream.sent"])
+	assert.Equal(t, "2", got["rpc.pkg.Service.Method.stream.received"])
+	for _, key := range []string{"p50", "p90", "p99"} {
+		_, err := time.ParseDuration(got["rpc.pkg.Service.Method.latency."+key])
+		assert.NoError(t, err, key)
+	}
+
+	// The counters are reset, while the calls in flight are not
+	got = make(map[string]string)
+	m.collect("rpc.pkg.Service.Method", got)
+	assert.Equal(t, map[string]string{
+		"rpc.pkg.Service.Method.requests":        "0",
+		"rpc.pkg.Service.Method.in_flight":       "1",
+		"rpc.pkg.Service.Method.errors":          "0",
+		"rpc.pkg.Service.Method.stream.sent":     "0",
+		"rpc.pkg.Service.Method.stream.received": "0",
+	}, got)
+}
+
+func Test_methodMetrics_latencyWindow(t *testing.T) {
+	m := newMethodMetrics()
+	for range latencyWindow + 10 {
+		m.end(m.begin(), nil)
+	}
+	assert.Len(t, m.latencies, latencyWindow)
+	assert.Equal(t, 10, m.next)
+	assert.Equal(t, uint64(latencyWindow+10), m.requests)
+}
+
+func Test_metricsKey(t *testing.T) {
+	assert.Equal(t, "rpc.synternet.rpc.TestService.Test", metricsKey("/synternet.rpc.TestService/Test"))
+}
@@ -87,6 +87,279 @@ func (_c *MockPublisher_PublishTo_Call) RunAndReturn(run func(protoreflect.Proto
 	return _c
 }
 
+// PublishToRpc provides a mock function with given fields: msg, replyTo, tokens
+func (_m *MockPublisher) PublishToRpc(msg protoreflect.ProtoMessage, replyTo string, tokens ...string) error {
+	_va := make([]interface{}, len(tokens))
+	for _i := range tokens {
+		_va[_i] = tokens[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, msg, replyTo)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PublishToRpc")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(protoreflect.ProtoMessage, string, ...string) error); ok {
+		r0 = rf(msg, replyTo, tokens...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPublisher_PublishToRpc_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PublishToRpc'
+type MockPublisher_PublishToRpc_Call struct {
+	*mock.Call
+}
+
+// PublishToRpc is a helper method to define mock.On call
+//   - msg protoreflect.ProtoMessage
+//   - replyTo string
+//   - tokens ...string
+func (_e *MockPublisher_Expecter) PublishToRpc(msg interface{}, replyTo interface{}, tokens ...interface{}) *MockPublisher_PublishToRpc_Call {
+	return &MockPublisher_PublishToRpc_Call{Call: _e.mock.On("PublishToRpc",
+		append([]interface{}{msg, replyTo}, tokens...)...)}
+}
+
+func (_c *MockPublisher_PublishToRpc_Call) Run(run func(msg protoreflect.ProtoMessage, replyTo string, tokens ...string)) *MockPublisher_PublishToRpc_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(protoreflect.ProtoMessage), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPublisher_PublishToRpc_Call) Return(_a0 error) *MockPublisher_PublishToRpc_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPublisher_PublishToRpc_Call) RunAndReturn(run func(protoreflect.ProtoMessage, string, ...string) error) *MockPublisher_PublishToRpc_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PublishToRpcWithHeader provides a mock function with given fields: msg, header, replyTo, tokens
+func (_m *MockPublisher) PublishToRpcWithHeader(msg protoreflect.ProtoMessage, header nats.Header, replyTo string, tokens ...string) error {
+	_va := make([]interface{}, len(tokens))
+	for _i := range tokens {
+		_va[_i] = tokens[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, msg, header, replyTo)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PublishToRpcWithHeader")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(protoreflect.ProtoMessage, nats.Header, string, ...string) error); ok {
+		r0 = rf(msg, header, replyTo, tokens...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPublisher_PublishToRpcWithHeader_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PublishToRpcWithHeader'
+type MockPublisher_PublishToRpcWithHeader_Call struct {
+	*mock.Call
+}
+
+// PublishToRpcWithHeader is a helper method to define mock.On call
+//   - msg protoreflect.ProtoMessage
+//   - header nats.Header
+//   - replyTo string
+//   - tokens ...string
+func (_e *MockPublisher_Expecter) PublishToRpcWithHeader(msg interface{}, header interface{}, replyTo interface{}, tokens ...interface{}) *MockPublisher_PublishToRpcWithHeader_Call {
+	return &MockPublisher_PublishToRpcWithHeader_Call{Call: _e.mock.On("PublishToRpcWithHeader",
+		append([]interface{}{msg, header, replyTo}, tokens...)...)}
+}
+
+func (_c *MockPublisher_PublishToRpcWithHeader_Call) Run(run func(msg protoreflect.ProtoMessage, header nats.Header, replyTo string, tokens ...string)) *MockPublisher_PublishToRpcWithHeader_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(protoreflect.ProtoMessage), args[1].(nats.Header), args[2].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPublisher_PublishToRpcWithHeader_Call) Return(_a0 error) *MockPublisher_PublishToRpcWithHeader_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPublisher_PublishToRpcWithHeader_Call) RunAndReturn(run func(protoreflect.ProtoMessage, nats.Header, string, ...string) error) *MockPublisher_PublishToRpcWithHeader_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueueServeWithHeader provides a mock function with given fields: handler, queue, suffixes
+func (_m *MockPublisher) QueueServeWithHeader(handler service.ServiceHeaderHandler, queue string, suffixes ...string) (*nats.Subscription, error) {
+	_va := make([]interface{}, len(suffixes))
+	for _i := range suffixes {
+		_va[_i] = suffixes[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, handler, queue)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueueServeWithHeader")
+	}
+
+	var r0 *nats.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(service.ServiceHeaderHandler, string, ...string) (*nats.Subscription, error)); ok {
+		return rf(handler, queue, suffixes...)
+	}
+	if rf, ok := ret.Get(0).(func(service.ServiceHeaderHandler, string, ...string) *nats.Subscription); ok {
+		r0 = rf(handler, queue, suffixes...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*nats.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(service.ServiceHeaderHandler, string, ...string) error); ok {
+		r1 = rf(handler, queue, suffixes...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPublisher_QueueServeWithHeader_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueueServeWithHeader'
+type MockPublisher_QueueServeWithHeader_Call struct {
+	*mock.Call
+}
+
+// QueueServeWithHeader is a helper method to define mock.On call
+//   - handler service.ServiceHeaderHandler
+//   - queue string
+//   - suffixes ...string
+func (_e *MockPublisher_Expecter) QueueServeWithHeader(handler interface{}, queue interface{}, suffixes ...interface{}) *MockPublisher_QueueServeWithHeader_Call {
+	return &MockPublisher_QueueServeWithHeader_Call{Call: _e.mock.On("QueueServeWithHeader",
+		append([]interface{}{handler, queue}, suffixes...)...)}
+}
+
+func (_c *MockPublisher_QueueServeWithHeader_Call) Run(run func(handler service.ServiceHeaderHandler, queue string, suffixes ...string)) *MockPublisher_QueueServeWithHeader_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(service.ServiceHeaderHandler), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPublisher_QueueServeWithHeader_Call) Return(_a0 *nats.Subscription, _a1 error) *MockPublisher_QueueServeWithHeader_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPublisher_QueueServeWithHeader_Call) RunAndReturn(run func(service.ServiceHeaderHandler, string, ...string) (*nats.Subscription, error)) *MockPublisher_QueueServeWithHeader_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueueSubscribeTo provides a mock function with given fields: handler, queue, tokens
+func (_m *MockPublisher) QueueSubscribeTo(handler service.MessageHandler, queue string, tokens ...string) (*nats.Subscription, error) {
+	_va := make([]interface{}, len(tokens))
+	for _i := range tokens {
+		_va[_i] = tokens[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, handler, queue)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueueSubscribeTo")
+	}
+
+	var r0 *nats.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(service.MessageHandler, string, ...string) (*nats.Subscription, error)); ok {
+		return rf(handler, queue, tokens...)
+	}
+	if rf, ok := ret.Get(0).(func(service.MessageHandler, string, ...string) *nats.Subscription); ok {
+		r0 = rf(handler, queue, tokens...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*nats.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(service.MessageHandler, string, ...string) error); ok {
+		r1 = rf(handler, queue, tokens...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPublisher_QueueSubscribeTo_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueueSubscribeTo'
+type MockPublisher_QueueSubscribeTo_Call struct {
+	*mock.Call
+}
+
+// QueueSubscribeTo is a helper method to define mock.On call
+//   - handler service.MessageHandler
+//   - queue string
+//   - tokens ...string
+func (_e *MockPublisher_Expecter) QueueSubscribeTo(handler interface{}, queue interface{}, tokens ...interface{}) *MockPublisher_QueueSubscribeTo_Call {
+	return &MockPublisher_QueueSubscribeTo_Call{Call: _e.mock.On("QueueSubscribeTo",
+		append([]interface{}{handler, queue}, tokens...)...)}
+}
+
+func (_c *MockPublisher_QueueSubscribeTo_Call) Run(run func(handler service.MessageHandler, queue string, tokens ...string)) *MockPublisher_QueueSubscribeTo_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(service.MessageHandler), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPublisher_QueueSubscribeTo_Call) Return(_a0 *nats.Subscription, _a1 error) *MockPublisher_QueueSubscribeTo_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPublisher_QueueSubscribeTo_Call) RunAndReturn(run func(service.MessageHandler, string, ...string) (*nats.Subscription, error)) *MockPublisher_QueueSubscribeTo_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // RequestFrom provides a mock function with given fields: ctx, msg, resp, tokens
 func (_m *MockPublisher) RequestFrom(ctx context.Context, msg protoreflect.ProtoMessage, resp protoreflect.ProtoMessage, tokens ...string) (service.Message, error) {
 	_va := make([]interface{}, len(tokens))
@@ -162,6 +435,216 @@ func (_c *MockPublisher_RequestFrom_Call) RunAndReturn(run func(context.Context,
 	return _c
 }
 
+// RequestFromWithHeader provides a mock function with given fields: ctx, msg, resp, header, tokens
+func (_m *MockPublisher) RequestFromWithHeader(ctx context.Context, msg protoreflect.ProtoMessage, resp protoreflect.ProtoMessage, header nats.Header, tokens ...string) (service.Message, error) {
+	_va := make([]interface{}, len(tokens))
+	for _i := range tokens {
+		_va[_i] = tokens[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, msg, resp, header)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequestFromWithHeader")
+	}
+
+	var r0 service.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, protoreflect.ProtoMessage, protoreflect.ProtoMessage, nats.Header, ...string) (service.Message, error)); ok {
+		return rf(ctx, msg, resp, header, tokens...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, protoreflect.ProtoMessage, protoreflect.ProtoMessage, nats.Header, ...string) service.Message); ok {
+		r0 = rf(ctx, msg, resp, header, tokens...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(service.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, protoreflect.ProtoMessage, protoreflect.ProtoMessage, nats.Header, ...string) error); ok {
+		r1 = rf(ctx, msg, resp, header, tokens...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPublisher_RequestFromWithHeader_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RequestFromWithHeader'
+type MockPublisher_RequestFromWithHeader_Call struct {
+	*mock.Call
+}
+
+// RequestFromWithHeader is a helper method to define mock.On call
+//   - ctx context.Context
+//   - msg protoreflect.ProtoMessage
+//   - resp protoreflect.ProtoMessage
+//   - header nats.Header
+//   - tokens ...string
+func (_e *MockPublisher_Expecter) RequestFromWithHeader(ctx interface{}, msg interface{}, resp interface{}, header interface{}, tokens ...interface{}) *MockPublisher_RequestFromWithHeader_Call {
+	return &MockPublisher_RequestFromWithHeader_Call{Call: _e.mock.On("RequestFromWithHeader",
+		append([]interface{}{ctx, msg, resp, header}, tokens...)...)}
+}
+
+func (_c *MockPublisher_RequestFromWithHeader_Call) Run(run func(ctx context.Context, msg protoreflect.ProtoMessage, resp protoreflect.ProtoMessage, header nats.Header, tokens ...string)) *MockPublisher_RequestFromWithHeader_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-4)
+		for i, a := range args[4:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(context.Context), args[1].(protoreflect.ProtoMessage), args[2].(protoreflect.ProtoMessage), args[3].(nats.Header), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPublisher_RequestFromWithHeader_Call) Return(_a0 service.Message, _a1 error) *MockPublisher_RequestFromWithHeader_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPublisher_RequestFromWithHeader_Call) RunAndReturn(run func(context.Context, protoreflect.ProtoMessage, protoreflect.ProtoMessage, nats.Header, ...string) (service.Message, error)) *MockPublisher_RequestFromWithHeader_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RequestMany provides a mock function with given fields: ctx, msg, opts, tokens
+func (_m *MockPublisher) RequestMany(ctx context.Context, msg protoreflect.ProtoMessage, opts service.RequestManyOptions, tokens ...string) (<-chan service.Response, error) {
+	_va := make([]interface{}, len(tokens))
+	for _i := range tokens {
+		_va[_i] = tokens[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, msg, opts)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequestMany")
+	}
+
+	var r0 <-chan service.Response
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, protoreflect.ProtoMessage, service.RequestManyOptions, ...string) (<-chan service.Response, error)); ok {
+		return rf(ctx, msg, opts, tokens...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, protoreflect.ProtoMessage, service.RequestManyOptions, ...string) <-chan service.Response); ok {
+		r0 = rf(ctx, msg, opts, tokens...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan service.Response)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, protoreflect.ProtoMessage, service.RequestManyOptions, ...string) error); ok {
+		r1 = rf(ctx, msg, opts, tokens...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPublisher_RequestMany_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RequestMany'
+type MockPublisher_RequestMany_Call struct {
+	*mock.Call
+}
+
+// RequestMany is a helper method to define mock.On call
+//   - ctx context.Context
+//   - msg protoreflect.ProtoMessage
+//   - opts service.RequestManyOptions
+//   - tokens ...string
+func (_e *MockPublisher_Expecter) RequestMany(ctx interface{}, msg interface{}, opts interface{}, tokens ...interface{}) *MockPublisher_RequestMany_Call {
+	return &MockPublisher_RequestMany_Call{Call: _e.mock.On("RequestMany",
+		append([]interface{}{ctx, msg, opts}, tokens...)...)}
+}
+
+func (_c *MockPublisher_RequestMany_Call) Run(run func(ctx context.Context, msg protoreflect.ProtoMessage, opts service.RequestManyOptions, tokens ...string)) *MockPublisher_RequestMany_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(context.Context), args[1].(protoreflect.ProtoMessage), args[2].(service.RequestManyOptions), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPublisher_RequestMany_Call) Return(_a0 <-chan service.Response, _a1 error) *MockPublisher_RequestMany_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPublisher_RequestMany_Call) RunAndReturn(run func(context.Context, protoreflect.ProtoMessage, service.RequestManyOptions, ...string) (<-chan service.Response, error)) *MockPublisher_RequestMany_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RpcInbox provides a mock function with given fields: suffixes
+func (_m *MockPublisher) RpcInbox(suffixes ...string) string {
+	_va := make([]interface{}, len(suffixes))
+	for _i := range suffixes {
+		_va[_i] = suffixes[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RpcInbox")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(...string) string); ok {
+		r0 = rf(suffixes...)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MockPublisher_RpcInbox_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RpcInbox'
+type MockPublisher_RpcInbox_Call struct {
+	*mock.Call
+}
+
+// RpcInbox is a helper method to define mock.On call
+//   - suffixes ...string
+func (_e *MockPublisher_Expecter) RpcInbox(suffixes ...interface{}) *MockPublisher_RpcInbox_Call {
+	return &MockPublisher_RpcInbox_Call{Call: _e.mock.On("RpcInbox",
+		append([]interface{}{}, suffixes...)...)}
+}
+
+func (_c *MockPublisher_RpcInbox_Call) Run(run func(suffixes ...string)) *MockPublisher_RpcInbox_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-0)
+		for i, a := range args[0:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPublisher_RpcInbox_Call) Return(_a0 string) *MockPublisher_RpcInbox_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPublisher_RpcInbox_Call) RunAndReturn(run func(...string) string) *MockPublisher_RpcInbox_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Serve provides a mock function with given fields: handler, suffixes
 func (_m *MockPublisher) Serve(handler service.ServiceHandler, suffixes ...string) (*nats.Subscription, error) {
 	_va := make([]interface{}, len(suffixes))
@@ -235,6 +718,79 @@ func (_c *MockPublisher_Serve_Call) RunAndReturn(run func(service.ServiceHandler
 	return _c
 }
 
+// ServeWithHeader provides a mock function with given fields: handler, suffixes
+func (_m *MockPublisher) ServeWithHeader(handler service.ServiceHeaderHandler, suffixes ...string) (*nats.Subscription, error) {
+	_va := make([]interface{}, len(suffixes))
+	for _i := range suffixes {
+		_va[_i] = suffixes[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, handler)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ServeWithHeader")
+	}
+
+	var r0 *nats.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(service.ServiceHeaderHandler, ...string) (*nats.Subscription, error)); ok {
+		return rf(handler, suffixes...)
+	}
+	if rf, ok := ret.Get(0).(func(service.ServiceHeaderHandler, ...string) *nats.Subscription); ok {
+		r0 = rf(handler, suffixes...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*nats.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(service.ServiceHeaderHandler, ...string) error); ok {
+		r1 = rf(handler, suffixes...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPublisher_ServeWithHeader_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ServeWithHeader'
+type MockPublisher_ServeWithHeader_Call struct {
+	*mock.Call
+}
+
+// ServeWithHeader is a helper method to define mock.On call
+//   - handler service.ServiceHeaderHandler
+//   - suffixes ...string
+func (_e *MockPublisher_Expecter) ServeWithHeader(handler interface{}, suffixes ...interface{}) *MockPublisher_ServeWithHeader_Call {
+	return &MockPublisher_ServeWithHeader_Call{Call: _e.mock.On("ServeWithHeader",
+		append([]interface{}{handler}, suffixes...)...)}
+}
+
+func (_c *MockPublisher_ServeWithHeader_Call) Run(run func(handler service.ServiceHeaderHandler, suffixes ...string)) *MockPublisher_ServeWithHeader_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-1)
+		for i, a := range args[1:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(service.ServiceHeaderHandler), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPublisher_ServeWithHeader_Call) Return(_a0 *nats.Subscription, _a1 error) *MockPublisher_ServeWithHeader_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPublisher_ServeWithHeader_Call) RunAndReturn(run func(service.ServiceHeaderHandler, ...string) (*nats.Subscription, error)) *MockPublisher_ServeWithHeader_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Subject provides a mock function with given fields: suffixes
 func (_m *MockPublisher) Subject(suffixes ...string) string {
 	_va := make([]interface{}, len(suffixes))
@@ -20,6 +20,7 @@ type Message struct {
 	ch           chan service.Message
 	replySubject string
 	subject      string
+	header       nats.Header
 }
 
 func NewMsg(t *testing.T, data []byte, ch chan service.Message, replySubject, subject string) *Message {
@@ -32,6 +33,12 @@ func NewMsg(t *testing.T, data []byte, ch chan service.Message, replySubject, su
 	}
 }
 
+func NewMsgWithHeader(t *testing.T, data []byte, header nats.Header, ch chan service.Message, replySubject, subject string) *Message {
+	msg := NewMsg(t, data, ch, replySubject, subject)
+	msg.header = header
+	return msg
+}
+
 // Ack implements service.Message.
 func (m *Message) Ack(opts ...nats.AckOpt) error {
 	m.t.Log("msg Ack")
@@ -56,9 +63,13 @@ func (m *Message) Equal(msg service.Message) bool {
 
 // Header implements service.Message.
 func (m *Message) Header() nats.Header {
-	return nats.Header{
+	header := nats.Header{
 		"identity": []string{"some", "identity"},
 	}
+	for k, v := range m.header {
+		header[k] = v
+	}
+	return header
 }
 
 // InProgress implements service.Message.
@@ -99,13 +110,18 @@ func (m *Message) Reply() string {
 
 // Respond implements service.Message.
 func (m *Message) Respond(msg proto.Message) error {
+	return m.RespondWithHeader(msg, nil)
+}
+
+// RespondWithHeader implements service.Message.
+func (m *Message) RespondWithHeader(msg proto.Message, header nats.Header) error {
 	msgData, err := protojson.Marshal(msg)
 	if err != nil {
 		return err
 	}
 
 	m.t.Logf("message Respond: reply=%s subj=%s msg=%s ch=%v", m.replySubject, m.subject, reflect.TypeOf(msg).Name(), m.ch)
-	m.ch <- NewMsg(m.t, msgData, nil, "", m.replySubject)
+	m.ch <- NewMsgWithHeader(m.t, msgData, header, nil, "", m.replySubject)
 	return nil
 }
 
@@ -0,0 +1,34 @@
+package rpc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/service"
+)
+
+// natsPrefix is the prefix of the subjects served by the service returned by makeNatsService.
+const natsPrefix = "test.prefix"
+
+// makeNatsService returns a started service connected to an embedded NATS server. Unlike the fake Publisher,
+// the server honours Unsubscribe and Drain, reports no responders for unserved subjects, and runs JetStream.
+func makeNatsService(t *testing.T, ctx context.Context) *service.Service {
+	opts := test.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	srv := test.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+
+	nc, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+
+	svc := &service.Service{}
+	require.NoError(t, svc.Configure(service.WithContext(ctx), service.WithNats(nc), service.WithPrefix("test"), service.WithName("prefix")))
+	svc.Start()
+	return svc
+}
@@ -0,0 +1,301 @@
+package rpc
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	service "github.com/synternet/data-layer-sdk/pkg/service"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// InterestProbeHeader marks the requests that probe whether a pure stream has consumers. The probes are sent on the stream
+// subject itself, since NATS reports no responders only if nobody is subscribed to it. Subscribers receive the probes
+// as empty messages and must drop them, which ClientConn and the generated Data Layer clients do. See IsInterestProbe.
+const InterestProbeHeader = "rpc-interest-probe"
+
+// interestProbeStream is the stream the probes expect to be stored in. It is not a valid stream name, so JetStream streams
+// capturing the subject reject the probes instead of storing them, and the rejections show the interest of the streams.
+const interestProbeStream = "rpc.interest.probe"
+
+// IsInterestProbe reports whether the message is a probe of the producer of a pure stream, rather than a stream message.
+func IsInterestProbe(msg service.Message) bool {
+	return msg.Header().Get(InterestProbeHeader) != ""
+}
+
+// ProducerPolicy configures how the registrar runs producers, i.e. the handlers of pure streams with `disable_inputs` option.
+// See WithProducerPolicy.
+type ProducerPolicy struct {
+	// InitialBackoff and MaxBackoff limit the delay before restarting a failed producer, which grows with BackoffMultiplier
+	// after every consecutive failure. The defaults are 1s, 1m and 2.
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	// OnInterest runs the producers only while at least one consumer is subscribed to their subjects, including JetStream
+	// streams capturing the subjects. Every producer probes the interest every InterestPeriod, 1s by default, with a request
+	// on its subject that has no responders if there are no consumers. See InterestProbeHeader.
+	OnInterest     bool
+	InterestPeriod time.Duration
+}
+
+// WithProducerPolicy sets how the producers of pure streams are restarted and whether they run only while they have consumers.
+func WithProducerPolicy(policy ProducerPolicy) RegistrarOption {
+	return func(s *ServiceRegistrar) {
+		s.producerPolicy = policy
+	}
+}
+
+func (p ProducerPolicy) withDefaults() ProducerPolicy {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = time.Second
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = time.Minute
+	}
+	if p.BackoffMultiplier <= 0 {
+		p.BackoffMultiplier = 2
+	}
+	if p.InterestPeriod <= 0 {
+		p.InterestPeriod = time.Second
+	}
+	return p
+}
+
+// ProducerState is the state of a producer.
+type ProducerState string
+
+const (
+	// ProducerIdle producer waits for a consumer.
+	ProducerIdle ProducerState = "idle"
+	// ProducerRunning producer is running.
+	ProducerRunning ProducerState = "running"
+	// ProducerBackoff producer has failed, and waits to be restarted.
+	ProducerBackoff ProducerState = "backoff"
+	// ProducerFinished producer has returned without an error, so it is not restarted.
+	ProducerFinished ProducerState = "finished"
+	// ProducerStopped producer is stopped, since the context passed to Start is done, or the registrar has stopped serving it.
+	ProducerStopped ProducerState = "stopped"
+)
+
+// ProducerStatus describes a producer of a pure stream.
+type ProducerStatus struct {
+	// Method is the full name of the method, e.g. `/pkg.Service/Method`.
+	Method   string
+	Subject  string
+	State    ProducerState
+	Restarts int
+	// LastError is the last error the producer has failed with.
+	LastError error
+}
+
+// Producers returns the status of the producers of pure streams started by Start, sorted by method name.
+func (s *ServiceRegistrar) Producers() []ProducerStatus {
+	s.mu.Lock()
+	producers := slices.Clone(s.producers)
+	s.mu.Unlock()
+
+	ret := make([]ProducerStatus, len(producers))
+	for i, p := range producers {
+		ret[i] = p.status()
+	}
+	slices.SortFunc(ret, func(a, b ProducerStatus) int {
+		return cmp.Compare(a.Method, b.Method)
+	})
+	return ret
+}
+
+// producerTelemetry returns the telemetry status of the producers.
+func (s *ServiceRegistrar) producerTelemetry() map[string]string {
+	ret := make(map[string]string)
+	for _, p := range s.Producers() {
+		key := metricsKey(p.Method) + ".producer"
+		ret[key+".state"] = string(p.State)
+		ret[key+".restarts"] = strconv.Itoa(p.Restarts)
+		if p.LastError != nil {
+			ret[key+".error"] = p.LastError.Error()
+		}
+	}
+	return ret
+}
+
+// startProducer runs the producer of the pure stream in the group until the context is done, or the producer is cancelled.
+// It must be called with the registrar lock held.
+func (s *ServiceRegistrar) startProducer(ctx context.Context, svc *serviceInfo, stream *grpc.StreamDesc, subject string) *producer {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &producer{
+		registrar: s,
+		svc:       svc,
+		stream:    stream,
+		method:    fmt.Sprintf("/%s/%s", svc.serviceDesc.ServiceName, stream.StreamName),
+		subject:   subject,
+		policy:    s.producerPolicy.withDefaults(),
+		state:     ProducerIdle,
+		cancel:    cancel,
+	}
+	p.metrics = s.methodMetrics(p.method)
+	s.producers = append(s.producers, p)
+	s.running.add()
+	s.group.Go(func() error {
+		defer s.running.done()
+		defer cancel()
+		p.run(ctx)
+		return nil
+	})
+	return p
+}
+
+// producer runs the handler of a pure stream, and restarts it with backoff if it fails.
+type producer struct {
+	registrar *ServiceRegistrar
+	svc       *serviceInfo
+	stream    *grpc.StreamDesc
+	method    string
+	subject   string
+	policy    ProducerPolicy
+	cancel    context.CancelFunc
+	metrics   *methodMetrics
+
+	mu       sync.Mutex
+	state    ProducerState
+	restarts int
+	lastErr  error
+}
+
+func (p *producer) status() ProducerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return ProducerStatus{
+		Method:    p.method,
+		Subject:   p.subject,
+		State:     p.state,
+		Restarts:  p.restarts,
+		LastError: p.lastErr,
+	}
+}
+
+func (p *producer) setState(state ProducerState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = state
+}
+
+// fail records the error of the producer and returns the number of restarts so far.
+func (p *producer) fail(err error) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = ProducerBackoff
+	p.lastErr = err
+	p.restarts++
+	return p.restarts
+}
+
+func (p *producer) run(ctx context.Context) {
+	defer p.setState(ProducerStopped)
+	backoff := RetryPolicy{InitialBackoff: p.policy.InitialBackoff, MaxBackoff: p.policy.MaxBackoff, BackoffMultiplier: p.policy.BackoffMultiplier}
+	failures := 0
+	for ctx.Err() == nil {
+		if p.policy.OnInterest {
+			p.setState(ProducerIdle)
+			if !p.waitInterest(ctx) {
+				return
+			}
+		}
+
+		p.setState(ProducerRunning)
+		started := time.Now()
+		runCtx, cancel := context.WithCancel(ctx)
+		if p.policy.OnInterest {
+			go p.watchInterest(runCtx, cancel)
+		}
+		err := p.produce(runCtx)
+		lostInterest := runCtx.Err() != nil
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return
+		case lostInterest:
+			slog.Debug("ServiceRegistrar: producer has no consumers", "method", p.method, "subject", p.subject)
+			continue
+		case err == nil:
+			p.setState(ProducerFinished)
+			return
+		}
+
+		// A producer that has been running for a while starts over with the initial backoff
+		if time.Since(started) > p.policy.MaxBackoff {
+			failures = 0
+		}
+		failures++
+		restarts := p.fail(err)
+		delay := backoff.backoff(failures)
+		slog.Warn("ServiceRegistrar: producer failed", "err", err, "method", p.method, "subject", p.subject, "restarts", restarts, "delay", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// produce calls the stream handler until it returns.
+func (p *producer) produce(ctx context.Context) error {
+	ss := &serverStream{msg: nil, ctx: ctx, pub: p.registrar.pub, subject: p.subject, metrics: p.metrics}
+	started := p.metrics.begin()
+	err := p.registrar.handleStream(p.svc, p.stream, ss)
+	p.metrics.end(started, err)
+	return err
+}
+
+// waitInterest waits until the subject has a consumer. It returns false if the context is done before.
+func (p *producer) waitInterest(ctx context.Context) bool {
+	ticker := time.NewTicker(p.policy.InterestPeriod)
+	defer ticker.Stop()
+	for !p.interested(ctx) {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// watchInterest cancels the producer once the subject has no consumers.
+func (p *producer) watchInterest(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(p.policy.InterestPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !p.interested(ctx) && ctx.Err() == nil {
+			cancel()
+			return
+		}
+	}
+}
+
+// interested probes the subject with a request. There are no responders only if nobody is subscribed to the subject,
+// while consumers drop the probe, so the request times out, and JetStream streams reject it.
+func (p *producer) interested(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, p.policy.InterestPeriod/2)
+	defer cancel()
+	header := nats.Header{}
+	header.Set(InterestProbeHeader, "1")
+	header.Set(nats.ExpectedStreamHdr, interestProbeStream)
+	_, err := p.registrar.pub.RequestFromWithHeader(ctx, &emptypb.Empty{}, nil, header, p.subject)
+	return !errors.Is(err, nats.ErrNoResponders)
+}
@@ -0,0 +1,167 @@
+package rpc_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	"github.com/synternet/data-layer-sdk/pkg/service"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestProducerRestarts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	grp, ctx := errgroup.WithContext(ctx)
+	pub := NewPublisher(ctx, t, "test_prefix")
+	srv := rpc.NewServiceRegistrar(grp, pub, rpc.WithoutHealth(), rpc.WithoutReflection(), rpc.WithProducerPolicy(rpc.ProducerPolicy{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond * 5,
+	}))
+	// The unimplemented producer fails right away
+	rpctypes.RegisterTestServiceServer(srv, rpctypes.UnimplementedTestServiceServer{})
+	require.NoError(t, srv.Start(ctx, map[string]string{"variable": "1"}))
+
+	assert.Eventually(t, func() bool {
+		producers := srv.Producers()
+		return len(producers) == 1 && producers[0].Restarts >= 3
+	}, time.Millisecond*500, time.Millisecond)
+
+	producer := srv.Producers()[0]
+	assert.Equal(t, "/synternet.rpc.TestService/TestStreamOnly", producer.Method)
+	assert.Equal(t, "test_prefix.override.test.override.test.stream.data", producer.Subject)
+	assert.Equal(t, codes.Unimplemented, status.Code(producer.LastError))
+
+	cancel()
+	require.NoError(t, grp.Wait())
+	assert.Equal(t, rpc.ProducerStopped, srv.Producers()[0].State)
+}
+
+func TestProducerOnInterest(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	grp, ctx := errgroup.WithContext(ctx)
+	pub := NewPublisher(ctx, t, "test_prefix")
+	srv := rpc.NewServiceRegistrar(grp, pub, rpc.WithoutHealth(), rpc.WithoutReflection(), rpc.WithProducerPolicy(rpc.ProducerPolicy{
+		OnInterest:     true,
+		InterestPeriod: time.Millisecond * 10,
+	}))
+	rpctypes.RegisterTestServiceServer(srv, &Test{t: t})
+	require.NoError(t, srv.Start(ctx, map[string]string{"variable": "1"}))
+
+	time.Sleep(time.Millisecond * 50)
+	producer := srv.Producers()[0]
+	assert.Equal(t, rpc.ProducerIdle, producer.State)
+
+	var received, probes atomic.Int32
+	_, err := pub.SubscribeTo(func(msg service.Message) {
+		if rpc.IsInterestProbe(msg) {
+			probes.Add(1)
+			return
+		}
+		received.Add(1)
+	}, producer.Subject)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return srv.Producers()[0].State == rpc.ProducerRunning && received.Load() > 0
+	}, time.Millisecond*500, time.Millisecond)
+	assert.NotZero(t, probes.Load())
+	assert.Zero(t, srv.Producers()[0].Restarts)
+
+	cancel()
+	require.NoError(t, grp.Wait())
+	assert.Equal(t, rpc.ProducerStopped, srv.Producers()[0].State)
+}
+
+// tickingProducer sends a stream message every millisecond rather than flooding the server.
+type tickingProducer struct {
+	rpctypes.UnimplementedTestServiceServer
+}
+
+func (tickingProducer) TestStreamOnly(_ *emptypb.Empty, srv rpctypes.TestService_TestStreamOnlyServer) error {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-srv.Context().Done():
+			return srv.Context().Err()
+		case <-ticker.C:
+		}
+		if err := srv.Send(&rpctypes.TestResponse{}); err != nil {
+			return err
+		}
+	}
+}
+
+func TestProducerOnInterestNats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	grp, gctx := errgroup.WithContext(ctx)
+	pub := makeNatsService(t, gctx)
+	nc := pub.SubNats.(*nats.Conn)
+	srv := rpc.NewServiceRegistrar(grp, pub, rpc.WithoutHealth(), rpc.WithoutReflection(), rpc.WithProducerPolicy(rpc.ProducerPolicy{
+		OnInterest:     true,
+		InterestPeriod: time.Millisecond * 20,
+	}))
+	rpctypes.RegisterTestServiceServer(srv, tickingProducer{})
+	require.NoError(t, srv.Start(gctx, map[string]string{"variable": "1"}))
+	state := func() rpc.ProducerState { return srv.Producers()[0].State }
+	subject := srv.Producers()[0].Subject
+
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, rpc.ProducerIdle, state())
+
+	// A plain subscriber starts the producer, and the producer goes idle once it unsubscribes
+	var received atomic.Int32
+	sub, err := nc.Subscribe(subject, func(msg *nats.Msg) {
+		if msg.Header.Get(rpc.InterestProbeHeader) == "" {
+			received.Add(1)
+		}
+	})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return state() == rpc.ProducerRunning && received.Load() > 0 }, time.Second, time.Millisecond)
+	require.NoError(t, sub.Unsubscribe())
+	assert.Eventually(t, func() bool { return state() == rpc.ProducerIdle }, time.Second, time.Millisecond)
+
+	// A JetStream stream capturing the subject keeps the producer running, and does not store the probes
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "producer", Subjects: []string{subject}, MaxMsgs: 1000})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return state() == rpc.ProducerRunning }, time.Second, time.Millisecond)
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, rpc.ProducerRunning, state())
+
+	stored, err := js.SubscribeSync(subject, nats.BindStream("producer"), nats.DeliverAll(), nats.AckNone())
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		msg, err := stored.NextMsg(time.Second)
+		require.NoError(t, err)
+		assert.Empty(t, msg.Header.Get(rpc.InterestProbeHeader))
+	}
+	require.NoError(t, stored.Unsubscribe())
+	info, err := js.StreamInfo("producer")
+	require.NoError(t, err)
+	assert.NotZero(t, info.State.Msgs)
+
+	// Deleting the stream removes the last consumer
+	require.NoError(t, js.DeleteStream("producer"))
+	assert.Eventually(t, func() bool { return state() == rpc.ProducerIdle }, time.Second, time.Millisecond)
+	assert.Zero(t, srv.Producers()[0].Restarts)
+
+	cancel()
+	require.NoError(t, grp.Wait())
+}
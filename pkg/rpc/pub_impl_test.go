@@ -2,7 +2,7 @@ package rpc_test
 
 import (
 	"context"
-	"encoding/json"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -15,25 +15,70 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-var _ rpc.Publisher = (*Publisher)(nil)
+var _ rpc.StatusPublisher = (*Publisher)(nil)
 
 type Publisher struct {
 	ctx     context.Context
 	t       *testing.T
 	mu      sync.Mutex
 	streams map[string]chan service.Message
-	prefix  string
+	queues  map[string]string
+	// handlers of every subject served, so that RequestMany can reach all of them
+	handlers map[string][]service.ServiceHeaderHandler
+	// subscribed subjects, so that requests to subjects nobody listens to fail with nats.ErrNoResponders
+	subscribed map[string]bool
+	prefix     string
+	callbacks  []service.StatusFunc
 }
 
 func NewPublisher(ctx context.Context, t *testing.T, prefix string) *Publisher {
 	return &Publisher{
-		ctx:     ctx,
-		t:       t,
-		streams: make(map[string]chan service.Message),
-		prefix:  prefix,
+		ctx:        ctx,
+		t:          t,
+		streams:    make(map[string]chan service.Message),
+		queues:     make(map[string]string),
+		handlers:   make(map[string][]service.ServiceHeaderHandler),
+		subscribed: make(map[string]bool),
+		prefix:     prefix,
 	}
 }
 
+// AddStatusCallback implements rpc.StatusPublisher.
+func (p *Publisher) AddStatusCallback(callback service.StatusFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callbacks = append(p.callbacks, callback)
+}
+
+// Status collects the status from the callbacks, like service.Service does for telemetry.
+func (p *Publisher) Status() map[string]string {
+	p.mu.Lock()
+	callbacks := slices.Clone(p.callbacks)
+	p.mu.Unlock()
+	status := make(map[string]string)
+	for _, callback := range callbacks {
+		for k, v := range callback() {
+			status[k] = v
+		}
+	}
+	return status
+}
+
+// Queue returns the queue group the subject was subscribed with. Subscribers of the same subject always
+// compete for the messages, like a queue group does.
+func (p *Publisher) Queue(subj string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	q, ok := p.queues[subj]
+	return q, ok
+}
+
+func (p *Publisher) setQueue(subj, queue string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queues[subj] = queue
+}
+
 func subject(subj ...string) string {
 	return strings.Join(subj, ".")
 }
@@ -52,7 +97,42 @@ func (p *Publisher) stream(subj ...string) chan service.Message {
 
 // RequestFrom implements rpc.Publisher.
 func (p *Publisher) RequestFrom(ctx context.Context, msg proto.Message, resp proto.Message, tokens ...string) (service.Message, error) {
-	ch := p.stream(tokens...)
+	return p.RequestFromWithHeader(ctx, msg, resp, nil, tokens...)
+}
+
+// served returns the served subject that the subject is routed to. Subjects served with `*` tokens match any token
+// if the subject is not served as is.
+func (p *Publisher) served(subj string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.handlers[subj]; ok {
+		return subj
+	}
+	tokens := strings.Split(subj, ".")
+	for pattern := range p.handlers {
+		patternTokens := strings.Split(pattern, ".")
+		if slices.EqualFunc(patternTokens, tokens, func(a, b string) bool { return a == "*" || a == b }) {
+			return pattern
+		}
+	}
+	return subj
+}
+
+// listened reports whether the subject is served or subscribed to.
+func (p *Publisher) listened(subj string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.handlers[subj]
+	return ok || p.subscribed[subj]
+}
+
+// RequestFromWithHeader implements rpc.Publisher.
+func (p *Publisher) RequestFromWithHeader(ctx context.Context, msg proto.Message, resp proto.Message, header nats.Header, tokens ...string) (service.Message, error) {
+	served := p.served(subject(tokens...))
+	if !p.listened(served) {
+		return nil, nats.ErrNoResponders
+	}
+	ch := p.stream(served)
 	replyTo := p.RpcInbox(tokens...)
 	replyCh := p.stream(replyTo)
 
@@ -65,7 +145,7 @@ func (p *Publisher) RequestFrom(ctx context.Context, msg proto.Message, resp pro
 	select {
 	case <-p.ctx.Done():
 		return nil, p.ctx.Err()
-	case ch <- NewMsg(p.t, msgData, replyCh, replyTo, subject(tokens...)):
+	case ch <- NewMsgWithHeader(p.t, msgData, header, replyCh, replyTo, subject(tokens...)):
 	}
 
 	select {
@@ -75,6 +155,9 @@ func (p *Publisher) RequestFrom(ctx context.Context, msg proto.Message, resp pro
 		return nil, p.ctx.Err()
 	case data := <-replyCh:
 		p.t.Log("requestFrom: received", "replyTo=", replyTo, "sendTo=", subject(tokens...))
+		if resp == nil {
+			return data, nil
+		}
 		_, err := p.Unmarshal(data, resp)
 		return data, err
 	}
@@ -86,9 +169,20 @@ func (p *Publisher) RpcInbox(suffixes ...string) string {
 
 // Serve implements rpc.Publisher.
 func (p *Publisher) Serve(handler service.ServiceHandler, suffixes ...string) (*nats.Subscription, error) {
+	return p.ServeWithHeader(func(msg service.Message) (proto.Message, nats.Header, error) {
+		resp, err := handler(msg)
+		return resp, nil, err
+	}, suffixes...)
+}
+
+// ServeWithHeader implements rpc.Publisher.
+func (p *Publisher) ServeWithHeader(handler service.ServiceHeaderHandler, suffixes ...string) (*nats.Subscription, error) {
 	subject := p.Subject(suffixes...)
 	ch := p.stream(subject)
 	p.t.Log("serve", "listenTo=", subject, "ch=", ch)
+	p.mu.Lock()
+	p.handlers[subject] = append(p.handlers[subject], handler)
+	p.mu.Unlock()
 	go func() {
 		for {
 			select {
@@ -96,22 +190,11 @@ func (p *Publisher) Serve(handler service.ServiceHandler, suffixes ...string) (*
 				return
 			case data := <-ch:
 				p.t.Log("serve: received", "listenTo=", subject, "subj=", data.Subject(), "replyTo=", data.Reply())
-				reply, err := handler(data)
-				if err != nil {
-					reply = &rpctypes.Error{
-						Error: err.Error(),
-					}
-				}
 				replyCh := p.stream(data.Reply())
-				msgData, err := protojson.Marshal(reply)
-				if err != nil {
-					panic(err)
-				}
-
 				select {
 				case <-p.ctx.Done():
 					return
-				case replyCh <- NewMsg(p.t, msgData, nil, "", data.Reply()):
+				case replyCh <- p.handle(handler, data):
 					p.t.Log("serve: publish", "subj=", data.Reply())
 				}
 			}
@@ -120,10 +203,100 @@ func (p *Publisher) Serve(handler service.ServiceHandler, suffixes ...string) (*
 	return &nats.Subscription{}, nil
 }
 
+// handle calls the handler and returns the response message, which carries rpc.Error if the handler failed.
+func (p *Publisher) handle(handler service.ServiceHeaderHandler, data service.Message) *Message {
+	reply, header, err := handler(data)
+	if err != nil {
+		var errHeader nats.Header
+		reply, errHeader = service.NewRpcError(err)
+		if header == nil {
+			header = nats.Header{}
+		}
+		for k, v := range errHeader {
+			header[k] = v
+		}
+	}
+	msgData, err := protojson.Marshal(reply)
+	if err != nil {
+		panic(err)
+	}
+	return NewMsgWithHeader(p.t, msgData, header, nil, "", data.Reply())
+}
+
+// RequestMany implements rpc.Publisher. The request is handled by every handler serving the subject,
+// regardless of queue groups. Only MaxResponses stop condition is supported.
+func (p *Publisher) RequestMany(ctx context.Context, msg proto.Message, opts service.RequestManyOptions, tokens ...string) (<-chan service.Response, error) {
+	p.mu.Lock()
+	handlers := slices.Clone(p.handlers[subject(tokens...)])
+	p.mu.Unlock()
+
+	msgData, err := protojson.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	replyTo := p.RpcInbox()
+	replies := make(chan *Message, len(handlers))
+	for _, handler := range handlers {
+		go func() {
+			replies <- p.handle(handler, NewMsgWithHeader(p.t, msgData, opts.Header, nil, replyTo, subject(tokens...)))
+		}()
+	}
+
+	out := make(chan service.Response)
+	go func() {
+		defer close(out)
+		for i := range handlers {
+			if opts.MaxResponses > 0 && i >= opts.MaxResponses {
+				return
+			}
+			var reply *Message
+			select {
+			case <-ctx.Done():
+				return
+			case reply = <-replies:
+			}
+			resp := service.Response{Message: reply}
+			if reply.Header().Get(service.ErrorHeader) != "" {
+				var rpcErr rpctypes.Error
+				if _, err := p.Unmarshal(reply, &rpcErr); err != nil {
+					panic(err)
+				}
+				resp.Err = service.StatusFromRpcError(&rpcErr).Err()
+			} else if opts.Response != nil {
+				resp.Payload = opts.Response.ProtoReflect().New().Interface()
+				if _, err := p.Unmarshal(reply, resp.Payload); err != nil {
+					panic(err)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- resp:
+			}
+		}
+	}()
+	return out, nil
+}
+
+// QueueServeWithHeader implements rpc.Publisher.
+func (p *Publisher) QueueServeWithHeader(handler service.ServiceHeaderHandler, queue string, suffixes ...string) (*nats.Subscription, error) {
+	p.setQueue(p.Subject(suffixes...), queue)
+	return p.ServeWithHeader(handler, suffixes...)
+}
+
+// QueueSubscribeTo implements rpc.Publisher.
+func (p *Publisher) QueueSubscribeTo(handler service.MessageHandler, queue string, tokens ...string) (*nats.Subscription, error) {
+	p.setQueue(subject(tokens...), queue)
+	return p.SubscribeTo(handler, tokens...)
+}
+
 // SubscribeTo implements rpc.Publisher.
 func (p *Publisher) SubscribeTo(handler service.MessageHandler, tokens ...string) (*nats.Subscription, error) {
 	ch := p.stream(tokens...)
 	p.t.Log("subscribeTo", "listenTo=", subject(tokens...), "ch=", ch)
+	p.mu.Lock()
+	p.subscribed[subject(tokens...)] = true
+	p.mu.Unlock()
 	go func() {
 		for {
 			select {
@@ -157,6 +330,10 @@ func (p *Publisher) PublishTo(msg proto.Message, tokens ...string) error {
 }
 
 func (p *Publisher) PublishToRpc(msg proto.Message, replyTo string, tokens ...string) error {
+	return p.PublishToRpcWithHeader(msg, nil, replyTo, tokens...)
+}
+
+func (p *Publisher) PublishToRpcWithHeader(msg proto.Message, header nats.Header, replyTo string, tokens ...string) error {
 	ch := p.stream(tokens...)
 	replyCh := p.stream(replyTo)
 
@@ -169,7 +346,7 @@ func (p *Publisher) PublishToRpc(msg proto.Message, replyTo string, tokens ...st
 	select {
 	case <-p.ctx.Done():
 		return p.ctx.Err()
-	case ch <- NewMsg(p.t, msgData, replyCh, replyTo, subject(tokens...)):
+	case ch <- NewMsgWithHeader(p.t, msgData, header, replyCh, replyTo, subject(tokens...)):
 	}
 
 	return nil
@@ -177,7 +354,7 @@ func (p *Publisher) PublishToRpc(msg proto.Message, replyTo string, tokens ...st
 
 // Unmarshal implements rpc.Publisher.
 func (p *Publisher) Unmarshal(nmsg service.Message, msg proto.Message) (nats.Header, error) {
-	err := json.Unmarshal(nmsg.Data(), msg)
+	err := protojson.Unmarshal(nmsg.Data(), msg)
 	return nil, err
 }
 
@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// WithQueueGroup sets the queue group, so that replicas of the rpc server in the same group share the requests.
+// The name selects what the queue group applies to:
+//
+//   - empty name - all services;
+//   - fully qualified service name, e.g. `pkg.MyService` - all methods of the service;
+//   - full method name, e.g. `/pkg.MyService/Method` - a single method.
+//
+// The more specific setting wins, and Go options take precedence over `queue_group` and `method_queue_group`
+// proto options on the same level. Unary methods and streaming sessions are served using the queue group, while pure streams
+// are not affected, since they are only published to.
+func WithQueueGroup(name, queue string) RegistrarOption {
+	return func(s *ServiceRegistrar) {
+		if s.queueGroups == nil {
+			s.queueGroups = make(map[string]string)
+		}
+		s.queueGroups[name] = queue
+	}
+}
+
+// queueGroup returns the queue group of a method. Empty queue group falls back to the Publisher configuration.
+func (s *ServiceRegistrar) queueGroup(serviceDescriptor protoreflect.ServiceDescriptor, methodDescriptor protoreflect.MethodDescriptor) string {
+	if q, ok := s.queueGroups[fmt.Sprintf("/%s/%s", serviceDescriptor.FullName(), methodDescriptor.Name())]; ok {
+		return q
+	}
+	if q := getMethodQueueGroup(methodDescriptor); q != "" {
+		return q
+	}
+	if q, ok := s.queueGroups[string(serviceDescriptor.FullName())]; ok {
+		return q
+	}
+	if q := getServiceQueueGroup(serviceDescriptor); q != "" {
+		return q
+	}
+	return s.queueGroups[""]
+}
+
+// getServiceQueueGroup extracts the queue group from the service options, if set.
+func getServiceQueueGroup(serviceDescriptor protoreflect.Descriptor) string {
+	if serviceDescriptor == nil {
+		return ""
+	}
+	opts := serviceDescriptor.Options()
+	if opts == nil {
+		return ""
+	}
+	ext := proto.GetExtension(opts, rpc.E_QueueGroup)
+	if queue, ok := ext.(string); ok {
+		return queue
+	}
+	return ""
+}
+
+// getMethodQueueGroup extracts the queue group from the method options, if set.
+func getMethodQueueGroup(methodDescriptor protoreflect.Descriptor) string {
+	if methodDescriptor == nil {
+		return ""
+	}
+	opts := methodDescriptor.Options()
+	if opts == nil {
+		return ""
+	}
+	ext := proto.GetExtension(opts, rpc.E_MethodQueueGroup)
+	if queue, ok := ext.(string); ok {
+		return queue
+	}
+	return ""
+}
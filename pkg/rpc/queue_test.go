@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func Test_queueGroup(t *testing.T) {
+	svcDesc := rpc.File_synternet_rpc_test_service_proto.Services().ByName("TestService")
+	method := func(name string) protoreflect.MethodDescriptor {
+		return svcDesc.Methods().ByName(protoreflect.Name(name))
+	}
+
+	tests := []struct {
+		name   string
+		opts   []RegistrarOption
+		method string
+		want   string
+	}{
+		{"none", nil, "Test", ""},
+		{"proto method option", nil, "TestVars", "test-vars"},
+		{"default", []RegistrarOption{WithQueueGroup("", "all")}, "Test", "all"},
+		{"proto method option over default", []RegistrarOption{WithQueueGroup("", "all")}, "TestVars", "test-vars"},
+		{"service", []RegistrarOption{WithQueueGroup("", "all"), WithQueueGroup("synternet.rpc.TestService", "svc")}, "Test", "svc"},
+		{"proto method option over service", []RegistrarOption{WithQueueGroup("synternet.rpc.TestService", "svc")}, "TestVars", "test-vars"},
+		{"method", []RegistrarOption{WithQueueGroup("synternet.rpc.TestService", "svc"), WithQueueGroup("/synternet.rpc.TestService/Test", "method")}, "Test", "method"},
+		{"method over proto method option", []RegistrarOption{WithQueueGroup("/synternet.rpc.TestService/TestVars", "method")}, "TestVars", "method"},
+		{"other service", []RegistrarOption{WithQueueGroup("synternet.rpc.OtherService", "svc")}, "Test", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ServiceRegistrar{}
+			for _, opt := range tt.opts {
+				opt(s)
+			}
+			assert.Equal(t, tt.want, s.queueGroup(svcDesc, method(tt.method)))
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var _ rpc.ReflectionServer = (*reflectionServer)(nil)
+
+// reflectionServer describes the services registered in the registrar.
+type reflectionServer struct {
+	registrar *ServiceRegistrar
+}
+
+// ListServices implements rpc.ReflectionServer.
+func (r *reflectionServer) ListServices(_ context.Context, req *rpc.ListServicesRequest) (*rpc.ListServicesResponse, error) {
+	return r.registrar.describe(req.GetServices())
+}
+
+// WithoutReflection disables the reflection service that is otherwise registered by Start.
+func WithoutReflection() RegistrarOption {
+	return func(s *ServiceRegistrar) {
+		s.disableReflection = true
+	}
+}
+
+// describe builds the description of the registered services. All services are described if names are empty.
+func (s *ServiceRegistrar) describe(names []string) (*rpc.ListServicesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &rpc.ListServicesResponse{}
+	var files []protoreflect.FileDescriptor
+	for name, svc := range s.services {
+		if len(names) != 0 && !slices.Contains(names, name) {
+			continue
+		}
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "service desc %s: %v", name, err)
+		}
+		svcDesc := desc.(protoreflect.ServiceDescriptor)
+		svcDescription, err := s.describeService(svc, svcDesc)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "service desc %s: %v", name, err)
+		}
+		resp.Services = append(resp.Services, svcDescription)
+		files = appendFileWithDeps(files, svcDesc.ParentFile())
+	}
+	if len(names) != 0 && len(resp.Services) != len(names) {
+		return nil, status.Errorf(codes.NotFound, "services not found: %v", names)
+	}
+	slices.SortFunc(resp.Services, func(a, b *rpc.ServiceDescription) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, fd := range files {
+		b, err := proto.Marshal(protodesc.ToFileDescriptorProto(fd))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "file descriptor %s: %v", fd.Path(), err)
+		}
+		resp.FileDescriptors = append(resp.FileDescriptors, b)
+	}
+	return resp, nil
+}
+
+func (s *ServiceRegistrar) describeService(svc *serviceInfo, svcDesc protoreflect.ServiceDescriptor) (*rpc.ServiceDescription, error) {
+	ret := &rpc.ServiceDescription{Name: string(svcDesc.FullName())}
+	methods := svcDesc.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		methodDesc := methods.Get(i)
+		tokens, err := deriveSubject(s.prefix, svcDesc, methodDesc, svc.vars, true)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", methodDesc.FullName(), err)
+		}
+		ret.Methods = append(ret.Methods, &rpc.MethodDescription{
+			Name:            string(methodDesc.Name()),
+			FullMethod:      fmt.Sprintf("/%s/%s", svcDesc.FullName(), methodDesc.Name()),
+			Subject:         s.pub.Subject(tokens...),
+			InputType:       string(methodDesc.Input().FullName()),
+			OutputType:      string(methodDesc.Output().FullName()),
+			ClientStreaming: methodDesc.IsStreamingClient(),
+			ServerStreaming: methodDesc.IsStreamingServer(),
+			DisableInputs:   disableSubscription(methodDesc),
+		})
+	}
+	return ret, nil
+}
+
+// appendFileWithDeps appends the file and its transitive imports, so that dependencies precede the files importing them.
+// Files already present are skipped.
+func appendFileWithDeps(files []protoreflect.FileDescriptor, fd protoreflect.FileDescriptor) []protoreflect.FileDescriptor {
+	if slices.ContainsFunc(files, func(f protoreflect.FileDescriptor) bool { return f.Path() == fd.Path() }) {
+		return files
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		files = appendFileWithDeps(files, imports.Get(i).FileDescriptor)
+	}
+	return append(files, fd)
+}
+
+// Resolver discovers services served by a remote publisher using its reflection service.
+type Resolver struct {
+	client rpc.ReflectionClient
+}
+
+// NewResolver returns a resolver for the remote publisher. The remote prefix has the same meaning as in NewClientConn.
+func NewResolver(ctx context.Context, sub Publisher, remotePrefix string, opts ...ClientOption) *Resolver {
+	return &Resolver{
+		client: rpc.NewReflectionClient(NewClientConn(ctx, sub, remotePrefix, nil, opts...)),
+	}
+}
+
+// ListServices returns the description of the remote services. All services are described if names are empty.
+func (r *Resolver) ListServices(ctx context.Context, names ...string) (*rpc.ListServicesResponse, error) {
+	return r.client.ListServices(ctx, &rpc.ListServicesRequest{Services: names})
+}
+
+// ResolveService returns the descriptor of the remote service built from the file descriptors it advertises,
+// together with the description of its methods and subjects. The service does not need to be compiled into the client.
+func (r *Resolver) ResolveService(ctx context.Context, name string) (protoreflect.ServiceDescriptor, *rpc.ServiceDescription, error) {
+	resp, err := r.ListServices(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err := FilesFromReflection(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, nil, fmt.Errorf("service desc %s: %w", name, err)
+	}
+	svcDesc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, nil, fmt.Errorf("not a service: %s", name)
+	}
+	for _, svc := range resp.GetServices() {
+		if svc.GetName() == name {
+			return svcDesc, svc, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("service description missing: %s", name)
+}
+
+// FilesFromReflection builds a registry of the file descriptors carried in the reflection response.
+func FilesFromReflection(resp *rpc.ListServicesResponse) (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, b := range resp.GetFileDescriptors() {
+		fd := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(b, fd); err != nil {
+			return nil, fmt.Errorf("file descriptor: %w", err)
+		}
+		set.File = append(set.File, fd)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("file descriptors: %w", err)
+	}
+	return files, nil
+}
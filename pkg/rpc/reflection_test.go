@@ -0,0 +1,99 @@
+package rpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestReflectionListServices(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, map[string]string{"variable": "123456"})
+	resolver := rpc.NewResolver(ctx, sub, "test_prefix")
+
+	resp, err := resolver.ListServices(ctx)
+	require.NoError(t, err)
+	services := make(map[string]*rpctypes.ServiceDescription)
+	for _, svc := range resp.Services {
+		services[svc.Name] = svc
+	}
+	require.Contains(t, services, "synternet.rpc.Reflection")
+	assert.Equal(t, "test_prefix.service.reflection", services["synternet.rpc.Reflection"].Methods[0].Subject)
+
+	require.Contains(t, services, "synternet.rpc.TestService")
+	svc := services["synternet.rpc.TestService"]
+	require.Len(t, svc.Methods, 5)
+	methods := make(map[string]*rpctypes.MethodDescription)
+	for _, m := range svc.Methods {
+		methods[m.Name] = m
+	}
+
+	assert.Equal(t, rpctypes.TestService_Test_FullMethodName, methods["Test"].FullMethod)
+	assert.Equal(t, "test_prefix.override.test.override.test.method", methods["Test"].Subject)
+	assert.Equal(t, "synternet.rpc.TestRequest", methods["Test"].InputType)
+	assert.Equal(t, "synternet.rpc.TestResponse", methods["Test"].OutputType)
+	assert.Equal(t, "test_prefix.override.test.override.test.method.123456", methods["TestVars"].Subject)
+	assert.True(t, methods["TestStream"].ServerStreaming)
+	assert.False(t, methods["TestStream"].ClientStreaming)
+	assert.True(t, methods["TestStreamOnly"].DisableInputs)
+	assert.True(t, methods["TestStreamBidirectional"].ClientStreaming)
+	assert.True(t, methods["TestStreamBidirectional"].ServerStreaming)
+
+	files, err := rpc.FilesFromReflection(resp)
+	require.NoError(t, err)
+	_, err = files.FindFileByPath("synternet/rpc/test_service.proto")
+	assert.NoError(t, err)
+	_, err = files.FindFileByPath("google/protobuf/empty.proto")
+	assert.NoError(t, err)
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestReflectionResolveService(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil)
+	resolver := rpc.NewResolver(ctx, sub, "test_prefix")
+
+	svcDesc, svc, err := resolver.ResolveService(ctx, "synternet.rpc.TestService")
+	require.NoError(t, err)
+	assert.Equal(t, protoreflect.FullName("synternet.rpc.TestService"), svcDesc.FullName())
+	assert.Equal(t, 5, svcDesc.Methods().Len())
+	assert.NotNil(t, svcDesc.Methods().ByName("TestStreamBidirectional"))
+	assert.Equal(t, "synternet.rpc.TestService", svc.Name)
+	assert.Len(t, svc.Methods, 5)
+
+	_, _, err = resolver.ResolveService(ctx, "synternet.rpc.MissingService")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestWithoutReflection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil, rpc.WithoutReflection())
+	resolver := rpc.NewResolver(ctx, sub, "test_prefix")
+
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel1()
+	_, err := resolver.ListServices(ctx1)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
@@ -0,0 +1,231 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxAttempts limits the number of attempts of a call, the same as gRPC does.
+const maxAttempts = 5
+
+// RetryPolicy configures retries of failed unary calls. It follows the semantics of gRPC retry policy:
+// a call is retried with exponential backoff with jitter as long as it fails with one of the retryable codes.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the original one. It must be greater than 1 and is limited to 5.
+	MaxAttempts int
+	// InitialBackoff and MaxBackoff limit the backoff, which grows with BackoffMultiplier after every attempt.
+	// The delay before the n-th retry is random between 0 and min(InitialBackoff*BackoffMultiplier^(n-1), MaxBackoff).
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	// RetryableStatusCodes lists the codes of failures that are retried.
+	RetryableStatusCodes []codes.Code
+}
+
+// HedgingPolicy configures hedged unary calls: the same request is sent again every HedgingDelay until a response
+// is received, so that a slow replica in a queue group does not delay the call. It follows the semantics of gRPC hedging policy.
+type HedgingPolicy struct {
+	// MaxAttempts is the maximum number of requests sent including the original one. It must be greater than 1 and is limited to 5.
+	MaxAttempts int
+	// HedgingDelay is the delay between requests. Zero delay sends all requests at once.
+	HedgingDelay time.Duration
+	// NonFatalStatusCodes lists the codes of failures that do not stop the other requests.
+	// A failure with any other code is returned immediately.
+	NonFatalStatusCodes []codes.Code
+}
+
+// MethodConfig configures unary calls of a method. RetryPolicy and HedgingPolicy are mutually exclusive.
+type MethodConfig struct {
+	// Timeout limits the duration of the whole call including all attempts. Zero means no limit.
+	Timeout       time.Duration
+	RetryPolicy   *RetryPolicy
+	HedgingPolicy *HedgingPolicy
+}
+
+// WithMethodConfig configures unary calls of the methods selected by the name:
+//
+//   - empty name - all services;
+//   - fully qualified service name, e.g. `pkg.MyService` - all methods of the service;
+//   - full method name, e.g. `/pkg.MyService/Method` - a single method.
+//
+// The most specific configuration is used. Streams are neither retried nor hedged.
+//
+// The policies are validated the same way ParseServiceConfig validates them. If the configuration is invalid,
+// all unary calls of the connection fail with codes.InvalidArgument.
+func WithMethodConfig(name string, cfg MethodConfig) ClientOption {
+	return func(c *ClientConn) {
+		if err := cfg.validate(); err != nil {
+			c.configErr = status.Errorf(codes.InvalidArgument, "method config %q: %v", name, err)
+			return
+		}
+		if c.methodConfigs == nil {
+			c.methodConfigs = make(map[string]MethodConfig)
+		}
+		c.methodConfigs[name] = cfg
+	}
+}
+
+// validate checks the policies and replaces them with validated copies.
+func (cfg *MethodConfig) validate() error {
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if cfg.RetryPolicy != nil && cfg.HedgingPolicy != nil {
+		return fmt.Errorf("retryPolicy and hedgingPolicy are mutually exclusive")
+	}
+	if cfg.RetryPolicy != nil {
+		p := *cfg.RetryPolicy
+		if err := p.validate(); err != nil {
+			return err
+		}
+		cfg.RetryPolicy = &p
+	}
+	if cfg.HedgingPolicy != nil {
+		p := *cfg.HedgingPolicy
+		if err := p.validate(); err != nil {
+			return err
+		}
+		cfg.HedgingPolicy = &p
+	}
+	return nil
+}
+
+// methodConfig returns the configuration of a full method name.
+func (c *ClientConn) methodConfig(method string) MethodConfig {
+	if !strings.HasPrefix(method, "/") {
+		method = "/" + method
+	}
+	if cfg, ok := c.methodConfigs[method]; ok {
+		return cfg
+	}
+	svc, _, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	if cfg, ok := c.methodConfigs[svc]; ok {
+		return cfg
+	}
+	return c.methodConfigs[""]
+}
+
+// validate checks the policy and limits the number of attempts.
+func (p *RetryPolicy) validate() error {
+	switch {
+	case p.MaxAttempts < 2:
+		return fmt.Errorf("retry policy: maxAttempts must be greater than 1")
+	case p.InitialBackoff <= 0 || p.MaxBackoff <= 0:
+		return fmt.Errorf("retry policy: initialBackoff and maxBackoff must be positive")
+	case p.BackoffMultiplier <= 0:
+		return fmt.Errorf("retry policy: backoffMultiplier must be positive")
+	case len(p.RetryableStatusCodes) == 0:
+		return fmt.Errorf("retry policy: retryableStatusCodes must not be empty")
+	}
+	p.MaxAttempts = min(p.MaxAttempts, maxAttempts)
+	return nil
+}
+
+// backoff returns a random delay before the retry following the given number of attempts.
+func (p *RetryPolicy) backoff(attempts int) time.Duration {
+	limit := float64(p.InitialBackoff) * math.Pow(p.BackoffMultiplier, float64(attempts-1))
+	limit = min(limit, float64(p.MaxBackoff))
+	if limit < 1 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(limit)))
+}
+
+// validate checks the policy and limits the number of attempts.
+func (p *HedgingPolicy) validate() error {
+	if p.MaxAttempts < 2 {
+		return fmt.Errorf("hedging policy: maxAttempts must be greater than 1")
+	}
+	if p.HedgingDelay < 0 {
+		return fmt.Errorf("hedging policy: hedgingDelay must not be negative")
+	}
+	p.MaxAttempts = min(p.MaxAttempts, maxAttempts)
+	return nil
+}
+
+// retry calls the function until it succeeds, fails with a code that is not retryable, or the attempts are exhausted.
+// The result and the error of the last attempt are returned.
+func retry[T any](ctx context.Context, policy *RetryPolicy, call func(context.Context) (T, error)) (T, error) {
+	attempts := min(policy.MaxAttempts, maxAttempts)
+	for n := 1; ; n++ {
+		res, err := call(ctx)
+		if err == nil || n >= attempts || !slices.Contains(policy.RetryableStatusCodes, status.Code(err)) {
+			return res, err
+		}
+		timer := time.NewTimer(policy.backoff(n))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, err
+		case <-timer.C:
+		}
+	}
+}
+
+// hedge calls the function every HedgingDelay until one of the calls succeeds, fails with a fatal code,
+// or all the attempts fail. The other calls are cancelled once the result is known.
+func hedge[T any](ctx context.Context, policy *HedgingPolicy, call func(context.Context) (T, error)) (T, error) {
+	type result struct {
+		res T
+		err error
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	attempts := min(policy.MaxAttempts, maxAttempts)
+	results := make(chan result, attempts)
+	start := func() {
+		go func() {
+			res, err := call(ctx)
+			results <- result{res, err}
+		}()
+	}
+
+	start()
+	started, finished := 1, 0
+	timer := time.NewTimer(policy.HedgingDelay)
+	defer timer.Stop()
+	var last result
+	for {
+		select {
+		case <-ctx.Done():
+			// The result does not wait for the calls that ignore ctx
+			var zero T
+			return zero, status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+			if started < attempts {
+				start()
+				started++
+				timer.Reset(policy.HedgingDelay)
+			}
+		case last = <-results:
+			finished++
+			if last.err == nil || !slices.Contains(policy.NonFatalStatusCodes, status.Code(last.err)) {
+				return last.res, last.err
+			}
+			if finished == attempts {
+				return last.res, last.err
+			}
+			// A non-fatal failure sends the next request without waiting for the delay
+			if started < attempts {
+				start()
+				started++
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(policy.HedgingDelay)
+			}
+		}
+	}
+}
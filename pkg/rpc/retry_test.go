@@ -0,0 +1,215 @@
+package rpc_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// failingInterceptor fails the first calls with codes.Unavailable and counts all the calls.
+func failingInterceptor(failures int32, calls *atomic.Int32) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if calls.Add(1) <= failures {
+			return nil, status.Error(codes.Unavailable, "try again")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := &rpc.RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		MaxBackoff:           time.Millisecond * 5,
+		BackoffMultiplier:    2,
+		RetryableStatusCodes: []codes.Code{codes.Unavailable},
+	}
+	tests := []struct {
+		name      string
+		failures  int32
+		request   *rpctypes.TestRequest
+		wantCode  codes.Code
+		wantCalls int32
+	}{
+		{"no failures", 0, &rpctypes.TestRequest{A: 1, B: 2}, codes.OK, 1},
+		{"recovers", 2, &rpctypes.TestRequest{A: 1, B: 2}, codes.OK, 3},
+		{"attempts exhausted", 5, &rpctypes.TestRequest{A: 1, B: 2}, codes.Unavailable, 3},
+		{"not retryable", 0, &rpctypes.TestRequest{A: 1, B: -2}, codes.InvalidArgument, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			var calls atomic.Int32
+			sub := makeServer(t, ctx, nil, rpc.WithUnaryInterceptors(failingInterceptor(tt.failures, &calls)))
+			clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil,
+				rpc.WithMethodConfig(rpctypes.TestService_Test_FullMethodName, rpc.MethodConfig{RetryPolicy: policy}),
+			)
+			client := rpctypes.NewTestServiceClient(clt)
+
+			res, err := client.Test(ctx, tt.request)
+			assert.Equal(t, tt.wantCode, status.Code(err), err)
+			if tt.wantCode == codes.OK {
+				require.NotNil(t, res)
+				assert.Equal(t, tt.request.A+tt.request.B, res.Ab)
+			}
+			assert.Equal(t, tt.wantCalls, calls.Load())
+
+			cancel()
+			time.Sleep(time.Millisecond * 10)
+		})
+	}
+}
+
+func TestRetryServiceConfig(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var calls atomic.Int32
+	sub := makeServer(t, ctx, nil, rpc.WithUnaryInterceptors(failingInterceptor(1, &calls)))
+	opt, err := rpc.ParseServiceConfig(`{"methodConfig": [{
+		"name": [{"service": "synternet.rpc.TestService"}],
+		"retryPolicy": {"maxAttempts": 2, "initialBackoff": "0.001s", "maxBackoff": "0.001s", "backoffMultiplier": 1, "retryableStatusCodes": ["UNAVAILABLE"]}
+	}]}`)
+	require.NoError(t, err)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil, opt)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	_, err = client.Test(ctx, &rpctypes.TestRequest{A: 1, B: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestMethodConfigTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil, rpc.WithUnaryInterceptors(
+		func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	))
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil,
+		rpc.WithMethodConfig("", rpc.MethodConfig{Timeout: time.Millisecond * 20}),
+	)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	start := time.Now()
+	_, err := client.Test(ctx, &rpctypes.TestRequest{A: 1, B: 2})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(start), time.Millisecond*200)
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestHedging(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Two replicas in a queue group, where the first request stalls the replica that received it
+	grp, ctx := errgroup.WithContext(ctx)
+	pub := NewPublisher(ctx, t, "test_prefix")
+	var calls atomic.Int32
+	for range 2 {
+		srv := rpc.NewServiceRegistrar(grp, pub,
+			rpc.WithQueueGroup("", "workers"),
+			rpc.WithUnaryInterceptors(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if calls.Add(1) == 1 {
+					select {
+					case <-ctx.Done():
+					case <-time.After(time.Millisecond * 300):
+					}
+				}
+				return handler(ctx, req)
+			}),
+		)
+		rpctypes.RegisterTestServiceServer(srv, &Test{t: t})
+		require.NoError(t, srv.Start(ctx, nil))
+	}
+	time.Sleep(time.Millisecond * 10)
+
+	clt := rpc.NewClientConn(ctx, pub, "test_prefix", nil,
+		rpc.WithMethodConfig("synternet.rpc.TestService", rpc.MethodConfig{HedgingPolicy: &rpc.HedgingPolicy{
+			MaxAttempts:  2,
+			HedgingDelay: time.Millisecond * 20,
+		}}),
+	)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	start := time.Now()
+	res, err := client.Test(ctx, &rpctypes.TestRequest{A: 1, B: 2})
+	require.NoError(t, err)
+	assert.Equal(t, float32(3), res.Ab)
+	assert.Less(t, time.Since(start), time.Millisecond*200, "hedged request should have been served by the other replica")
+	assert.Equal(t, int32(2), calls.Load())
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var calls atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	sub := makeServer(t, ctx, map[string]string{"variable": "1"}, rpc.WithUnaryInterceptors(
+		func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			calls.Add(1)
+			if failing.Load() {
+				return nil, status.Error(codes.Unavailable, "down")
+			}
+			return handler(ctx, req)
+		},
+	))
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", map[string]string{"variable": "1"},
+		rpc.WithCircuitBreaker(rpc.CircuitBreaker{FailureThreshold: 2, OpenTimeout: time.Millisecond * 50}),
+	)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	for range 2 {
+		_, err := client.Test(ctx, &rpctypes.TestRequest{A: 1, B: 2})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	assert.Equal(t, int32(2), calls.Load())
+
+	// The breaker is open, so the request does not reach the server
+	_, err := client.Test(ctx, &rpctypes.TestRequest{A: 1, B: 2})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "circuit breaker")
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Other subjects are not affected
+	_, err = client.TestVars(ctx, &rpctypes.TestRequest{A: 1, B: 2})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, int32(3), calls.Load())
+
+	// The trial request closes the breaker
+	failing.Store(false)
+	time.Sleep(time.Millisecond * 60)
+	for range 2 {
+		_, err = client.Test(ctx, &rpctypes.TestRequest{A: 1, B: 2})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(5), calls.Load())
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
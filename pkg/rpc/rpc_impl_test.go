@@ -2,18 +2,38 @@ package rpc_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/synternet/data-layer-sdk/pkg/rpc"
 	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 var _ rpctypes.TestServiceServer = (*Test)(nil)
 
+// echoMetadata sends the incoming authorization metadata back in the header, and the trace metadata in the trailer.
+func echoMetadata(ctx context.Context) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get("authorization")) == 0 {
+		return nil
+	}
+	if err := grpc.SetHeader(ctx, metadata.MD{"echo": md.Get("authorization")}); err != nil {
+		return err
+	}
+	return grpc.SetTrailer(ctx, metadata.MD{"trace-bin": md.Get("trace-bin")})
+}
+
 type Test struct {
 	t *testing.T
 }
@@ -23,9 +43,21 @@ func (t *Test) Test(ctx context.Context, r *rpctypes.TestRequest) (*rpctypes.Tes
 	subject, _ := rpc.GetSubject(ctx)
 	headers, _ := rpc.GetHeaders(ctx)
 	t.t.Log("Test", "r=", r, "subject=", subject, "header=", headers)
+	if err := echoMetadata(ctx); err != nil {
+		return nil, err
+	}
 	if r.A < 0 {
 		return nil, fmt.Errorf("negative value: a:%v b:%v", r.A, r.B)
 	}
+	if r.B < 0 {
+		st, err := status.Newf(codes.InvalidArgument, "negative value: b:%v", r.B).WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "b", Description: "must not be negative"}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, st.Err()
+	}
 
 	hdr := make(map[string]string)
 	for k, v := range headers {
@@ -49,6 +81,8 @@ func (t *Test) TestVars(ctx context.Context, r *rpctypes.TestRequest) (*rpctypes
 	}
 
 	assert.Equal(t.t, "123456", subject.Tokens()[len(subject.Tokens())-1])
+	assert.Equal(t.t, map[string]string{"variable": "123456"}, rpc.Vars(ctx))
+	assert.Equal(t.t, uint64(123456), r.GetVariable())
 
 	hdr := make(map[string]string)
 	for k, v := range headers {
@@ -94,12 +128,25 @@ func (t *Test) TestStreamOnly(_ *emptypb.Empty, srv rpctypes.TestService_TestStr
 // TestStreamBidirectional implements rpc.TestServiceServer.
 func (t *Test) TestStreamBidirectional(srv rpctypes.TestService_TestStreamBidirectionalServer) error {
 	t.t.Log("TestStreamBidirectional")
+	if err := echoMetadata(srv.Context()); err != nil {
+		return err
+	}
+	count := 0
 	for {
 		var msg rpctypes.TestRequest
-		if err := srv.RecvMsg(&msg); err != nil {
+		err := srv.RecvMsg(&msg)
+		if errors.Is(err, io.EOF) {
+			srv.SetTrailer(metadata.Pairs("count", strconv.Itoa(count)))
+			return nil
+		}
+		if err != nil {
 			t.t.Log("TestStreamBidirectional recv", "err=", err)
 			return err
 		}
+		if msg.A < 0 {
+			return status.Errorf(codes.InvalidArgument, "negative value: a:%v b:%v", msg.A, msg.B)
+		}
+		count++
 
 		if err := srv.Send(&rpctypes.TestResponse{
 			Ab: msg.A + msg.B,
@@ -2,29 +2,45 @@ package rpc_test
 
 import (
 	"context"
+	"io"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/synternet/data-layer-sdk/pkg/rpc"
 	service "github.com/synternet/data-layer-sdk/pkg/service"
 	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
-func makeServer(t *testing.T, ctx context.Context, vars map[string]string) rpc.Publisher {
-	ctx, _ = context.WithTimeout(ctx, time.Second*30)
+func makeServer(t *testing.T, ctx context.Context, vars map[string]string, opts ...rpc.RegistrarOption) rpc.Publisher {
+	pub, _ := makeRegistrar(t, ctx, vars, opts...)
+	return pub
+}
+
+// makeRegistrar is the same as makeServer, but also returns the registrar.
+func makeRegistrar(t *testing.T, ctx context.Context, vars map[string]string, opts ...rpc.RegistrarOption) (rpc.Publisher, *rpc.ServiceRegistrar) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	t.Cleanup(cancel)
 	grp, ctx := errgroup.WithContext(ctx)
 	pub := NewPublisher(ctx, t, "test_prefix")
-	srv := rpc.NewServiceRegistrar(grp, pub)
+	srv := rpc.NewServiceRegistrar(grp, pub, opts...)
 
 	rpctypes.RegisterTestServiceServer(srv, &Test{t: t})
 	go srv.Start(ctx, vars)
 	time.Sleep(time.Millisecond * 10) // Allow go routines to run before we start the client
 
-	return pub
+	return pub, srv
 }
 
 // TestPubSub tests the mock publisher implementation
@@ -89,7 +105,9 @@ func TestRequestReply(t *testing.T) {
 	assert.Equal(t, float32(123.0+321.0), res.Ab)
 	assert.Equal(t, "", res.Error)
 	assert.Equal(t, "test_prefix.override.test.override.test.method", res.Subject)
-	assert.Equal(t, map[string]string{"identity": "some,identity"}, res.Header)
+	assert.Equal(t, "some,identity", res.Header["identity"])
+	assert.Contains(t, res.Header, rpc.TimeoutHeader)
+	assert.Len(t, res.Header, 2)
 
 	time.Sleep(time.Millisecond * 10)
 }
@@ -111,11 +129,136 @@ func TestRequestReplyWithVars(t *testing.T) {
 	assert.Equal(t, float32(123.0+321.0), res.Ab)
 	assert.Equal(t, "", res.Error)
 	assert.Equal(t, "test_prefix.override.test.override.test.method.123456", res.Subject)
-	assert.Equal(t, map[string]string{"identity": "some,identity"}, res.Header)
+	assert.Equal(t, "some,identity", res.Header["identity"])
+	assert.Contains(t, res.Header, rpc.TimeoutHeader)
+	assert.Len(t, res.Header, 2)
 
 	time.Sleep(time.Millisecond * 10)
 }
 
+func TestRequestReplyCallVars(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, map[string]string{"variable": "123456"})
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	tests := []struct {
+		name     string
+		req      *rpctypes.TestRequest
+		opts     []grpc.CallOption
+		wantCode codes.Code
+	}{
+		{"call option", &rpctypes.TestRequest{A: 1, B: 2}, []grpc.CallOption{rpc.WithVars(map[string]string{"variable": "123456"})}, codes.OK},
+		{"request field", &rpctypes.TestRequest{A: 1, B: 2, Variable: proto.Uint64(123456)}, nil, codes.OK},
+		{"request field mismatch", &rpctypes.TestRequest{A: 1, B: 2, Variable: proto.Uint64(7)}, []grpc.CallOption{rpc.WithVars(map[string]string{"variable": "123456"})}, codes.InvalidArgument},
+	}
+	// The server logs to t, so the cases are not run as subtests
+	for _, tt := range tests {
+		res, err := client.TestVars(ctx, tt.req, tt.opts...)
+		require.Equal(t, tt.wantCode, status.Code(err), tt.name)
+		if tt.wantCode == codes.OK {
+			assert.Equal(t, "test_prefix.override.test.override.test.method.123456", res.Subject, tt.name)
+		}
+	}
+
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestRequestReplyWildcardVars(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The server is started without the variable, so it serves all of its values
+	sub := makeServer(t, ctx, nil)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	// The handler receives the variable from the subject, and the bound request field is set
+	res, err := client.TestVars(ctx, &rpctypes.TestRequest{A: 1, B: 2}, rpc.WithVars(map[string]string{"variable": "123456"}))
+	require.NoError(t, err)
+	assert.Equal(t, "test_prefix.override.test.override.test.method.123456", res.Subject)
+
+	// Values that do not match the variable schema are rejected by the server
+	msg, err := sub.RequestFromWithHeader(ctx, &rpctypes.TestRequest{}, nil, nil, "test_prefix.override.test.override.test.method.abc")
+	require.NoError(t, err)
+	require.NotEmpty(t, msg.Header().Get(service.ErrorHeader))
+	var rpcErr rpctypes.Error
+	_, err = sub.Unmarshal(msg, &rpcErr)
+	require.NoError(t, err)
+	assert.Equal(t, codes.InvalidArgument, service.StatusFromRpcError(&rpcErr).Code())
+
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestRequestReplyMissingVars(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	_, err := client.TestVars(ctx, &rpctypes.TestRequest{A: 123, B: 321})
+	var varsErr *rpc.VariablesError
+	require.ErrorAs(t, err, &varsErr)
+	assert.Equal(t, []string{"variable"}, varsErr.Missing)
+
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestStrictSubjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr string
+	}{
+		{"ok", map[string]string{"synternet.rpc.TestService/variable": "123"}, ""},
+		{"global", map[string]string{"variable": "123"}, ""},
+		{"missing", nil, "synternet.rpc.TestService.TestVars: missing variables: variable"},
+		{"unused", map[string]string{"variable": "123", "other": "1", "synternet.rpc.TestService/another": "2"}, "unused variables: other, synternet.rpc.TestService/another"},
+		{"shadowed", map[string]string{"variable": "123", "synternet.rpc.TestService/variable": "1"}, "unused variables: variable"},
+		{"invalid value", map[string]string{"variable": "abc"}, `variable variable: value "abc" is not VARIABLE_TYPE_UINT`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			grp, ctx := errgroup.WithContext(ctx)
+			pub := NewPublisher(ctx, t, "test_prefix")
+			srv := rpc.NewServiceRegistrar(grp, pub, rpc.WithStrictSubjects(), rpc.WithoutHealth())
+			rpctypes.RegisterTestServiceServer(srv, rpctypes.UnimplementedTestServiceServer{})
+
+			err := srv.Start(ctx, tt.vars)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+			cancel()
+			time.Sleep(time.Millisecond * 10)
+		})
+	}
+}
+
+func TestInvalidSubjectVariable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	grp, ctx := errgroup.WithContext(ctx)
+	pub := NewPublisher(ctx, t, "test_prefix")
+	srv := rpc.NewServiceRegistrar(grp, pub)
+	rpctypes.RegisterTestServiceServer(srv, rpctypes.UnimplementedTestServiceServer{})
+
+	// Invalid values fail Start even without strict mode
+	err := srv.Start(ctx, map[string]string{"variable": "a.b"})
+	assert.ErrorContains(t, err, "not a single subject token")
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
 func TestRequestReplyWithError(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
 	defer cancel()
@@ -127,12 +270,37 @@ func TestRequestReplyWithError(t *testing.T) {
 	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*50)
 	defer cancel1()
 	res, err := client.Test(ctx1, &rpctypes.TestRequest{A: -123, B: -321})
-	require.NoError(t, err)
-	require.NotNil(t, res)
+	require.Error(t, err)
+	require.Nil(t, res)
 
-	assert.Equal(t, "synternet.rpc.TestService.Test: negative value: a:-123 b:-321", res.Error)
-	assert.Equal(t, "", res.Subject, "")
-	assert.Equal(t, map[string]string(nil), res.Header)
+	st := status.Convert(err)
+	assert.Equal(t, codes.Unknown, st.Code())
+	assert.Equal(t, "synternet.rpc.TestService.Test: negative value: a:-123 b:-321", st.Message())
+
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestRequestReplyWithStatus(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel1()
+	res, err := client.Test(ctx1, &rpctypes.TestRequest{A: 123, B: -321})
+	require.Error(t, err)
+	require.Nil(t, res)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "negative value: b:-321", st.Message())
+	require.Len(t, st.Details(), 1)
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	assert.Equal(t, "b", badRequest.FieldViolations[0].Field)
 
 	time.Sleep(time.Millisecond * 10)
 }
@@ -164,6 +332,43 @@ func TestRequestStream(t *testing.T) {
 	time.Sleep(time.Millisecond * 10)
 }
 
+func TestRequestStreamReleasesInbox(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	grp, gctx := errgroup.WithContext(ctx)
+	pub := makeNatsService(t, gctx)
+	srv := rpc.NewServiceRegistrar(grp, pub, rpc.WithoutReflection(), rpc.WithoutHealth(), rpc.WithProducerPolicy(rpc.ProducerPolicy{InitialBackoff: time.Second}))
+	rpctypes.RegisterTestServiceServer(srv, &statusServer{})
+	require.NoError(t, srv.Start(gctx, nil))
+	client := rpctypes.NewTestServiceClient(rpc.NewClientConn(ctx, pub, natsPrefix, nil))
+	// The connection keeps a subscription for the replies to all requests, which the first request creates
+	_, err := client.Test(ctx, &rpctypes.TestRequest{A: 1})
+	require.NoError(t, err)
+	conn := pub.SubNats.(*nats.Conn)
+	subscriptions := conn.NumSubscriptions()
+
+	// The stream context outlives the stream, so only the end of the stream may release the inbox
+	str, err := client.TestStream(ctx, &rpctypes.TestRequest{A: 1})
+	require.NoError(t, err)
+	assert.Greater(t, conn.NumSubscriptions(), subscriptions)
+	for {
+		var msg rpctypes.TestResponse
+		if err := str.RecvMsg(&msg); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return conn.NumSubscriptions() == subscriptions
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, srv.Stop(ctx))
+	cancel()
+	require.NoError(t, grp.Wait())
+}
+
 func TestRequestStreamOnly(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
@@ -189,3 +394,319 @@ func TestRequestStreamOnly(t *testing.T) {
 	cancel()
 	time.Sleep(time.Millisecond * 10)
 }
+
+func TestRequestStreamBidirectional(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*200)
+	defer cancel1()
+	str, err := client.TestStreamBidirectional(ctx1)
+	require.NoError(t, err)
+	require.NotNil(t, str)
+
+	for i := 0; i < 10; i++ {
+		err := str.Send(&rpctypes.TestRequest{A: float32(i), B: 1})
+		require.NoError(t, err)
+	}
+	require.NoError(t, str.CloseSend())
+
+	for i := 0; i < 10; i++ {
+		msg, err := str.Recv()
+		require.NoError(t, err)
+		t.Log("Recv", "i=", i, "msg=", msg)
+		assert.Equal(t, float32(i+1), msg.Ab)
+	}
+
+	_, err = str.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+	_, err = str.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, metadata.Pairs("count", "10"), str.Trailer())
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestRequestStreamBidirectionalWithError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil)
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*200)
+	defer cancel1()
+	str, err := client.TestStreamBidirectional(ctx1)
+	require.NoError(t, err)
+
+	require.NoError(t, str.Send(&rpctypes.TestRequest{A: 1, B: 2}))
+	msg, err := str.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, float32(3), msg.Ab)
+
+	require.NoError(t, str.Send(&rpctypes.TestRequest{A: -1, B: 2}))
+	_, err = str.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "negative value: a:-1 b:2", status.Convert(err).Message())
+	assert.Empty(t, str.Trailer())
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestRequestReplyDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	deadlines := make(chan time.Time, 1)
+	sub := makeServer(t, ctx, nil, rpc.WithUnaryInterceptors(
+		func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok)
+			deadlines <- deadline
+			return handler(ctx, req)
+		},
+	))
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel1()
+	_, err := client.Test(ctx1, &rpctypes.TestRequest{A: 123, B: 321})
+	require.NoError(t, err)
+
+	expected, _ := ctx1.Deadline()
+	select {
+	case deadline := <-deadlines:
+		assert.False(t, deadline.After(expected.Add(time.Millisecond)))
+		assert.WithinDuration(t, expected, deadline, time.Millisecond*20)
+	case <-ctx.Done():
+		require.Fail(t, "handler was not called")
+	}
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestRequestStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	handlerErr := make(chan error, 1)
+	sub := makeServer(t, ctx, nil, rpc.WithStreamInterceptors(
+		func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			err := handler(srv, ss)
+			if info.FullMethod == rpctypes.TestService_TestStream_FullMethodName {
+				handlerErr <- err
+			}
+			return err
+		},
+	))
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	defer cancel1()
+	str, err := client.TestStream(ctx1, &rpctypes.TestRequest{A: 123, B: 321})
+	require.NoError(t, err)
+
+	var msg rpctypes.TestResponse
+	require.NoError(t, str.RecvMsg(&msg))
+
+	cancel1()
+	err = str.RecvMsg(&msg)
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	// The server stops producing while the registrar context is still alive
+	select {
+	case err := <-handlerErr:
+		assert.Equal(t, codes.Canceled, status.Code(err))
+	case <-time.After(time.Millisecond * 200):
+		require.Fail(t, "server stream was not cancelled")
+	}
+	require.NoError(t, ctx.Err())
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestRequestStreamDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	handlerErr := make(chan error, 1)
+	sub := makeServer(t, ctx, nil, rpc.WithStreamInterceptors(
+		func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			err := handler(srv, ss)
+			if info.FullMethod == rpctypes.TestService_TestStreamBidirectional_FullMethodName {
+				handlerErr <- err
+			}
+			return err
+		},
+	))
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel1()
+	str, err := client.TestStreamBidirectional(ctx1)
+	require.NoError(t, err)
+
+	// The client never closes the stream, so the server handler is stopped by the deadline
+	var msg rpctypes.TestResponse
+	err = str.RecvMsg(&msg)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	select {
+	case err := <-handlerErr:
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	case <-time.After(time.Millisecond * 200):
+		require.Fail(t, "server stream did not observe the deadline")
+	}
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestRequestStreamIdle(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sub := makeServer(t, ctx, nil, rpc.WithStreamIdleTimeout(time.Millisecond*50))
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	str, err := client.TestStreamBidirectional(ctx)
+	require.NoError(t, err)
+	require.NoError(t, str.Send(&rpctypes.TestRequest{A: 1, B: 2}))
+	msg, err := str.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, float32(3), msg.Ab)
+
+	// The client stays silent without closing the stream
+	_, err = str.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestRequestStreamIdleServerSending(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// The server streams for several idle timeouts while the client only receives
+	const count = 30
+	sub := makeServer(t, ctx, nil, rpc.WithStreamIdleTimeout(time.Millisecond*50), rpc.WithStreamInterceptors(
+		func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			for i := 0; i < count; i++ {
+				time.Sleep(time.Millisecond * 10)
+				if err := ss.SendMsg(&rpctypes.TestResponse{Ab: float32(i)}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	))
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	str, err := client.TestStreamBidirectional(ctx)
+	require.NoError(t, err)
+	for i := 0; i < count; i++ {
+		msg, err := str.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, float32(i), msg.Ab)
+	}
+	_, err = str.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestRequestStreamRecvBufferFull(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// The handler never receives, so the frames pile up in the receive buffer
+	sub := makeServer(t, ctx, nil, rpc.WithStreamInterceptors(
+		func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			<-ss.Context().Done()
+			return context.Cause(ss.Context())
+		},
+	))
+	clt := rpc.NewClientConn(ctx, sub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+
+	str, err := client.TestStreamBidirectional(ctx)
+	require.NoError(t, err)
+	for i := 0; i < 1001; i++ {
+		require.NoError(t, str.Send(&rpctypes.TestRequest{A: float32(i)}))
+	}
+
+	_, err = str.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
+
+func TestQueueGroupDistribution(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Replicas share the same bus, where subscribers of a subject compete for the messages
+	grp, ctx := errgroup.WithContext(ctx)
+	pub := NewPublisher(ctx, t, "test_prefix")
+	const replicas = 2
+	var calls [replicas]atomic.Int32
+	for i := range replicas {
+		srv := rpc.NewServiceRegistrar(grp, pub,
+			rpc.WithQueueGroup("synternet.rpc.TestService", "workers"),
+			rpc.WithUnaryInterceptors(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				calls[i].Add(1)
+				return handler(ctx, req)
+			}),
+		)
+		rpctypes.RegisterTestServiceServer(srv, &Test{t: t})
+		require.NoError(t, srv.Start(ctx, nil))
+	}
+	time.Sleep(time.Millisecond * 10)
+
+	method := "test_prefix.override.test.override.test.method"
+	queue, ok := pub.Queue(method)
+	require.True(t, ok)
+	assert.Equal(t, "workers", queue)
+	queue, _ = pub.Queue("test_prefix.override.test.override.test.method.*")
+	assert.Equal(t, "test-vars", queue)
+	queue, _ = pub.Queue("test_prefix.override.test.override.test.stream.method")
+	assert.Equal(t, "workers", queue)
+	_, ok = pub.Queue("test_prefix.override.test.override.test.stream.data")
+	assert.False(t, ok, "pure streams are not subscribed")
+
+	clt := rpc.NewClientConn(ctx, pub, "test_prefix", nil)
+	client := rpctypes.NewTestServiceClient(clt)
+	const requests = 50
+	for range requests {
+		_, err := client.Test(ctx, &rpctypes.TestRequest{A: 1, B: 2})
+		require.NoError(t, err)
+	}
+
+	total := int32(0)
+	for i := range replicas {
+		t.Log("replica", i, "calls", calls[i].Load())
+		assert.Positive(t, calls[i].Load(), "replica %d received no requests", i)
+		total += calls[i].Load()
+	}
+	assert.Equal(t, int32(requests), total, "every request is served exactly once")
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+}
@@ -0,0 +1,273 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/synternet/data-layer-sdk/pkg/options"
+	"github.com/synternet/data-layer-sdk/pkg/service"
+	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var _ rpc.SchemaRegistryServer = (*SchemaRegistry)(nil)
+
+// SchemaRegistry advertises the types of the messages published on subjects, together with the Protobuf files
+// defining them, so that subscribers can decode the messages with SchemaCodec without compiling the files in.
+//
+// The registry is served on `service.schema` subject by the registrar created with WithSchemaRegistry option,
+// and it can be stored in a key-value bucket with Store. Subscribers resolve the schemas with SchemaResolver or LoadSchema.
+type SchemaRegistry struct {
+	mu      sync.Mutex
+	schemas map[string]*rpc.SubjectSchema
+}
+
+// NewSchemaRegistry returns an empty registry.
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		schemas: make(map[string]*rpc.SubjectSchema),
+	}
+}
+
+// WithSchemaRegistry registers the schema registry service on Start, and advertises the output types of pure streams
+// on their subjects. The schemas of pure streams follow their subjects when they change, see UpdateVars.
+func WithSchemaRegistry(registry *SchemaRegistry) RegistrarOption {
+	return func(s *ServiceRegistrar) {
+		s.schemas = registry
+	}
+}
+
+// Advertise advertises that the messages of the type of msg are published on the subject made of the tokens,
+// the same way as PublishTo makes it. The subject may contain `*` and `>` wildcards. Advertising the subject
+// again replaces its schema.
+func (r *SchemaRegistry) Advertise(msg proto.Message, tokens ...string) error {
+	return r.advertise(strings.Join(tokens, "."), msg.ProtoReflect().Descriptor())
+}
+
+func (r *SchemaRegistry) advertise(subject string, desc protoreflect.MessageDescriptor) error {
+	schema, err := NewSubjectSchema(subject, desc)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas[subject] = schema
+	return nil
+}
+
+// Withdraw stops advertising the schema of the subject made of the tokens.
+func (r *SchemaRegistry) Withdraw(tokens ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.schemas, strings.Join(tokens, "."))
+}
+
+// Schemas returns the schemas of the subject patterns that overlap with the subject, sorted by subject.
+// All schemas are returned if the subject is empty.
+func (r *SchemaRegistry) Schemas(subject string) []*rpc.SubjectSchema {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ret []*rpc.SubjectSchema
+	for pattern, schema := range r.schemas {
+		if subject == "" || service.Subject(pattern).SymmetricMatch(service.Subject(subject)) {
+			ret = append(ret, schema)
+		}
+	}
+	slices.SortFunc(ret, func(a, b *rpc.SubjectSchema) int {
+		return strings.Compare(a.Subject, b.Subject)
+	})
+	return ret
+}
+
+// ListSchemas implements rpc.SchemaRegistryServer. It fails with codes.NotFound if no schemas match the subject.
+func (r *SchemaRegistry) ListSchemas(_ context.Context, req *rpc.ListSchemasRequest) (*rpc.ListSchemasResponse, error) {
+	schemas := r.Schemas(req.GetSubject())
+	if len(schemas) == 0 && req.GetSubject() != "" {
+		return nil, status.Errorf(codes.NotFound, "no schemas for subject: %s", req.GetSubject())
+	}
+	return &rpc.ListSchemasResponse{Schemas: schemas}, nil
+}
+
+// SchemaBucket is a key-value bucket the schemas are stored in, such as service.KV.
+type SchemaBucket interface {
+	Put(key string, msg proto.Message) (uint64, error)
+	Get(key string, msg proto.Message) (*service.KVEntry, error)
+}
+
+var _ SchemaBucket = (*service.KV)(nil)
+
+// Store puts all advertised schemas into the bucket, under the keys returned by SchemaKey.
+func (r *SchemaRegistry) Store(bucket SchemaBucket) error {
+	for _, schema := range r.Schemas("") {
+		if _, err := bucket.Put(SchemaKey(schema.Subject), schema); err != nil {
+			return fmt.Errorf("schema %s: %w", schema.Subject, err)
+		}
+	}
+	return nil
+}
+
+// SchemaKey returns the key the schema of the subject pattern is stored under. Subject patterns are encoded with
+// URL-safe base64, since the keys must not contain wildcards.
+func SchemaKey(subject string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(subject))
+}
+
+// LoadSchema returns the schema of the subject pattern stored in the bucket with Store.
+func LoadSchema(bucket SchemaBucket, subject string) (*Schema, error) {
+	var schema rpc.SubjectSchema
+	if _, err := bucket.Get(SchemaKey(subject), &schema); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", subject, err)
+	}
+	return NewSchema(&schema)
+}
+
+// NewSubjectSchema describes the messages of the type published on the subject.
+func NewSubjectSchema(subject string, desc protoreflect.MessageDescriptor) (*rpc.SubjectSchema, error) {
+	if err := service.Subject(subject).Validate(); err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range appendFileWithDeps(nil, desc.ParentFile()) {
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	return &rpc.SubjectSchema{
+		Subject:           subject,
+		MessageType:       string(desc.FullName()),
+		FileDescriptorSet: set,
+	}, nil
+}
+
+// Schema is a subject schema resolved into the message descriptor.
+type Schema struct {
+	// Subject is the subject pattern the messages are published on.
+	Subject    string
+	Descriptor protoreflect.MessageDescriptor
+	// Types resolves the types defined in the files of the schema, e.g. for google.protobuf.Any fields.
+	Types *dynamicpb.Types
+}
+
+// NewSchema builds the message descriptor from the files of the subject schema.
+func NewSchema(schema *rpc.SubjectSchema) (*Schema, error) {
+	files, err := protodesc.NewFiles(schema.GetFileDescriptorSet())
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: file descriptors: %w", schema.GetSubject(), err)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(schema.GetMessageType()))
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: message desc %s: %w", schema.GetSubject(), schema.GetMessageType(), err)
+	}
+	msgDesc, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("schema %s: not a message: %s", schema.GetSubject(), schema.GetMessageType())
+	}
+	return &Schema{
+		Subject:    schema.GetSubject(),
+		Descriptor: msgDesc,
+		Types:      dynamicpb.NewTypes(files),
+	}, nil
+}
+
+// New returns an empty message of the schema type.
+func (s *Schema) New() *dynamicpb.Message {
+	return dynamicpb.NewMessage(s.Descriptor)
+}
+
+// SchemaResolver resolves the schemas advertised by the schema registry of a remote publisher.
+type SchemaResolver struct {
+	client rpc.SchemaRegistryClient
+}
+
+// NewSchemaResolver returns a resolver for the remote publisher. The remote prefix has the same meaning as in NewClientConn.
+func NewSchemaResolver(ctx context.Context, sub Publisher, remotePrefix string, opts ...ClientOption) *SchemaResolver {
+	return &SchemaResolver{
+		client: rpc.NewSchemaRegistryClient(NewClientConn(ctx, sub, remotePrefix, nil, opts...)),
+	}
+}
+
+// ListSchemas returns the schemas of the subject patterns that overlap with the subject. All schemas are returned
+// if the subject is empty.
+func (r *SchemaResolver) ListSchemas(ctx context.Context, subject string) ([]*rpc.SubjectSchema, error) {
+	resp, err := r.client.ListSchemas(ctx, &rpc.ListSchemasRequest{Subject: subject})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetSchemas(), nil
+}
+
+// Resolve returns the schema of the messages published on the subject. The subject pattern equal to the subject
+// is preferred over the patterns matching it.
+func (r *SchemaResolver) Resolve(ctx context.Context, subject string) (*Schema, error) {
+	schemas, err := r.ListSchemas(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	var found *rpc.SubjectSchema
+	for _, schema := range schemas {
+		if schema.GetSubject() == subject {
+			found = schema
+			break
+		}
+		if found == nil && service.Subject(schema.GetSubject()).Match(service.Subject(subject)) {
+			found = schema
+		}
+	}
+	if found == nil {
+		return nil, status.Errorf(codes.NotFound, "no schemas for subject: %s", subject)
+	}
+	return NewSchema(found)
+}
+
+var _ options.Codec = (*SchemaCodec)(nil)
+
+// SchemaCodec decodes the messages published on a subject using its schema, so that subscribers do not need
+// to compile the Protobuf files of the publisher in. Messages are decoded either into messages of the schema type,
+// e.g. the one returned by Schema.New, or into *structpb.Struct, which gets the JSON representation of the message.
+//
+// The wrapped codec must match the codec of the publisher, and must support dynamic messages, e.g. codec.PbCodec
+// or codec.ProtoJsonCodec. Messages are encoded with the wrapped codec.
+type SchemaCodec struct {
+	codec  options.Codec
+	schema *Schema
+}
+
+// NewSchemaCodec returns a codec that decodes the messages of the schema with the wrapped codec.
+func NewSchemaCodec(codec options.Codec, schema *Schema) *SchemaCodec {
+	return &SchemaCodec{codec: codec, schema: schema}
+}
+
+// Encode implements options.Codec.
+func (c *SchemaCodec) Encode(buf []byte, msg proto.Message) ([]byte, error) {
+	return c.codec.Encode(buf, msg)
+}
+
+// Decode implements options.Codec.
+func (c *SchemaCodec) Decode(buf []byte, msg proto.Message) error {
+	if msg.ProtoReflect().Descriptor().FullName() == c.schema.Descriptor.FullName() {
+		return c.codec.Decode(buf, msg)
+	}
+	st, ok := msg.(*structpb.Struct)
+	if !ok {
+		return fmt.Errorf("schema %s: cannot decode %s into %s", c.schema.Subject, c.schema.Descriptor.FullName(), msg.ProtoReflect().Descriptor().FullName())
+	}
+	dyn := c.schema.New()
+	if err := c.codec.Decode(buf, dyn); err != nil {
+		return err
+	}
+	data, err := protojson.MarshalOptions{Resolver: c.schema.Types}.Marshal(dyn)
+	if err != nil {
+		return fmt.Errorf("schema %s: %w", c.schema.Subject, err)
+	}
+	return protojson.Unmarshal(data, st)
+}
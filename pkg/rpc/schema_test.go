@@ -0,0 +1,144 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/codec"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	"github.com/synternet/data-layer-sdk/pkg/service"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// mapBucket is an in-memory SchemaBucket.
+type mapBucket map[string][]byte
+
+func (b mapBucket) Put(key string, msg proto.Message) (uint64, error) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	b[key] = data
+	return uint64(len(b)), nil
+}
+
+func (b mapBucket) Get(key string, msg proto.Message) (*service.KVEntry, error) {
+	data, ok := b[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	return nil, proto.Unmarshal(data, msg)
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	registry := rpc.NewSchemaRegistry()
+	require.NoError(t, registry.Advertise(&rpctypes.TestRequest{}, "test_prefix", "requests", "*"))
+	assert.Error(t, registry.Advertise(&rpctypes.TestRequest{}, "test_prefix", "", "requests"))
+
+	grp, gctx := errgroup.WithContext(ctx)
+	pub := NewPublisher(gctx, t, "test_prefix")
+	srv := rpc.NewServiceRegistrar(grp, pub, rpc.WithoutHealth(), rpc.WithSchemaRegistry(registry), rpc.WithProducerPolicy(rpc.ProducerPolicy{InitialBackoff: time.Second}))
+	rpctypes.RegisterTestServiceServer(srv, &statusServer{})
+	require.NoError(t, srv.Start(gctx, nil))
+	resolver := rpc.NewSchemaResolver(ctx, pub, "test_prefix")
+
+	// The output type of the pure stream is advertised on its subject
+	schemas, err := resolver.ListSchemas(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, schemas, 2)
+	assert.Equal(t, "test_prefix.override.test.override.test.stream.data", schemas[0].Subject)
+	assert.Equal(t, "synternet.rpc.TestResponse", schemas[0].MessageType)
+	assert.Equal(t, "test_prefix.requests.*", schemas[1].Subject)
+
+	schemas, err = resolver.ListSchemas(ctx, "test_prefix.override.>")
+	require.NoError(t, err)
+	assert.Len(t, schemas, 1)
+
+	schema, err := resolver.Resolve(ctx, "test_prefix.requests.1")
+	require.NoError(t, err)
+	assert.Equal(t, "test_prefix.requests.*", schema.Subject)
+	assert.Equal(t, "synternet.rpc.TestRequest", string(schema.Descriptor.FullName()))
+
+	_, err = resolver.Resolve(ctx, "test_prefix.missing")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	schema, err = resolver.Resolve(ctx, "test_prefix.override.test.override.test.stream.data")
+	require.NoError(t, err)
+
+	// Subscribers decode the messages without the generated types
+	pbjson := codec.NewProtoJsonCodec()
+	data, err := pbjson.Encode(nil, &rpctypes.TestResponse{Ab: 3})
+	require.NoError(t, err)
+	schemaCodec := rpc.NewSchemaCodec(pbjson, schema)
+
+	var st structpb.Struct
+	require.NoError(t, schemaCodec.Decode(data, &st))
+	assert.Equal(t, 3.0, st.Fields["ab"].GetNumberValue())
+
+	dyn := schema.New()
+	require.NoError(t, schemaCodec.Decode(data, dyn))
+	assert.Equal(t, float32(3), float32(dyn.Get(schema.Descriptor.Fields().ByName("ab")).Float()))
+
+	assert.Error(t, schemaCodec.Decode(data, &rpctypes.TestRequest{}))
+
+	// Schemas are stored in and loaded from a bucket
+	bucket := mapBucket{}
+	require.NoError(t, registry.Store(bucket))
+	assert.Len(t, bucket, 2)
+	loaded, err := rpc.LoadSchema(bucket, "test_prefix.requests.*")
+	require.NoError(t, err)
+	assert.Equal(t, "synternet.rpc.TestRequest", string(loaded.Descriptor.FullName()))
+	_, err = rpc.LoadSchema(bucket, "test_prefix.missing")
+	assert.Error(t, err)
+
+	// The schemas of the pure streams are withdrawn with their services
+	require.NoError(t, srv.Unregister("synternet.rpc.TestService"))
+	assert.Len(t, registry.Schemas(""), 1)
+
+	require.NoError(t, srv.Stop(ctx))
+	cancel()
+	require.NoError(t, grp.Wait())
+}
+
+func TestSchemaKey(t *testing.T) {
+	key := rpc.SchemaKey("org.publisher.>")
+	assert.NotContains(t, key, ">")
+	assert.NotContains(t, key, ".")
+	assert.NotEqual(t, key, rpc.SchemaKey("org.publisher.*"))
+}
+
+func TestNewSchema(t *testing.T) {
+	schema, err := rpc.NewSubjectSchema("a.b", (&rpctypes.ListSchemasResponse{}).ProtoReflect().Descriptor())
+	require.NoError(t, err)
+	// The dependencies precede the files importing them
+	files := schema.FileDescriptorSet.File
+	assert.Equal(t, "synternet/rpc/schema.proto", files[len(files)-1].GetName())
+
+	got, err := rpc.NewSchema(schema)
+	require.NoError(t, err)
+	assert.Equal(t, "synternet.rpc.ListSchemasResponse", string(got.Descriptor.FullName()))
+
+	schema.MessageType = "synternet.rpc.SchemaRegistry"
+	_, err = rpc.NewSchema(schema)
+	assert.ErrorContains(t, err, "not a message")
+
+	schema.MessageType = "synternet.rpc.Missing"
+	_, err = rpc.NewSchema(schema)
+	assert.Error(t, err)
+
+	schema.FileDescriptorSet.File = files[len(files)-1:]
+	_, err = rpc.NewSchema(schema)
+	assert.ErrorContains(t, err, "file descriptors")
+}
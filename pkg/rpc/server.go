@@ -2,22 +2,26 @@ package rpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/synternet/data-layer-sdk/pkg/service"
+	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
-	"google.golang.org/protobuf/reflect/protoregistry"
-
-	_ "github.com/synternet/data-layer-sdk/x/synternet/rpc"
 )
 
 type serviceKey string
@@ -54,6 +58,11 @@ type serviceInfo struct {
 	streams     map[string]*grpc.StreamDesc
 	mdata       any
 	vars        map[string]string
+
+	// ctx is cancelled when the service is stopped
+	ctx      context.Context
+	cancel   context.CancelFunc
+	bindings map[string]*binding
 }
 
 // ServiceRegistrar implements grpc.ServiceRegistrar for NATS
@@ -63,22 +72,64 @@ type ServiceRegistrar struct {
 	pub      Publisher
 	services map[string]*serviceInfo
 	prefix   string
+
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	unaryInterceptor   grpc.UnaryServerInterceptor
+	streamInterceptor  grpc.StreamServerInterceptor
+	disableReflection  bool
+	disableHealth      bool
+	strictSubjects     bool
+	health             *health.Server
+	stopHealth         func() bool
+	queueGroups        map[string]string
+	producerPolicy     ProducerPolicy
+	producers          []*producer
+	started            bool
+	stopped            bool
+	running            tracker
+	metrics            map[string]*methodMetrics
+	schemas            *SchemaRegistry
+	streamIdleTimeout  time.Duration
 }
 
-func NewServiceRegistrar(group *errgroup.Group, pub Publisher) *ServiceRegistrar {
+// NewServiceRegistrar returns a registrar that serves registered Protobuf services over the Publisher.
+// Standard gRPC server interceptors can be installed with WithUnaryInterceptors and WithStreamInterceptors options.
+//
+// If pub is a StatusPublisher, e.g. service.Service, the registrar reports the metrics of every method in telemetry
+// under `rpc.<service>.<method>.` keys: the number of requests, errors by status code, calls in flight, latency
+// percentiles, and stream messages sent and received. The counters cover the period since the last report.
+func NewServiceRegistrar(group *errgroup.Group, pub Publisher, opts ...RegistrarOption) *ServiceRegistrar {
 	ret := &ServiceRegistrar{
 		pub:      pub,
 		group:    group,
 		services: make(map[string]*serviceInfo),
+		metrics:  make(map[string]*methodMetrics),
 		prefix:   "",
+		health:   health.NewServer(),
+
+		streamIdleTimeout: DefaultStreamIdleTimeout,
 	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	ret.unaryInterceptor = chainUnaryServerInterceptors(ret.unaryInterceptors)
+	ret.streamInterceptor = chainStreamServerInterceptors(ret.streamInterceptors)
 
-	// pub.AddStatusCallback(ret.getStatus)
+	if pub, ok := pub.(StatusPublisher); ok {
+		pub.AddStatusCallback(ret.getStatus)
+	}
 	return ret
 }
 
+// getStatus returns the metrics of the methods and the state of the producers, which are reported in telemetry
+// under `rpc.<service>.<method>.` keys.
 func (s *ServiceRegistrar) getStatus() map[string]string {
-	return map[string]string{}
+	ret := s.metricsTelemetry()
+	for k, v := range s.producerTelemetry() {
+		ret[k] = v
+	}
+	return ret
 }
 
 // RegisterService is called by the autogenerated Protobuf service code.
@@ -151,153 +202,330 @@ func extractServiceVars(svcName string, vars map[string]string) map[string]strin
 //
 // It automatically derives the correct subjects based on the Protobuf schema, however, you may need to pass vars.
 // The subjects derived if contain any tokens such as `{id}` will be substituted by the string stored under key "id" under a specific service key.
-// Otherwise such subject token will be replaced with `*`.
+// Otherwise such subject token will be replaced with `*`, unless WithStrictSubjects option is set, in which case Start fails
+// with VariablesError listing the missing and unused variables. Malformed subject templates, invalid variable patterns, and
+// values that do not match the variable schemas declared with `subject_variables` and `method_subject_variables` options,
+// always fail Start.
 //
 // You can also specify the full name of the service and the variable using fully qualified name of the service if more than one service shares the same variable.
+//
+// Handlers get the variables matched by the request subject with Vars. The values are validated against the variable
+// schemas, and copied to the request fields bound with the `bind_variable` option. Requests that fail the validation,
+// or whose bound fields do not match the subject, are rejected with codes.InvalidArgument.
+//
+// Handlers of pure-stream methods are producers that run in the group until ctx is done. They are restarted with backoff
+// if they fail, and may run only while their subjects have consumers. See WithProducerPolicy and Producers.
+//
+// Start fails if the registrar is already started. If a service cannot be bound, none of the services are served,
+// and Start can be called again. The subjects can be changed with UpdateVars while it runs,
+// services can be removed with Unregister, and Stop stops serving all of them.
+//
+// Start also registers the reflection service on `service.reflection` subject, which describes the registered services,
+// their subjects and file descriptors (see Resolver). It can be disabled with WithoutReflection option.
+// Likewise, Start registers grpc.health.v1.Health service (see Health), which can be disabled with WithoutHealth option.
+// The schema registry service is registered on `service.schema` subject with WithSchemaRegistry option.
 func (s *ServiceRegistrar) Start(ctx context.Context, vars map[string]string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.started {
+		return fmt.Errorf("registrar is already started")
+	}
+	if _, ok := s.services[rpc.Reflection_ServiceDesc.ServiceName]; !ok && !s.disableReflection {
+		s.register(&rpc.Reflection_ServiceDesc, &reflectionServer{registrar: s})
+	}
+	if _, ok := s.services[rpc.SchemaRegistry_ServiceDesc.ServiceName]; !ok && s.schemas != nil {
+		s.register(&rpc.SchemaRegistry_ServiceDesc, s.schemas)
+	}
+	if s.strictSubjects {
+		if err := s.checkSubjects(vars); err != nil {
+			return fmt.Errorf("subjects: %w", err)
+		}
+	}
+	s.startHealth(ctx)
+	s.started = true
 	for _, svc := range s.services {
 		svc.vars = extractServiceVars(svc.serviceDesc.ServiceName, vars)
+		svc.ctx, svc.cancel = context.WithCancel(ctx)
 
-		err := s.startUnaryMethods(ctx, svc)
-		if err != nil {
-			return fmt.Errorf("unary methods: %w", err)
-		}
-		err = s.startStreamMethods(ctx, svc)
+		bindings, err := s.bindService(svc, svc.vars)
 		if err != nil {
-			return fmt.Errorf("stream methods: %w", err)
+			// Stop serving the services bound so far, so that Start can be retried
+			for _, cancel := range s.halt() {
+				cancel()
+			}
+			return err
 		}
+		svc.bindings = bindings
 	}
 	return nil
 }
 
-func (s *ServiceRegistrar) startUnaryMethods(ctx context.Context, svc *serviceInfo) error {
-	serviceDesc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(svc.serviceDesc.ServiceName))
+// serveUnary serves the unary method on the subject of the binding.
+func (s *ServiceRegistrar) serveUnary(svc *serviceInfo, svcDesc protoreflect.ServiceDescriptor, methodDesc protoreflect.MethodDescriptor, method *grpc.MethodDesc, b *binding, tokens []string) (*nats.Subscription, error) {
+	matcher, err := newSubjectMatcher(s.prefix, svcDesc, methodDesc)
 	if err != nil {
-		return fmt.Errorf("service desc: %w", err)
+		return nil, fmt.Errorf("subject matcher: %w", err)
 	}
-	svcDesc := serviceDesc.(protoreflect.ServiceDescriptor)
-
-	for _, method := range svc.methods {
-		methodDesc := svcDesc.Methods().ByName(protoreflect.Name(method.MethodName))
-		if methodDesc == nil {
-			return fmt.Errorf("service desc nil: %s@%s", method.MethodName, svc.serviceDesc.ServiceName)
-		}
-
-		// deriveSubject uses svc.desc and mDesc to compute the subject tokens.
-		tokens := deriveSubject(s.prefix, serviceDesc, methodDesc, svc.vars)
-		if tokens == nil {
-			return fmt.Errorf("failed to derive subject for method %s", method.MethodName)
-		}
-		slog.Debug("ServiceRegistrar", "service", svcDesc.FullName(), "method", method.MethodName, "subject", s.pub.Subject(tokens...))
-		if disableSubscription(methodDesc) {
-			continue
+	ctx := svc.ctx
+	fullMethod := fmt.Sprintf("/%s/%s", svc.serviceDesc.ServiceName, method.MethodName)
+	metrics := s.methodMetrics(fullMethod)
+	// Create a handler that reuses a decoder function.
+	serve := func(msg service.Message) (proto.Message, nats.Header, error) {
+		vars, err := matcher.match(msg.Subject())
+		if err != nil {
+			return nil, nil, status.Error(codes.InvalidArgument, err.Error())
 		}
-		// Create a handler that reuses a decoder function.
-		handler := func(msg service.Message) (proto.Message, error) {
-			// Build a decoder function: the generated handler will call dec with a new request instance.
-			dec := func(v interface{}) error {
-				// We expect v to be a proto.Message.
-				pm, ok := v.(proto.Message)
-				if !ok {
-					return fmt.Errorf("expected proto.Message, got %T", v)
-				}
-				_, err := s.pub.Unmarshal(msg, pm)
-				return err
+		// Build a decoder function: the generated handler will call dec with a new request instance.
+		dec := func(v interface{}) error {
+			// We expect v to be a proto.Message.
+			pm, ok := v.(proto.Message)
+			if !ok {
+				return fmt.Errorf("expected proto.Message, got %T", v)
 			}
-			ctx := addSubject(ctx, service.Subject(msg.Subject()))
-			ctx = addHeaders(ctx, msg.Header())
-
-			// Invoke the generated handler directly.
-			// The handler has signature:
-			//   func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error)
-			out, err := method.Handler(svc.serviceImpl, ctx, dec, nil)
-			if err != nil {
-				return nil, fmt.Errorf("%s.%s: %w", svcDesc.FullName(), method.MethodName, err)
+			if _, err := s.pub.Unmarshal(msg, pm); err != nil {
+				return err
 			}
-			return out.(proto.Message), nil
+			return bindRequest(pm, vars)
 		}
-
-		if _, err := s.pub.Serve(handler, tokens...); err != nil {
-			return fmt.Errorf("failed to serve on %v: %w", tokens, err)
+		ctx, cancel := withTimeoutHeader(ctx, msg.Header())
+		defer cancel()
+		ctx = addSubject(ctx, service.Subject(msg.Subject()))
+		ctx = addHeaders(ctx, msg.Header())
+		ctx = addVars(ctx, vars)
+		ctx = metadata.NewIncomingContext(ctx, metadataFromHeader(msg.Header(), MetadataHeaderPrefix))
+		transport := &unaryTransportStream{method: fullMethod}
+		ctx = grpc.NewContextWithServerTransportStream(ctx, transport)
+
+		// Invoke the generated handler directly.
+		// The handler has signature:
+		//   func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error)
+		out, err := method.Handler(svc.serviceImpl, ctx, dec, s.unaryInterceptor)
+		if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			err = status.FromContextError(err).Err()
+		}
+		if _, ok := status.FromError(err); ok && err != nil {
+			return nil, transport.responseHeader(), err
+		}
+		if err != nil {
+			return nil, transport.responseHeader(), fmt.Errorf("%s.%s: %w", svcDesc.FullName(), method.MethodName, err)
 		}
+		return out.(proto.Message), transport.responseHeader(), nil
 	}
-	return nil
+	handler := func(msg service.Message) (proto.Message, nats.Header, error) {
+		if b.closed.Load() {
+			return nil, nil, errClosed
+		}
+		s.running.add()
+		defer s.running.done()
+
+		started := metrics.begin()
+		out, header, err := serve(msg)
+		metrics.end(started, err)
+		return out, header, err
+	}
+
+	sub, err := s.pub.QueueServeWithHeader(handler, s.queueGroup(svcDesc, methodDesc), tokens...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve on %v: %w", tokens, err)
+	}
+	return sub, nil
 }
 
-func (s *ServiceRegistrar) startStreamMethods(ctx context.Context, svc *serviceInfo) error {
-	serviceDesc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(svc.serviceDesc.ServiceName))
+// serveStream accepts the streaming sessions of the method opened on the subject of the binding.
+func (s *ServiceRegistrar) serveStream(svc *serviceInfo, svcDesc protoreflect.ServiceDescriptor, methodDesc protoreflect.MethodDescriptor, stream *grpc.StreamDesc, b *binding) (*nats.Subscription, error) {
+	matcher, err := newSubjectMatcher(s.prefix, svcDesc, methodDesc)
 	if err != nil {
-		return fmt.Errorf("service desc: %w", err)
-	}
-	svcDesc := serviceDesc.(protoreflect.ServiceDescriptor)
-
-	for _, stream := range svc.streams {
-		methodDesc := svcDesc.Methods().ByName(protoreflect.Name(stream.StreamName))
-		tokens := deriveSubject(s.prefix, svcDesc, methodDesc, svc.vars)
-		slog.Debug("ServiceRegistrar", "service", svcDesc.FullName(), "stream", stream.StreamName, "subject", s.pub.Subject(tokens...))
-
-		if disableSubscription(methodDesc) {
-			go func() {
-				serverStream := &serverStream{msg: nil, ctx: ctx, pub: s.pub, subject: s.pub.Subject(tokens...)}
-				err = stream.Handler(svc.serviceImpl, serverStream)
-				if err != nil {
-					slog.Debug("running a handler", "err", err, "service", svcDesc.FullName(), "stream", stream.StreamName, "subject", s.pub.Subject(tokens...))
-				}
-			}()
-			continue
+		return nil, fmt.Errorf("subject matcher: %w", err)
+	}
+	ctx := svc.ctx
+	metrics := s.methodMetrics(fmt.Sprintf("/%s/%s", svc.serviceDesc.ServiceName, stream.StreamName))
+	handler := func(msg service.Message) {
+		if b.closed.Load() {
+			return
 		}
+		if frame := getFrameType(msg.Header()); frame != frameOpen {
+			slog.Debug("unexpected stream frame", "frame", frame, "service", svcDesc.FullName(), "stream", stream.StreamName, "subject", msg.Subject())
+			return
+		}
+		if err := s.openStream(ctx, svc, stream, matcher, metrics, msg); err != nil {
+			slog.Warn("opening a stream failed", "err", err, "service", svcDesc.FullName(), "stream", stream.StreamName, "subject", msg.Subject())
+		}
+	}
 
-		handler := func(msg service.Message) {
-			ctx := addSubject(ctx, service.Subject(msg.Subject()))
-			ctx = addHeaders(ctx, msg.Header())
-			// Create the stream
-			serverStream := &serverStream{msg: msg, ctx: ctx, pub: s.pub}
-
-			// Call the implementation
-			// type StreamHandler func(srv any, stream ServerStream) error
-			if err := stream.Handler(svc.serviceImpl, serverStream); err != nil {
-				msg.Nak()
-				return
-			}
+	sub, err := s.pub.QueueSubscribeTo(handler, s.queueGroup(svcDesc, methodDesc), b.subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to %v: %v", b.subject, err)
+	}
+	return sub, nil
+}
 
-			msg.Ack()
-		}
+// openStream accepts a streaming session opened by the client and runs the stream handler in the group.
+// The session is finished with codes.InvalidArgument if the subject variables are invalid.
+func (s *ServiceRegistrar) openStream(ctx context.Context, svc *serviceInfo, stream *grpc.StreamDesc, matcher *subjectMatcher, metrics *methodMetrics, msg service.Message) error {
+	streamId := msg.Header().Get(StreamIdHeader)
+	if streamId == "" || msg.Reply() == "" {
+		return fmt.Errorf("stream id or reply subject missing")
+	}
+	vars, varsErr := matcher.match(msg.Subject())
+	if varsErr != nil {
+		varsErr = status.Error(codes.InvalidArgument, varsErr.Error())
+	}
 
-		if _, err := s.pub.SubscribeTo(handler, s.pub.Subject(tokens...)); err != nil {
-			return fmt.Errorf("failed to subscribe to %v: %v", tokens, err)
-		}
+	ctx, cancelTimeout := withTimeoutHeader(ctx, msg.Header())
+	ctx = addSubject(ctx, service.Subject(msg.Subject()))
+	ctx = addHeaders(ctx, msg.Header())
+	ctx = addVars(ctx, vars)
+	ctx = metadata.NewIncomingContext(ctx, metadataFromHeader(msg.Header(), MetadataHeaderPrefix))
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	cancel := func(err error) {
+		cancelCause(err)
+		cancelTimeout()
+	}
+
+	session := newStreamSession(s.pub, streamId, msg.Reply())
+	// The open frame is the first frame of the client
+	session.recvSeq.Store(1)
+	serverStream := &serverStream{
+		cancel:   cancel,
+		pub:      s.pub,
+		msg:      msg,
+		vars:     vars,
+		metrics:  metrics,
+		session:  session,
+		recvChan: make(chan service.Message, streamRecvBuffer),
+	}
+	if s.streamIdleTimeout > 0 {
+		serverStream.idleTimeout = s.streamIdleTimeout
+		serverStream.idle = time.AfterFunc(s.streamIdleTimeout, func() {
+			cancel(status.Error(codes.Unavailable, "stream idle timeout"))
+		})
+	}
+	transport := &serverTransportStream{serverStream: serverStream, method: fmt.Sprintf("/%s/%s", svc.serviceDesc.ServiceName, stream.StreamName)}
+	serverStream.ctx = grpc.NewContextWithServerTransportStream(ctx, transport)
+
+	sub, err := s.pub.SubscribeTo(serverStream.handleFrame, session.inbox)
+	if err != nil {
+		serverStream.stopIdle()
+		cancel(err)
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	if err := session.send(frameHeader, nil); err != nil {
+		serverStream.stopIdle()
+		cancel(err)
+		sub.Unsubscribe()
+		return fmt.Errorf("header frame: %w", err)
 	}
+
+	s.running.add()
+	started := metrics.begin()
+	s.group.Go(func() error {
+		defer s.running.done()
+		defer sub.Unsubscribe()
+		defer cancel(nil)
+		defer serverStream.stopIdle()
+
+		err := varsErr
+		if err == nil {
+			err = s.handleStream(svc, stream, serverStream)
+		}
+		metrics.end(started, err)
+		if err := serverStream.finish(err); err != nil {
+			slog.Debug("finishing a stream", "err", err, "stream", stream.StreamName)
+		}
+		return nil
+	})
 	return nil
 }
 
+// handleStream calls the stream handler through the stream interceptor chain.
+func (s *ServiceRegistrar) handleStream(svc *serviceInfo, stream *grpc.StreamDesc, ss grpc.ServerStream) error {
+	// Call the implementation
+	// type StreamHandler func(srv any, stream ServerStream) error
+	if s.streamInterceptor == nil {
+		return stream.Handler(svc.serviceImpl, ss)
+	}
+	info := &grpc.StreamServerInfo{
+		FullMethod:     fmt.Sprintf("/%s/%s", svc.serviceDesc.ServiceName, stream.StreamName),
+		IsClientStream: stream.ClientStreams,
+		IsServerStream: stream.ServerStreams,
+	}
+	return s.streamInterceptor(svc.serviceImpl, ss, info, stream.Handler)
+}
+
 var _ grpc.ServerStream = (*serverStream)(nil)
 
 // serverStream implements grpc.ServerStream
 type serverStream struct {
 	ctx     context.Context
+	cancel  context.CancelCauseFunc
 	pub     Publisher
 	msg     service.Message
 	subject string
+	// vars are bound to the received messages
+	vars    map[string]string
+	metrics *methodMetrics
+
+	// session is nil for pure streams
+	session    *streamSession
+	recvChan   chan service.Message
+	recvClosed bool
+	// idle cancels the session if neither side sends frames for idleTimeout; nil if disabled
+	idle        *time.Timer
+	idleTimeout time.Duration
+
+	mu          sync.Mutex
+	header      metadata.MD
+	headerSent  bool
+	trailer     metadata.MD
+	idleStopped bool
 }
 
+// SetHeader sets the header metadata which will be sent with the first response frame.
+// Multiple calls merge the metadata. It fails if the header has already been sent.
 func (s *serverStream) SetHeader(md metadata.MD) error {
-	header := nats.Header{}
-	for k, v := range md {
-		header[k] = v
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.headerSent {
+		return status.Error(codes.Internal, "header already sent")
 	}
-	s.msg.Message().Header = header
+	s.header = metadata.Join(s.header, md)
 	return nil
 }
 
-// SendHeader sets headers but not sends them. The headers will be attached together with the response message.
+// SendHeader sends the header metadata immediately.
 func (s *serverStream) SendHeader(md metadata.MD) error {
-	return s.SetHeader(md)
+	if err := s.SetHeader(md); err != nil {
+		return err
+	}
+	if s.session == nil {
+		return nil
+	}
+	if err := s.session.sendWithHeader(frameMetadata, nil, s.takeHeader()); err != nil {
+		return err
+	}
+	s.resetIdle()
+	return nil
 }
 
+// takeHeader returns the header metadata encoded as frame headers if it has not been sent yet.
+func (s *serverStream) takeHeader() nats.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.headerSent {
+		return nil
+	}
+	s.headerSent = true
+	header := nats.Header{}
+	metadataToHeader(header, ResponseHeaderPrefix, s.header)
+	return header
+}
+
+// SetTrailer sets the trailer metadata which will be sent with the end-of-stream or error frame.
+// Multiple calls merge the metadata.
 func (s *serverStream) SetTrailer(md metadata.MD) {
-	// No direct trailer support in NATS
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trailer = metadata.Join(s.trailer, md)
 }
 
 func (s *serverStream) Context() context.Context {
@@ -310,23 +538,136 @@ func (s *serverStream) SendMsg(m interface{}) error {
 	if !ok {
 		return fmt.Errorf("invalid message type")
 	}
-	// TODO: Set response headers.
-	if s.msg == nil {
-		return s.pub.PublishTo(msg, s.subject)
+	if s.ctx.Err() != nil {
+		return contextError(s.ctx)
 	}
-
-	return s.msg.Respond(msg)
+	var err error
+	if s.session == nil {
+		err = s.pub.PublishTo(msg, s.subject)
+	} else {
+		err = s.session.sendWithHeader(frameMsg, msg, s.takeHeader())
+	}
+	if err == nil {
+		s.metrics.sent.Add(1)
+		s.resetIdle()
+	}
+	return err
 }
 
-// RecvMsg supports only single input message at the moment.
+// RecvMsg receives the next message from the client. It returns io.EOF once the client has closed sending.
 func (s *serverStream) RecvMsg(m interface{}) error {
-	if s.msg == nil {
+	if s.session == nil {
 		return nil
 	}
 	msg, ok := m.(proto.Message)
 	if !ok {
 		return fmt.Errorf("invalid message type")
 	}
-	_, err := s.pub.Unmarshal(s.msg, msg)
-	return err
+	if s.recvClosed {
+		return io.EOF
+	}
+
+	select {
+	case <-s.ctx.Done():
+		return contextError(s.ctx)
+	case frame := <-s.recvChan:
+		if getFrameType(frame.Header()) == frameCloseSend {
+			s.recvClosed = true
+			return io.EOF
+		}
+		if _, err := s.pub.Unmarshal(frame, msg); err != nil {
+			return err
+		}
+		s.metrics.received.Add(1)
+		return bindRequest(msg, s.vars)
+	}
+}
+
+// handleFrame processes frames sent by the client to the session inbox.
+func (s *serverStream) handleFrame(msg service.Message) {
+	frame, err := s.session.accept(msg)
+	if errors.Is(err, ErrForeignFrame) {
+		return
+	}
+	if err != nil {
+		s.cancel(status.Error(codes.DataLoss, err.Error()))
+		return
+	}
+
+	switch frame {
+	case frameMsg, frameCloseSend:
+		if frame == frameCloseSend {
+			s.stopIdle()
+		} else {
+			s.resetIdle()
+		}
+		// Blocking here would stall the subscription, so a client that outpaces the handler fails the stream
+		select {
+		case s.recvChan <- msg:
+		default:
+			s.cancel(status.Error(codes.ResourceExhausted, "stream receive buffer is full"))
+		}
+	case frameCancel:
+		st, _ := statusFromHeader(msg.Header())
+		s.cancel(st.Err())
+	default:
+		slog.Debug("unexpected stream frame", "frame", frame, "stream_id", s.session.id)
+	}
+}
+
+// resetIdle restarts the idle timeout of the session unless it has been stopped.
+func (s *serverStream) resetIdle() {
+	if s.idle == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.idleStopped {
+		s.idle.Reset(s.idleTimeout)
+	}
+}
+
+// stopIdle stops the idle timeout of the session for good.
+func (s *serverStream) stopIdle() {
+	if s.idle == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idleStopped = true
+	s.idle.Stop()
+}
+
+// finish terminates the stream with an end-of-stream or an error frame carrying the status and trailers.
+func (s *serverStream) finish(err error) error {
+	s.mu.Lock()
+	trailer := s.trailer
+	s.mu.Unlock()
+
+	header := statusHeader(status.Convert(err), trailer)
+	for k, v := range s.takeHeader() {
+		header[k] = v
+	}
+	if err != nil {
+		rpcErr, _ := service.NewRpcError(err)
+		return s.session.sendWithHeader(frameError, rpcErr, header)
+	}
+	return s.session.sendWithHeader(frameEOS, nil, header)
+}
+
+var _ grpc.ServerTransportStream = (*serverTransportStream)(nil)
+
+// serverTransportStream allows using grpc.SetHeader, grpc.SendHeader and grpc.SetTrailer with the stream context.
+type serverTransportStream struct {
+	*serverStream
+	method string
+}
+
+func (t *serverTransportStream) Method() string {
+	return t.method
+}
+
+func (t *serverTransportStream) SetTrailer(md metadata.MD) error {
+	t.serverStream.SetTrailer(md)
+	return nil
 }
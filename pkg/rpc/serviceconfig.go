@@ -0,0 +1,135 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// serviceConfig is the subset of gRPC service config used by ClientConn.
+type serviceConfig struct {
+	MethodConfig []struct {
+		Name []struct {
+			Service string `json:"service"`
+			Method  string `json:"method"`
+		} `json:"name"`
+		Timeout     *string `json:"timeout"`
+		RetryPolicy *struct {
+			MaxAttempts          int          `json:"maxAttempts"`
+			InitialBackoff       string       `json:"initialBackoff"`
+			MaxBackoff           string       `json:"maxBackoff"`
+			BackoffMultiplier    float64      `json:"backoffMultiplier"`
+			RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+		} `json:"retryPolicy"`
+		HedgingPolicy *struct {
+			MaxAttempts         int          `json:"maxAttempts"`
+			HedgingDelay        *string      `json:"hedgingDelay"`
+			NonFatalStatusCodes []codes.Code `json:"nonFatalStatusCodes"`
+		} `json:"hedgingPolicy"`
+	} `json:"methodConfig"`
+}
+
+// ParseServiceConfig parses gRPC service config JSON and returns an option that applies its method configs.
+// Only `methodConfig` with `name`, `timeout`, `retryPolicy` and `hedgingPolicy` is supported, for example:
+//
+//	{
+//	  "methodConfig": [{
+//	    "name": [{"service": "pkg.MyService", "method": "Method"}],
+//	    "timeout": "1.5s",
+//	    "retryPolicy": {
+//	      "maxAttempts": 3,
+//	      "initialBackoff": "0.1s",
+//	      "maxBackoff": "1s",
+//	      "backoffMultiplier": 2,
+//	      "retryableStatusCodes": ["UNAVAILABLE"]
+//	    }
+//	  }]
+//	}
+//
+// The name without a method applies to all methods of the service, and the empty name applies to all services.
+func ParseServiceConfig(js string) (ClientOption, error) {
+	var sc serviceConfig
+	if err := json.Unmarshal([]byte(js), &sc); err != nil {
+		return nil, fmt.Errorf("service config: %w", err)
+	}
+
+	configs := make(map[string]MethodConfig)
+	for i, mc := range sc.MethodConfig {
+		var cfg MethodConfig
+		var err error
+		if mc.Timeout != nil {
+			if cfg.Timeout, err = parseDuration(*mc.Timeout); err != nil {
+				return nil, fmt.Errorf("method config %d: timeout: %w", i, err)
+			}
+		}
+		if mc.RetryPolicy != nil && mc.HedgingPolicy != nil {
+			return nil, fmt.Errorf("method config %d: retryPolicy and hedgingPolicy are mutually exclusive", i)
+		}
+		if rp := mc.RetryPolicy; rp != nil {
+			cfg.RetryPolicy = &RetryPolicy{
+				MaxAttempts:          rp.MaxAttempts,
+				BackoffMultiplier:    rp.BackoffMultiplier,
+				RetryableStatusCodes: rp.RetryableStatusCodes,
+			}
+			if cfg.RetryPolicy.InitialBackoff, err = parseDuration(rp.InitialBackoff); err != nil {
+				return nil, fmt.Errorf("method config %d: initialBackoff: %w", i, err)
+			}
+			if cfg.RetryPolicy.MaxBackoff, err = parseDuration(rp.MaxBackoff); err != nil {
+				return nil, fmt.Errorf("method config %d: maxBackoff: %w", i, err)
+			}
+			if err := cfg.RetryPolicy.validate(); err != nil {
+				return nil, fmt.Errorf("method config %d: %w", i, err)
+			}
+		}
+		if hp := mc.HedgingPolicy; hp != nil {
+			cfg.HedgingPolicy = &HedgingPolicy{
+				MaxAttempts:         hp.MaxAttempts,
+				NonFatalStatusCodes: hp.NonFatalStatusCodes,
+			}
+			if hp.HedgingDelay != nil {
+				if cfg.HedgingPolicy.HedgingDelay, err = parseDuration(*hp.HedgingDelay); err != nil {
+					return nil, fmt.Errorf("method config %d: hedgingDelay: %w", i, err)
+				}
+			}
+			if err := cfg.HedgingPolicy.validate(); err != nil {
+				return nil, fmt.Errorf("method config %d: %w", i, err)
+			}
+		}
+
+		for _, name := range mc.Name {
+			var key string
+			switch {
+			case name.Service == "" && name.Method != "":
+				return nil, fmt.Errorf("method config %d: method %q without a service", i, name.Method)
+			case name.Method != "":
+				key = fmt.Sprintf("/%s/%s", name.Service, name.Method)
+			default:
+				key = name.Service
+			}
+			if _, ok := configs[key]; ok {
+				return nil, fmt.Errorf("method config %d: duplicate name %q", i, key)
+			}
+			configs[key] = cfg
+		}
+	}
+
+	return func(c *ClientConn) {
+		for name, cfg := range configs {
+			WithMethodConfig(name, cfg)(c)
+		}
+	}, nil
+}
+
+// parseDuration parses JSON encoded google.protobuf.Duration, e.g. `1.5s`.
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" || s[len(s)-1] != 's' {
+		return 0, fmt.Errorf("invalid duration: %q", s)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,167 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseServiceConfig(t *testing.T) {
+	opt, err := ParseServiceConfig(`{
+		"methodConfig": [
+			{
+				"name": [{"service": "synternet.rpc.TestService", "method": "Test"}],
+				"timeout": "1.5s",
+				"retryPolicy": {
+					"maxAttempts": 10,
+					"initialBackoff": "0.1s",
+					"maxBackoff": "1s",
+					"backoffMultiplier": 2,
+					"retryableStatusCodes": ["UNAVAILABLE", 4]
+				}
+			},
+			{
+				"name": [{"service": "synternet.rpc.TestService"}],
+				"hedgingPolicy": {
+					"maxAttempts": 3,
+					"hedgingDelay": "0.05s",
+					"nonFatalStatusCodes": ["UNAVAILABLE"]
+				}
+			},
+			{
+				"name": [{}],
+				"timeout": "10s"
+			}
+		]
+	}`)
+	require.NoError(t, err)
+
+	c := &ClientConn{}
+	opt(c)
+
+	cfg := c.methodConfig("/synternet.rpc.TestService/Test")
+	assert.Equal(t, 1500*time.Millisecond, cfg.Timeout)
+	require.NotNil(t, cfg.RetryPolicy)
+	assert.Equal(t, &RetryPolicy{
+		MaxAttempts:          5,
+		InitialBackoff:       100 * time.Millisecond,
+		MaxBackoff:           time.Second,
+		BackoffMultiplier:    2,
+		RetryableStatusCodes: []codes.Code{codes.Unavailable, codes.DeadlineExceeded},
+	}, cfg.RetryPolicy)
+	assert.Nil(t, cfg.HedgingPolicy)
+
+	cfg = c.methodConfig("/synternet.rpc.TestService/TestVars")
+	assert.Nil(t, cfg.RetryPolicy)
+	assert.Equal(t, &HedgingPolicy{
+		MaxAttempts:         3,
+		HedgingDelay:        50 * time.Millisecond,
+		NonFatalStatusCodes: []codes.Code{codes.Unavailable},
+	}, cfg.HedgingPolicy)
+
+	cfg = c.methodConfig("/synternet.rpc.OtherService/Method")
+	assert.Equal(t, MethodConfig{Timeout: 10 * time.Second}, cfg)
+}
+
+func TestParseServiceConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"json", `{"methodConfig": [`},
+		{"timeout", `{"methodConfig": [{"name": [{}], "timeout": "1h"}]}`},
+		{"method without service", `{"methodConfig": [{"name": [{"method": "Test"}]}]}`},
+		{"duplicate name", `{"methodConfig": [{"name": [{}]}, {"name": [{}]}]}`},
+		{"both policies", `{"methodConfig": [{"name": [{}],
+			"retryPolicy": {"maxAttempts": 2, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": ["UNAVAILABLE"]},
+			"hedgingPolicy": {"maxAttempts": 2}}]}`},
+		{"retry attempts", `{"methodConfig": [{"name": [{}],
+			"retryPolicy": {"maxAttempts": 1, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": ["UNAVAILABLE"]}}]}`},
+		{"retry backoff", `{"methodConfig": [{"name": [{}],
+			"retryPolicy": {"maxAttempts": 2, "maxBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": ["UNAVAILABLE"]}}]}`},
+		{"retry codes", `{"methodConfig": [{"name": [{}],
+			"retryPolicy": {"maxAttempts": 2, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 1}}]}`},
+		{"retry unknown code", `{"methodConfig": [{"name": [{}],
+			"retryPolicy": {"maxAttempts": 2, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": ["BROKEN"]}}]}`},
+		{"hedging attempts", `{"methodConfig": [{"name": [{}], "hedgingPolicy": {"maxAttempts": 1}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseServiceConfig(tt.config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := &RetryPolicy{
+		InitialBackoff:    10 * time.Millisecond,
+		MaxBackoff:        50 * time.Millisecond,
+		BackoffMultiplier: 2,
+	}
+	limits := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}
+	for i, limit := range limits {
+		for range 100 {
+			d := p.backoff(i + 1)
+			assert.GreaterOrEqual(t, d, time.Duration(0))
+			assert.Less(t, d, limit)
+		}
+	}
+}
+
+func TestWithMethodConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  MethodConfig
+	}{
+		{"both policies", MethodConfig{
+			RetryPolicy:   &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Second, MaxBackoff: time.Second, BackoffMultiplier: 1, RetryableStatusCodes: []codes.Code{codes.Unavailable}},
+			HedgingPolicy: &HedgingPolicy{MaxAttempts: 2},
+		}},
+		{"retry attempts", MethodConfig{RetryPolicy: &RetryPolicy{MaxAttempts: 0}}},
+		{"hedging attempts", MethodConfig{HedgingPolicy: &HedgingPolicy{MaxAttempts: 0, NonFatalStatusCodes: []codes.Code{codes.Unavailable}}}},
+		{"negative timeout", MethodConfig{Timeout: -time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c ClientConn
+			WithMethodConfig("", tt.cfg)(&c)
+			assert.Equal(t, codes.InvalidArgument, status.Code(c.configErr))
+			assert.Empty(t, c.methodConfigs)
+		})
+	}
+
+	// The attempts are limited on a copy of the policy
+	policy := &HedgingPolicy{MaxAttempts: 10}
+	var c ClientConn
+	WithMethodConfig("", MethodConfig{HedgingPolicy: policy})(&c)
+	require.NoError(t, c.configErr)
+	assert.Equal(t, 10, policy.MaxAttempts)
+	assert.Equal(t, maxAttempts, c.methodConfigs[""].HedgingPolicy.MaxAttempts)
+}
+
+func Test_hedge_context(t *testing.T) {
+	// The calls ignore ctx, and never return before the test ends
+	release := make(chan struct{})
+	defer close(release)
+	call := func(context.Context) (int, error) {
+		<-release
+		return 0, nil
+	}
+	policy := &HedgingPolicy{MaxAttempts: 3, HedgingDelay: time.Millisecond, NonFatalStatusCodes: []codes.Code{codes.Unavailable}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	_, err := hedge(ctx, policy, call)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = hedge(ctx, policy, call)
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
@@ -0,0 +1,102 @@
+package rpc_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/rpc"
+	rpctypes "github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusServer fails the calls with negative values, and streams three messages.
+type statusServer struct {
+	rpctypes.UnimplementedTestServiceServer
+}
+
+func (s *statusServer) Test(_ context.Context, r *rpctypes.TestRequest) (*rpctypes.TestResponse, error) {
+	if r.A < 0 {
+		return nil, status.Error(codes.InvalidArgument, "negative value")
+	}
+	return &rpctypes.TestResponse{Ab: r.A + r.B}, nil
+}
+
+func (s *statusServer) TestStream(r *rpctypes.TestRequest, srv rpctypes.TestService_TestStreamServer) error {
+	for range 3 {
+		if err := srv.Send(&rpctypes.TestResponse{Ab: r.A + r.B}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRegistrarStatus(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	grp, gctx := errgroup.WithContext(ctx)
+	pub := NewPublisher(gctx, t, "test_prefix")
+	srv := rpc.NewServiceRegistrar(grp, pub, rpc.WithoutHealth(), rpc.WithoutReflection(), rpc.WithProducerPolicy(rpc.ProducerPolicy{InitialBackoff: time.Second}))
+	rpctypes.RegisterTestServiceServer(srv, &statusServer{})
+	require.NoError(t, srv.Start(gctx, map[string]string{"variable": "1"}))
+	client := rpctypes.NewTestServiceClient(rpc.NewClientConn(ctx, pub, "test_prefix", nil))
+
+	// The unimplemented producer fails
+	assert.Eventually(t, func() bool {
+		return srv.Producers()[0].Restarts == 1
+	}, time.Millisecond*500, time.Millisecond)
+	producers := pub.Status()
+	assert.Equal(t, "1", producers["rpc.synternet.rpc.TestService.TestStreamOnly.producer.restarts"])
+	assert.Equal(t, "backoff", producers["rpc.synternet.rpc.TestService.TestStreamOnly.producer.state"])
+	assert.Equal(t, "1", producers["rpc.synternet.rpc.TestService.TestStreamOnly.errors.Unimplemented"])
+
+	for _, a := range []float32{1, 2, -1} {
+		_, err := client.Test(ctx, &rpctypes.TestRequest{A: a})
+		if a < 0 {
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+	str, err := client.TestStream(ctx, &rpctypes.TestRequest{A: 1})
+	require.NoError(t, err)
+	for {
+		var msg rpctypes.TestResponse
+		if err := str.RecvMsg(&msg); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+	}
+
+	// The stream handler may still be running right after the client has received the end of the stream,
+	// so the status is collected once Stop has waited for it
+	require.NoError(t, srv.Stop(ctx))
+	got := pub.Status()
+	assert.Equal(t, "1", got["rpc.synternet.rpc.TestService.TestStream.requests"])
+	assert.Equal(t, "0", got["rpc.synternet.rpc.TestService.TestStream.errors"])
+	assert.Equal(t, "3", got["rpc.synternet.rpc.TestService.TestStream.stream.sent"])
+	assert.Equal(t, "0", got["rpc.synternet.rpc.TestService.TestStream.in_flight"])
+
+	assert.Equal(t, "3", got["rpc.synternet.rpc.TestService.Test.requests"])
+	assert.Equal(t, "0", got["rpc.synternet.rpc.TestService.Test.in_flight"])
+	assert.Equal(t, "1", got["rpc.synternet.rpc.TestService.Test.errors"])
+	assert.Equal(t, "1", got["rpc.synternet.rpc.TestService.Test.errors.InvalidArgument"])
+	assert.Contains(t, got, "rpc.synternet.rpc.TestService.Test.latency.p50")
+	assert.Contains(t, got, "rpc.synternet.rpc.TestService.Test.latency.p99")
+	assert.Equal(t, "0", got["rpc.synternet.rpc.TestService.TestVars.requests"])
+
+	// The counters are reset once collected
+	got = pub.Status()
+	assert.Equal(t, "0", got["rpc.synternet.rpc.TestService.Test.requests"])
+	assert.NotContains(t, got, "rpc.synternet.rpc.TestService.Test.latency.p50")
+
+	cancel()
+	require.NoError(t, grp.Wait())
+}
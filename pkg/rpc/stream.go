@@ -0,0 +1,178 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+	"github.com/synternet/data-layer-sdk/pkg/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Streaming session protocol.
+//
+// Client-streaming, server-streaming and bidirectional methods (except pure streams) run over a session:
+//
+//  1. The client subscribes to its inbox and sends an `open` frame to the method subject with reply set to the inbox.
+//  2. The server subscribes to a session inbox and replies with a `header` frame, whose reply is the session inbox.
+//  3. The client sends `msg` frames to the session inbox and half-closes the stream with a `close-send` frame.
+//     If the client gives up before the stream is finished, it sends a `cancel` frame with the status code
+//     (codes.Canceled or codes.DeadlineExceeded), and the server cancels the handler context with that status.
+//     The server cancels the handler context with codes.Unavailable if neither side sends anything for the idle timeout
+//     before the client half-closes, and with codes.ResourceExhausted if the handler falls behind the client by 1000 messages.
+//  4. The server sends `msg` frames to the client inbox and terminates the stream with either `eos` or `error` frame.
+//     The header metadata is sent with the first of those frames, or in a `metadata` frame if the server sends it explicitly.
+//
+// Every frame carries the stream id, a per-direction sequence number, and a frame type in the headers.
+// The `eos` and `error` frames also carry the status code, the status message, and trailers in the headers.
+const (
+	StreamIdHeader      = "rpc-stream-id"
+	SequenceHeader      = "rpc-seq"
+	FrameHeader         = "rpc-frame"
+	StatusHeader        = "rpc-status"
+	StatusMessageHeader = "rpc-status-message"
+	TrailerHeaderPrefix = "rpc-trailer-"
+)
+
+// DefaultStreamIdleTimeout is the default idle timeout of streaming sessions, see WithStreamIdleTimeout.
+const DefaultStreamIdleTimeout = 5 * time.Minute
+
+// streamRecvBuffer is the number of received frames buffered until the stream reads them.
+const streamRecvBuffer = 1000
+
+// WithStreamIdleTimeout sets how long a session may go without frames from either side while the client is sending messages.
+// Sessions whose clients went away without cancelling them are finished with codes.Unavailable once the timeout
+// passes, unless the server keeps sending. The timeout stops once the client half-closes the stream. Zero disables the timeout.
+func WithStreamIdleTimeout(timeout time.Duration) RegistrarOption {
+	return func(s *ServiceRegistrar) {
+		s.streamIdleTimeout = timeout
+	}
+}
+
+type frameType string
+
+const (
+	frameOpen      frameType = "open"
+	frameHeader    frameType = "header"
+	frameMsg       frameType = "msg"
+	frameCloseSend frameType = "close-send"
+	frameCancel    frameType = "cancel"
+	frameMetadata  frameType = "metadata"
+	frameEOS       frameType = "eos"
+	frameError     frameType = "error"
+)
+
+var (
+	ErrForeignFrame = errors.New("frame does not belong to the stream")
+	ErrSequenceGap  = errors.New("frame sequence gap")
+)
+
+func getFrameType(header nats.Header) frameType {
+	return frameType(header.Get(FrameHeader))
+}
+
+// statusHeader encodes the status and trailers into headers of a termination frame.
+func statusHeader(st *status.Status, trailer metadata.MD) nats.Header {
+	header := nats.Header{}
+	header.Set(StatusHeader, strconv.Itoa(int(st.Code())))
+	if st.Message() != "" {
+		header.Set(StatusMessageHeader, st.Message())
+	}
+	metadataToHeader(header, TrailerHeaderPrefix, trailer)
+	return header
+}
+
+// statusFromHeader decodes the status and trailers from headers of a termination frame.
+// Frames without a valid status header are reported with codes.Unknown status.
+func statusFromHeader(header nats.Header) (*status.Status, metadata.MD) {
+	trailer := metadataFromHeader(header, TrailerHeaderPrefix)
+
+	code, err := strconv.ParseUint(header.Get(StatusHeader), 10, 32)
+	if err != nil {
+		return status.New(codes.Unknown, header.Get(StatusMessageHeader)), trailer
+	}
+	return status.New(codes.Code(code), header.Get(StatusMessageHeader)), trailer
+}
+
+// streamSession keeps the state of a single end of a streaming session.
+type streamSession struct {
+	pub   Publisher
+	id    string
+	inbox string
+
+	mu      sync.Mutex
+	remote  string
+	sendSeq uint64
+	recvSeq atomic.Uint64
+}
+
+func newStreamSession(pub Publisher, id, remote string) *streamSession {
+	return &streamSession{
+		pub:    pub,
+		id:     id,
+		inbox:  pub.RpcInbox(),
+		remote: remote,
+	}
+}
+
+func newStreamId() string {
+	return nuid.Next()
+}
+
+// setRemote sets the subject where the frames will be sent to.
+func (s *streamSession) setRemote(remote string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remote = remote
+}
+
+// send publishes a frame to the remote end. Nil message is sent as an empty frame.
+func (s *streamSession) send(frame frameType, msg proto.Message) error {
+	return s.sendWithHeader(frame, msg, nil)
+}
+
+// sendWithHeader is the same as send, but also adds the header to the frame.
+func (s *streamSession) sendWithHeader(frame frameType, msg proto.Message, header nats.Header) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if msg == nil {
+		msg = &emptypb.Empty{}
+	}
+	if header == nil {
+		header = nats.Header{}
+	}
+	header.Set(StreamIdHeader, s.id)
+	header.Set(SequenceHeader, strconv.FormatUint(s.sendSeq, 10))
+	header.Set(FrameHeader, string(frame))
+	s.sendSeq++
+
+	return s.pub.PublishToRpcWithHeader(msg, header, s.inbox, s.remote)
+}
+
+// accept validates that the frame belongs to the session and arrives in order. It returns the frame type.
+func (s *streamSession) accept(msg service.Message) (frameType, error) {
+	header := msg.Header()
+	if header.Get(StreamIdHeader) != s.id {
+		return "", ErrForeignFrame
+	}
+	seq, err := strconv.ParseUint(header.Get(SequenceHeader), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("frame sequence: %w", err)
+	}
+	expected := s.recvSeq.Load()
+	if seq != expected {
+		return "", fmt.Errorf("%w: expected %d, got %d", ErrSequenceGap, expected, seq)
+	}
+	s.recvSeq.Add(1)
+	return getFrameType(header), nil
+}
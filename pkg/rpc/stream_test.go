@@ -0,0 +1,38 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func Test_statusHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  *status.Status
+		trailer metadata.MD
+	}{
+		{"ok", status.New(codes.OK, ""), metadata.MD{}},
+		{"error", status.New(codes.InvalidArgument, "bad argument"), metadata.MD{}},
+		{"trailer", status.New(codes.NotFound, "missing"), metadata.Pairs("count", "1", "count", "2", "key", "value")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, trailer := statusFromHeader(statusHeader(tt.status, tt.trailer))
+			assert.Equal(t, tt.status.Code(), st.Code())
+			assert.Equal(t, tt.status.Message(), st.Message())
+			assert.Equal(t, tt.trailer, trailer)
+		})
+	}
+}
+
+func Test_statusFromHeaderMissing(t *testing.T) {
+	st, trailer := statusFromHeader(nats.Header{StatusMessageHeader: {"failed"}})
+	assert.Equal(t, codes.Unknown, st.Code())
+	assert.Equal(t, "failed", st.Message())
+	assert.Empty(t, trailer)
+}
@@ -16,15 +16,30 @@ import (
 // Publisher interface from your NATS wrapper
 type Publisher interface {
 	Serve(handler service.ServiceHandler, suffixes ...string) (*nats.Subscription, error)
+	ServeWithHeader(handler service.ServiceHeaderHandler, suffixes ...string) (*nats.Subscription, error)
+	QueueServeWithHeader(handler service.ServiceHeaderHandler, queue string, suffixes ...string) (*nats.Subscription, error)
 	RequestFrom(ctx context.Context, msg proto.Message, resp proto.Message, tokens ...string) (service.Message, error)
+	RequestFromWithHeader(ctx context.Context, msg proto.Message, resp proto.Message, header nats.Header, tokens ...string) (service.Message, error)
+	RequestMany(ctx context.Context, msg proto.Message, opts service.RequestManyOptions, tokens ...string) (<-chan service.Response, error)
 	SubscribeTo(handler service.MessageHandler, tokens ...string) (*nats.Subscription, error)
+	QueueSubscribeTo(handler service.MessageHandler, queue string, tokens ...string) (*nats.Subscription, error)
 	PublishTo(msg proto.Message, tokens ...string) error
 	PublishToRpc(msg proto.Message, replyTo string, tokens ...string) error
+	PublishToRpcWithHeader(msg proto.Message, header nats.Header, replyTo string, tokens ...string) error
 	RpcInbox(suffixes ...string) string
 	Unmarshal(nmsg service.Message, msg protoreflect.ProtoMessage) (nats.Header, error)
 	Subject(suffixes ...string) string
 }
 
+var _ StatusPublisher = (*service.Service)(nil)
+
+// StatusPublisher is a Publisher that reports the status in telemetry, such as service.Service.
+// ServiceRegistrar registers its status callback with such publishers, see NewServiceRegistrar.
+type StatusPublisher interface {
+	Publisher
+	AddStatusCallback(callback service.StatusFunc)
+}
+
 // normalizePascalCase normalizes a Pascal Case string by converting a leading run
 // of uppercase letters to proper Pascal Case. For example:
 //
@@ -157,10 +172,55 @@ func disableSubscription(methodDescriptor protoreflect.Descriptor) bool {
 //     If set, use that; otherwise, derive tokens from the method name.
 //
 // Custom subjects can be parametrized like so: `organization.name.service.param.{id}`. If you pass map[string]string{"id": `123456`}
-// the subject will be transformed into `organization.name.service.param.123456`.
+// the subject will be transformed into `organization.name.service.param.123456`. Variables may also be a part of a token,
+// e.g. `block-{id}`. The values are validated against the variable schemas declared in the service and method options.
+//
+// Missing variables are reported with VariablesError, unless wildcard is set, in which case tokens with missing
+// variables are replaced with `*`.
 //
 // This is especially handy for parametrized services or streams where client is only authorized to access certain ids.
-func deriveSubject(prefix string, serviceDescriptor, methodDescriptor protoreflect.Descriptor, vars map[string]string) []string {
+func deriveSubject(prefix string, serviceDescriptor, methodDescriptor protoreflect.Descriptor, vars map[string]string, wildcard bool) ([]string, error) {
+	template, err := parseSubjectTemplates(subjectTokens(prefix, serviceDescriptor, methodDescriptor)...)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := compileVariableSchemas(variableSchemas(serviceDescriptor, methodDescriptor))
+	if err != nil {
+		return nil, err
+	}
+	return template.expand(vars, schemas, wildcard)
+}
+
+// SubjectTemplate returns the subject of the method relative to the publisher prefix, with the variables left
+// as `{name}` tokens, e.g. `service.pkg.user.get.{id}`. It is used by code generators to derive the same subjects
+// as ClientConn and ServiceRegistrar do at runtime.
+func SubjectTemplate(serviceDescriptor protoreflect.ServiceDescriptor, methodDescriptor protoreflect.MethodDescriptor) string {
+	var tokens []string
+	for _, token := range subjectTokens("", serviceDescriptor, methodDescriptor) {
+		tokens = append(tokens, strings.Split(token, ".")...)
+	}
+	tokens = slices.DeleteFunc(tokens, func(s string) bool { return s == "" })
+	return strings.Join(tokens, ".")
+}
+
+// JoinSubject joins the tokens into a subject skipping empty tokens. It is used by generated code to build subjects
+// from the publisher prefix, the templates and the variables.
+func JoinSubject(tokens ...string) string {
+	return strings.Join(slices.DeleteFunc(slices.Clone(tokens), func(s string) bool { return s == "" }), ".")
+}
+
+// JoinToken concatenates the literals and the variable values of a token such as `block-{id}`. It is used by generated
+// code the same way as JoinSubject. The whole token becomes the `*` wildcard if any part is `*`, since wildcards
+// cannot be part of a token; literals never contain wildcards.
+func JoinToken(parts ...string) string {
+	if slices.Contains(parts, "*") {
+		return "*"
+	}
+	return strings.Join(parts, "")
+}
+
+// subjectTokens returns the prefix, the service and the method parts of the subject. The parts may contain dots.
+func subjectTokens(prefix string, serviceDescriptor, methodDescriptor protoreflect.Descriptor) []string {
 	var tokens []string
 
 	// Use the explicit prefix if provided.
@@ -179,34 +239,5 @@ func deriveSubject(prefix string, serviceDescriptor, methodDescriptor protorefle
 	} else {
 		tokens = append(tokens, splitPascalCase(string(methodDescriptor.Name()))...)
 	}
-	tokens = splitAndParametrizeTokens(tokens, vars)
-
-	return slices.DeleteFunc(tokens, func(s string) bool { return s == "" })
-}
-
-func splitAndParametrizeTokens(tokens []string, vars map[string]string) []string {
-	result := make([]string, 0, len(tokens))
-
-	for _, token := range tokens {
-		result = append(result, strings.Split(token, ".")...)
-	}
-
-	for i, token := range result {
-		token = strings.TrimSpace(token)
-		if strings.HasPrefix(token, "{") || strings.HasSuffix(token, "}") {
-			st := strings.Trim(token, "{}")
-			if vars != nil {
-				if v, ok := vars[st]; ok {
-					token = strings.TrimSpace(v)
-				} else {
-					token = "*"
-				}
-			} else {
-				token = "*"
-			}
-		}
-		result[i] = token
-	}
-
-	return result
+	return tokens
 }
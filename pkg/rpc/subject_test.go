@@ -1,7 +1,6 @@
 package rpc
 
 import (
-	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -111,42 +110,36 @@ func Test_getCustomMethodSuffix(t *testing.T) {
 	assert.Equal(t, got, "override.test.stream.data")
 }
 
-func Test_splitAndParametrizeTokens(t *testing.T) {
-	type args struct {
-		tokens []string
-		vars   map[string]string
-	}
+func TestSubjectTemplate(t *testing.T) {
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName("synternet.rpc.TestService"))
+	require.NoError(t, err)
+	testService := desc.(protoreflect.ServiceDescriptor)
+	desc, err = protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName("synternet.rpc.Reflection"))
+	require.NoError(t, err)
+	reflection := desc.(protoreflect.ServiceDescriptor)
+
 	tests := []struct {
-		name string
-		args args
-		want []string
+		name    string
+		service protoreflect.ServiceDescriptor
+		method  protoreflect.Name
+		want    string
 	}{
-		{
-			name: "simple",
-			args: args{tokens: []string{"a", "b.c"}, vars: nil},
-			want: []string{"a", "b", "c"},
-		},
-		{
-			name: "no vars",
-			args: args{tokens: []string{"a", "b.{c}"}, vars: nil},
-			want: []string{"a", "b", "*"},
-		},
-		{
-			name: "vars",
-			args: args{tokens: []string{"a", "b.{c}"}, vars: map[string]string{"c": "123"}},
-			want: []string{"a", "b", "123"},
-		},
-		{
-			name: "split vars",
-			args: args{tokens: []string{"a", "b.{c.d}"}, vars: map[string]string{"c": "123"}},
-			want: []string{"a", "b", "123", "*"},
-		},
+		{"custom subject", testService, "Test", "override.test.override.test.method"},
+		{"variable", testService, "TestVars", "override.test.override.test.method.{variable}"},
+		{"custom suffix", reflection, "ListServices", "service.reflection"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := splitAndParametrizeTokens(tt.args.tokens, tt.args.vars); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("splitAndParametrizeTokens() = %v, want %v", got, tt.want)
-			}
+			methodDesc := tt.service.Methods().ByName(tt.method)
+			require.NotNil(t, methodDesc)
+			assert.Equal(t, tt.want, SubjectTemplate(tt.service, methodDesc))
 		})
 	}
 }
+
+func TestJoinToken(t *testing.T) {
+	assert.Equal(t, "block-1", JoinToken("block-", "1"))
+	assert.Equal(t, "a-b", JoinToken("a", "-", "b"))
+	assert.Equal(t, "*", JoinToken("block-", "*"))
+	assert.Equal(t, "*", JoinToken("*", "-", "b"))
+}
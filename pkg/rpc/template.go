@@ -0,0 +1,379 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var (
+	variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	hexValue     = regexp.MustCompile(`^(0x|0X)?[0-9a-fA-F]+$`)
+)
+
+// TemplatePart is a part of a subject template token: either a literal or a variable.
+// For example, token `block-{id}` consists of literal `block-` and variable `id`.
+type TemplatePart struct {
+	Literal  string
+	Variable string
+}
+
+// subjectTemplate is a parsed subject template, a list of tokens made of parts.
+type subjectTemplate [][]TemplatePart
+
+// ParseSubjectTemplate parses a subject template such as `blocks.block-{id}.{chain}` into tokens.
+// Tokens must not be empty, and must not contain whitespace or wildcards. Variables are written as `{name}`,
+// where the name consists of letters, digits and underscores, and must not start with a digit.
+func ParseSubjectTemplate(template string) ([][]TemplatePart, error) {
+	var ret [][]TemplatePart
+	for _, token := range strings.Split(template, ".") {
+		parts, err := parseTemplateToken(token)
+		if err != nil {
+			return nil, fmt.Errorf("subject template %q: %w", template, err)
+		}
+		ret = append(ret, parts)
+	}
+	return ret, nil
+}
+
+func parseTemplateToken(token string) ([]TemplatePart, error) {
+	if token == "" {
+		return nil, fmt.Errorf("empty token")
+	}
+	if strings.ContainsAny(token, "*>") {
+		return nil, fmt.Errorf("token %q contains wildcards", token)
+	}
+	if strings.ContainsFunc(token, func(r rune) bool { return unicode.IsSpace(r) || !unicode.IsPrint(r) }) {
+		return nil, fmt.Errorf("token %q contains whitespace", token)
+	}
+
+	var parts []TemplatePart
+	for rest := token; rest != ""; {
+		open := strings.IndexAny(rest, "{}")
+		switch {
+		case open < 0:
+			parts = append(parts, TemplatePart{Literal: rest})
+			rest = ""
+			continue
+		case rest[open] == '}':
+			return nil, fmt.Errorf("token %q has unmatched '}'", token)
+		case open > 0:
+			parts = append(parts, TemplatePart{Literal: rest[:open]})
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] != '}' {
+			return nil, fmt.Errorf("token %q has unmatched '{'", token)
+		}
+		name := rest[open+1 : open+1+end]
+		if !variableName.MatchString(name) {
+			return nil, fmt.Errorf("token %q has invalid variable name %q", token, name)
+		}
+		parts = append(parts, TemplatePart{Variable: name})
+		rest = rest[open+1+end+1:]
+	}
+	return parts, nil
+}
+
+// parseSubjectTemplates parses the templates and concatenates their tokens. Empty templates are skipped.
+func parseSubjectTemplates(templates ...string) (subjectTemplate, error) {
+	var ret subjectTemplate
+	for _, template := range templates {
+		if template == "" {
+			continue
+		}
+		tokens, err := ParseSubjectTemplate(template)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, tokens...)
+	}
+	return ret, nil
+}
+
+// variables returns the names of the variables in the order of appearance.
+func (t subjectTemplate) variables() []string {
+	var ret []string
+	for _, token := range t {
+		for _, part := range token {
+			if part.Variable != "" && !slices.Contains(ret, part.Variable) {
+				ret = append(ret, part.Variable)
+			}
+		}
+	}
+	return ret
+}
+
+// expand substitutes the variables and returns the subject tokens. The values are validated against the schemas.
+// If wildcard is set, tokens with missing variables, or variables set to `*`, are replaced with `*` wildcard.
+// Otherwise missing variables are reported with VariablesError.
+func (t subjectTemplate) expand(vars map[string]string, schemas map[string]*variableSchema, wildcard bool) ([]string, error) {
+	var missing []string
+	for _, name := range t.variables() {
+		value, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		if value == "*" && wildcard {
+			continue
+		}
+		if err := validateVariable(name, value, schemas[name]); err != nil {
+			return nil, err
+		}
+	}
+	if len(missing) != 0 && !wildcard {
+		return nil, &VariablesError{Missing: missing}
+	}
+
+	ret := make([]string, 0, len(t))
+	for _, token := range t {
+		var b strings.Builder
+		for _, part := range token {
+			if part.Variable == "" {
+				b.WriteString(part.Literal)
+				continue
+			}
+			value, ok := vars[part.Variable]
+			if !ok || value == "*" {
+				b.Reset()
+				b.WriteString("*")
+				break
+			}
+			b.WriteString(value)
+		}
+		ret = append(ret, b.String())
+	}
+	return ret, nil
+}
+
+// validateVariable checks that the value is a single subject token matching the schema, if any.
+func validateVariable(name, value string, schema *variableSchema) error {
+	switch {
+	case value == "":
+		return fmt.Errorf("variable %s is empty", name)
+	case strings.ContainsAny(value, ".*>") || strings.ContainsFunc(value, unicode.IsSpace):
+		return fmt.Errorf("variable %s: value %q is not a single subject token", name, value)
+	case schema == nil:
+		return nil
+	}
+
+	var err error
+	switch schema.GetType() {
+	case rpc.VariableType_VARIABLE_TYPE_INT:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case rpc.VariableType_VARIABLE_TYPE_UINT:
+		_, err = strconv.ParseUint(value, 10, 64)
+	case rpc.VariableType_VARIABLE_TYPE_HEX:
+		if !hexValue.MatchString(value) {
+			err = fmt.Errorf("not a hex string")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("variable %s: value %q is not %s: %w", name, value, schema.GetType(), err)
+	}
+	if schema.pattern != nil && !schema.pattern.MatchString(value) {
+		return fmt.Errorf("variable %s: value %q does not match %q", name, value, schema.GetPattern())
+	}
+	return nil
+}
+
+// variableSchema is the schema of a variable with its pattern compiled.
+type variableSchema struct {
+	*rpc.SubjectVariable
+	// pattern matches the whole value; nil if the schema has no pattern
+	pattern *regexp.Regexp
+}
+
+// compileVariableSchemas compiles the patterns of the schemas, so that the values are validated without compiling them again.
+func compileVariableSchemas(schemas map[string]*rpc.SubjectVariable) (map[string]*variableSchema, error) {
+	ret := make(map[string]*variableSchema, len(schemas))
+	for name, schema := range schemas {
+		ret[name] = &variableSchema{SubjectVariable: schema}
+		if pattern := schema.GetPattern(); pattern != "" {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("variable %s: pattern %q: %w", name, pattern, err)
+			}
+			ret[name].pattern = re
+		}
+	}
+	return ret, nil
+}
+
+// variableSchemas returns the schemas of the variables declared on the service and the method.
+// Method schemas override the service schemas.
+func variableSchemas(serviceDescriptor, methodDescriptor protoreflect.Descriptor) map[string]*rpc.SubjectVariable {
+	ret := make(map[string]*rpc.SubjectVariable)
+	if serviceDescriptor != nil && serviceDescriptor.Options() != nil {
+		for _, v := range proto.GetExtension(serviceDescriptor.Options(), rpc.E_SubjectVariables).([]*rpc.SubjectVariable) {
+			ret[v.GetName()] = v
+		}
+	}
+	if methodDescriptor != nil && methodDescriptor.Options() != nil {
+		for _, v := range proto.GetExtension(methodDescriptor.Options(), rpc.E_MethodSubjectVariables).([]*rpc.SubjectVariable) {
+			ret[v.GetName()] = v
+		}
+	}
+	return ret
+}
+
+// VariablesError reports subject variables that are missing, or that are passed but not used by any subject.
+type VariablesError struct {
+	Missing []string
+	Unused  []string
+}
+
+func (e *VariablesError) Error() string {
+	var msgs []string
+	if len(e.Missing) != 0 {
+		msgs = append(msgs, "missing variables: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unused) != 0 {
+		msgs = append(msgs, "unused variables: "+strings.Join(e.Unused, ", "))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// WithStrictSubjects makes Start fail if subject variables are missing, instead of subscribing to `*` in their place,
+// or if variables are passed but not used by any subject of the registered services.
+func WithStrictSubjects() RegistrarOption {
+	return func(s *ServiceRegistrar) {
+		s.strictSubjects = true
+	}
+}
+
+// subject derives the subject tokens of the method. Missing variables are replaced with `*` wildcard.
+func (s *ServiceRegistrar) subject(serviceDescriptor, methodDescriptor protoreflect.Descriptor, vars map[string]string) ([]string, error) {
+	tokens, err := deriveSubject(s.prefix, serviceDescriptor, methodDescriptor, vars, false)
+	var varsErr *VariablesError
+	if errors.As(err, &varsErr) {
+		slog.Warn("ServiceRegistrar: missing subject variables are replaced with wildcards", "method", methodDescriptor.FullName(), "missing", varsErr.Missing)
+		tokens, err = deriveSubject(s.prefix, serviceDescriptor, methodDescriptor, vars, true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", methodDescriptor.FullName(), err)
+	}
+	return tokens, nil
+}
+
+// checkSubjects derives the subjects of all registered methods, and reports the missing and unused variables.
+func (s *ServiceRegistrar) checkSubjects(vars map[string]string) error {
+	var errs []error
+	used := make(map[string]bool)
+	for name, svc := range s.services {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("service desc %s: %w", name, err))
+			continue
+		}
+		svcDesc := desc.(protoreflect.ServiceDescriptor)
+		svcVars := extractServiceVars(name, vars)
+
+		var methods []string
+		for _, m := range svc.methods {
+			methods = append(methods, m.MethodName)
+		}
+		for _, st := range svc.streams {
+			methods = append(methods, st.StreamName)
+		}
+		slices.Sort(methods)
+		for _, method := range methods {
+			methodDesc := svcDesc.Methods().ByName(protoreflect.Name(method))
+			if methodDesc == nil {
+				errs = append(errs, fmt.Errorf("service desc nil: %s@%s", method, name))
+				continue
+			}
+			if _, err := deriveSubject(s.prefix, svcDesc, methodDesc, svcVars, false); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", methodDesc.FullName(), err))
+				continue
+			}
+			template, _ := parseSubjectTemplates(subjectTokens(s.prefix, svcDesc, methodDesc)...)
+			for _, v := range template.variables() {
+				// Scoped variables override the global ones
+				if _, ok := vars[name+"/"+v]; ok {
+					used[name+"/"+v] = true
+				} else {
+					used[v] = true
+				}
+			}
+		}
+	}
+
+	var unused []string
+	for k := range vars {
+		if !used[k] {
+			unused = append(unused, k)
+		}
+	}
+	if len(unused) != 0 {
+		slices.Sort(unused)
+		errs = append(errs, &VariablesError{Unused: unused})
+	}
+	return errors.Join(errs...)
+}
+
+// subjectMatcher extracts the values of the variables from the subjects matching a template.
+type subjectMatcher struct {
+	re      *regexp.Regexp
+	names   []string
+	schemas map[string]*variableSchema
+}
+
+// newSubjectMatcher returns a matcher of the subjects of the method. The subjects may have any prefix before the template.
+func newSubjectMatcher(prefix string, serviceDescriptor, methodDescriptor protoreflect.Descriptor) (*subjectMatcher, error) {
+	template, err := parseSubjectTemplates(subjectTokens(prefix, serviceDescriptor, methodDescriptor)...)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := compileVariableSchemas(variableSchemas(serviceDescriptor, methodDescriptor))
+	if err != nil {
+		return nil, err
+	}
+	ret := &subjectMatcher{schemas: schemas}
+	tokens := make([]string, len(template))
+	for i, token := range template {
+		for _, part := range token {
+			if part.Variable == "" {
+				tokens[i] += regexp.QuoteMeta(part.Literal)
+				continue
+			}
+			tokens[i] += `([^.]+)`
+			ret.names = append(ret.names, part.Variable)
+		}
+	}
+	ret.re, err = regexp.Compile(`(?:^|\.)` + strings.Join(tokens, `\.`) + `$`)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// match returns the values of the variables in the subject. The values are validated against the schemas.
+func (m *subjectMatcher) match(subject string) (map[string]string, error) {
+	values := m.re.FindStringSubmatch(subject)
+	if values == nil {
+		return nil, fmt.Errorf("subject %q does not match %s", subject, m.re)
+	}
+	ret := make(map[string]string, len(m.names))
+	for i, name := range m.names {
+		value := values[i+1]
+		if other, ok := ret[name]; ok && other != value {
+			return nil, fmt.Errorf("variable %s has different values %q and %q", name, other, value)
+		}
+		if err := validateVariable(name, value, m.schemas[name]); err != nil {
+			return nil, err
+		}
+		ret[name] = value
+	}
+	return ret, nil
+}
@@ -0,0 +1,148 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+func TestParseSubjectTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     [][]TemplatePart
+		wantErr  string
+	}{
+		{"literals", "a.b", [][]TemplatePart{{{Literal: "a"}}, {{Literal: "b"}}}, ""},
+		{"variable", "a.{id}", [][]TemplatePart{{{Literal: "a"}}, {{Variable: "id"}}}, ""},
+		{"partial token", "block-{id}-{n}.x", [][]TemplatePart{{{Literal: "block-"}, {Variable: "id"}, {Literal: "-"}, {Variable: "n"}}, {{Literal: "x"}}}, ""},
+		{"empty token", "a..b", nil, "empty token"},
+		{"wildcard", "a.*", nil, "contains wildcards"},
+		{"full wildcard", "a.>", nil, "contains wildcards"},
+		{"whitespace", "a.b c", nil, "contains whitespace"},
+		{"unmatched open", "a.{id", nil, "unmatched '{'"},
+		{"nested", "a.{{id}}", nil, "unmatched '{'"},
+		{"unmatched close", "a.id}", nil, "unmatched '}'"},
+		{"invalid name", "a.{1d}", nil, `invalid variable name "1d"`},
+		{"empty name", "a.{}", nil, `invalid variable name ""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSubjectTemplate(tt.template)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_subjectTemplate_expand(t *testing.T) {
+	schemas, err := compileVariableSchemas(map[string]*rpc.SubjectVariable{
+		"n":   {Name: "n", Type: rpc.VariableType_VARIABLE_TYPE_UINT},
+		"i":   {Name: "i", Type: rpc.VariableType_VARIABLE_TYPE_INT},
+		"h":   {Name: "h", Type: rpc.VariableType_VARIABLE_TYPE_HEX},
+		"net": {Name: "net", Pattern: "main|test"},
+	})
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		template string
+		vars     map[string]string
+		wildcard bool
+		want     []string
+		wantErr  string
+	}{
+		{"vars", "a.b.{c}", map[string]string{"c": "123"}, false, []string{"a", "b", "123"}, ""},
+		{"partial token", "a.block-{n}", map[string]string{"n": "5"}, false, []string{"a", "block-5"}, ""},
+		{"missing", "a.{c}.{d}", nil, false, nil, "missing variables: c, d"},
+		{"missing wildcard", "a.block-{c}.{d}", map[string]string{"d": "x"}, true, []string{"a", "*", "x"}, ""},
+		{"explicit wildcard", "a.{c}", map[string]string{"c": "*"}, true, []string{"a", "*"}, ""},
+		{"wildcard not allowed", "a.{c}", map[string]string{"c": "*"}, false, nil, "not a single subject token"},
+		{"multiple tokens", "a.{c}", map[string]string{"c": "x.y"}, false, nil, "not a single subject token"},
+		{"empty value", "a.{c}", map[string]string{"c": ""}, false, nil, "variable c is empty"},
+		{"uint", "{n}", map[string]string{"n": "abc"}, false, nil, `variable n: value "abc" is not VARIABLE_TYPE_UINT`},
+		{"negative uint", "{n}", map[string]string{"n": "-1"}, false, nil, "is not VARIABLE_TYPE_UINT"},
+		{"int", "{i}", map[string]string{"i": "-1"}, false, []string{"-1"}, ""},
+		{"hex", "{h}", map[string]string{"h": "0xdeadBEEF"}, false, []string{"0xdeadBEEF"}, ""},
+		{"invalid hex", "{h}", map[string]string{"h": "0xzz"}, false, nil, "is not VARIABLE_TYPE_HEX"},
+		{"pattern", "{net}", map[string]string{"net": "main"}, false, []string{"main"}, ""},
+		{"pattern mismatch", "{net}", map[string]string{"net": "mainnet"}, false, nil, `does not match "main|test"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := parseSubjectTemplates(tt.template)
+			require.NoError(t, err)
+			got, err := template.expand(tt.vars, schemas, tt.wildcard)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_compileVariableSchemas(t *testing.T) {
+	schemas, err := compileVariableSchemas(map[string]*rpc.SubjectVariable{
+		"n":   {Name: "n", Type: rpc.VariableType_VARIABLE_TYPE_UINT},
+		"net": {Name: "net", Pattern: "main|test"},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, schemas["n"].pattern)
+	require.NotNil(t, schemas["net"].pattern)
+	assert.True(t, schemas["net"].pattern.MatchString("test"))
+	assert.False(t, schemas["net"].pattern.MatchString("testnet"))
+
+	_, err = compileVariableSchemas(map[string]*rpc.SubjectVariable{"net": {Name: "net", Pattern: "main|(test"}})
+	assert.ErrorContains(t, err, `variable net: pattern "main|(test"`)
+}
+
+func TestVariablesError(t *testing.T) {
+	var err error = &VariablesError{Missing: []string{"a", "b"}, Unused: []string{"c"}}
+	assert.EqualError(t, err, "missing variables: a, b; unused variables: c")
+
+	var varsErr *VariablesError
+	assert.True(t, errors.As(errors.Join(errors.New("other"), err), &varsErr))
+}
+
+func Test_subjectMatcher(t *testing.T) {
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName("synternet.rpc.TestService")
+	require.NoError(t, err)
+	svcDesc := desc.(protoreflect.ServiceDescriptor)
+	matcher, err := newSubjectMatcher("", svcDesc, svcDesc.Methods().ByName("TestVars"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		subject string
+		want    map[string]string
+		wantErr string
+	}{
+		{"match", "test_prefix.override.test.override.test.method.42", map[string]string{"variable": "42"}, ""},
+		{"no prefix", "override.test.override.test.method.42", map[string]string{"variable": "42"}, ""},
+		{"invalid value", "test_prefix.override.test.override.test.method.abc", nil, `value "abc" is not VARIABLE_TYPE_UINT`},
+		{"no match", "test_prefix.override.test.override.test.other.42", nil, "does not match"},
+		{"token boundary", "test_prefix.xoverride.test.override.test.method.42", nil, "does not match"},
+		{"extra token", "test_prefix.override.test.override.test.method.42.43", nil, "does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matcher.match(tt.subject)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+
+	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// VarsCallOption is a grpc.CallOption that sets the subject variables of a single call. See WithVars.
+type VarsCallOption struct {
+	grpc.EmptyCallOption
+	Vars map[string]string
+}
+
+// WithVars returns a call option that sets the subject variables of a single call, so that a single connection
+// can address any parametrized subject, e.g. `client.Get(ctx, req, rpc.WithVars(map[string]string{"id": id}))`.
+//
+// The variables override the variables of the connection and the variables bound to the request fields.
+func WithVars(vars map[string]string) grpc.CallOption {
+	return VarsCallOption{Vars: vars}
+}
+
+// callVars returns the subject variables of a call. The connection variables are overridden by the variables bound
+// to the request fields, which are overridden by WithVars options. The request is nil for streams.
+func (c *ClientConn) callVars(req proto.Message, opts []grpc.CallOption) (map[string]string, error) {
+	vars := c.vars
+	copied := false
+	override := func(m map[string]string) {
+		if len(m) == 0 {
+			return
+		}
+		if !copied {
+			vars = maps.Clone(vars)
+			if vars == nil {
+				vars = make(map[string]string, len(m))
+			}
+			copied = true
+		}
+		maps.Copy(vars, m)
+	}
+
+	if req != nil {
+		bound, err := requestVars(req)
+		if err != nil {
+			return nil, err
+		}
+		override(bound)
+	}
+	for _, opt := range opts {
+		if o, ok := opt.(VarsCallOption); ok {
+			override(o.Vars)
+		}
+	}
+	return vars, nil
+}
+
+// requestVars returns the variables bound to the set fields of the request with the bind_variable option.
+func requestVars(req proto.Message) (map[string]string, error) {
+	var ret map[string]string
+	msg := req.ProtoReflect()
+	fields := msg.Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		name, err := BoundVariable(fd)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" || !msg.Has(fd) {
+			continue
+		}
+		if ret == nil {
+			ret = make(map[string]string)
+		}
+		ret[name] = formatVariable(msg.Get(fd))
+	}
+	return ret, nil
+}
+
+// BoundVariable returns the name of the subject variable bound to the field with the bind_variable option, if any.
+// Only singular string and integer fields can be bound.
+func BoundVariable(fd protoreflect.FieldDescriptor) (string, error) {
+	if fd.Options() == nil {
+		return "", nil
+	}
+	name := proto.GetExtension(fd.Options(), rpc.E_BindVariable).(string)
+	if name == "" {
+		return "", nil
+	}
+	if fd.IsList() || fd.IsMap() {
+		return "", fmt.Errorf("field %s: cannot bind repeated field to variable %s", fd.FullName(), name)
+	}
+	switch fd.Kind() {
+	case protoreflect.StringKind,
+		protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return name, nil
+	}
+	return "", fmt.Errorf("field %s: cannot bind %s field to variable %s", fd.FullName(), fd.Kind(), name)
+}
+
+func formatVariable(v protoreflect.Value) string {
+	switch v := v.Interface().(type) {
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	default:
+		return v.(string)
+	}
+}
+
+// VarsContextKey is the context key of the subject variables of a request. See Vars.
+const VarsContextKey serviceKey = "vars"
+
+// Vars returns the subject variables of the request handled by the server, e.g. the value that matched `{id}`
+// when the method is served on `*` in place of the variable. It returns nil outside of unary and streaming session handlers.
+// The returned map must not be modified.
+func Vars(ctx context.Context) map[string]string {
+	vars, _ := ctx.Value(VarsContextKey).(map[string]string)
+	return vars
+}
+
+// addVars returns a new context with the subject variables added.
+func addVars(ctx context.Context, vars map[string]string) context.Context {
+	return context.WithValue(ctx, VarsContextKey, vars)
+}
+
+// bindRequest sets the request fields bound to the subject variables with the bind_variable option.
+// The request is rejected with codes.InvalidArgument if a set field does not match the variable.
+func bindRequest(req proto.Message, vars map[string]string) error {
+	msg := req.ProtoReflect()
+	fields := msg.Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		name, err := BoundVariable(fd)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		value, ok := vars[name]
+		if name == "" || !ok {
+			continue
+		}
+		v, err := parseVariable(fd, value)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "field %s: variable %s: %v", fd.FullName(), name, err)
+		}
+		if msg.Has(fd) && !msg.Get(fd).Equal(v) {
+			return status.Errorf(codes.InvalidArgument, "field %s = %v does not match subject variable %s = %s", fd.FullName(), msg.Get(fd), name, value)
+		}
+		msg.Set(fd, v)
+	}
+	return nil
+}
+
+// parseVariable parses the value of a variable into a value of the bound field.
+func parseVariable(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	default:
+		return protoreflect.ValueOfString(value), nil
+	}
+}
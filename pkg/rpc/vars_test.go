@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// boundMessage returns a message with a single field of the kind bound to variable `id`.
+func boundMessage(t *testing.T, kind descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label) *dynamicpb.Message {
+	opts := &descriptorpb.FieldOptions{}
+	proto.SetExtension(opts, rpc.E_BindVariable, "id")
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("bound.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Bound"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:           proto.String("id"),
+				Number:         proto.Int32(1),
+				Type:           kind.Enum(),
+				Label:          label.Enum(),
+				JsonName:       proto.String("id"),
+				Options:        opts,
+				Proto3Optional: proto.Bool(label == descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+				OneofIndex:     proto.Int32(0),
+			}},
+			OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("_id")}},
+		}},
+	}, nil)
+	require.NoError(t, err)
+	return dynamicpb.NewMessage(fd.Messages().Get(0))
+}
+
+func Test_requestVars(t *testing.T) {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	tests := []struct {
+		name    string
+		kind    descriptorpb.FieldDescriptorProto_Type
+		value   any
+		want    map[string]string
+		wantErr string
+	}{
+		{"unset", descriptorpb.FieldDescriptorProto_TYPE_STRING, nil, nil, ""},
+		{"string", descriptorpb.FieldDescriptorProto_TYPE_STRING, "abc", map[string]string{"id": "abc"}, ""},
+		{"int32", descriptorpb.FieldDescriptorProto_TYPE_SINT32, int32(-5), map[string]string{"id": "-5"}, ""},
+		{"uint64", descriptorpb.FieldDescriptorProto_TYPE_FIXED64, uint64(18446744073709551615), map[string]string{"id": "18446744073709551615"}, ""},
+		{"zero", descriptorpb.FieldDescriptorProto_TYPE_UINT64, uint64(0), map[string]string{"id": "0"}, ""},
+		{"float", descriptorpb.FieldDescriptorProto_TYPE_FLOAT, nil, nil, "field test.Bound.id: cannot bind float field to variable id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := boundMessage(t, tt.kind, optional)
+			if tt.value != nil {
+				msg.Set(msg.Descriptor().Fields().Get(0), protoreflect.ValueOf(tt.value))
+			}
+			got, err := requestVars(msg)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClientConn_callVars(t *testing.T) {
+	conn := &ClientConn{vars: map[string]string{"variable": "1", "other": "x"}}
+
+	got, err := conn.callVars(&rpc.TestRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"variable": "1", "other": "x"}, got)
+
+	got, err = conn.callVars(&rpc.TestRequest{Variable: proto.Uint64(2)}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"variable": "2", "other": "x"}, got)
+
+	got, err = conn.callVars(&rpc.TestRequest{Variable: proto.Uint64(2)}, []grpc.CallOption{WithVars(map[string]string{"variable": "3"}), grpc.WaitForReady(true)})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"variable": "3", "other": "x"}, got)
+
+	// The connection variables are not modified
+	assert.Equal(t, map[string]string{"variable": "1", "other": "x"}, conn.vars)
+}
+
+func Test_bindRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *rpc.TestRequest
+		vars     map[string]string
+		want     *rpc.TestRequest
+		wantCode codes.Code
+	}{
+		{"unset", &rpc.TestRequest{A: 1}, map[string]string{"variable": "42"}, &rpc.TestRequest{A: 1, Variable: proto.Uint64(42)}, codes.OK},
+		{"same", &rpc.TestRequest{Variable: proto.Uint64(42)}, map[string]string{"variable": "42"}, &rpc.TestRequest{Variable: proto.Uint64(42)}, codes.OK},
+		{"no variable", &rpc.TestRequest{A: 1}, map[string]string{"other": "x"}, &rpc.TestRequest{A: 1}, codes.OK},
+		{"mismatch", &rpc.TestRequest{Variable: proto.Uint64(7)}, map[string]string{"variable": "42"}, nil, codes.InvalidArgument},
+		{"invalid value", &rpc.TestRequest{}, map[string]string{"variable": "-1"}, nil, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := bindRequest(tt.req, tt.vars)
+			require.Equal(t, tt.wantCode, status.Code(err), err)
+			if tt.want != nil {
+				assert.True(t, proto.Equal(tt.want, tt.req), tt.req)
+			}
+		})
+	}
+}
@@ -59,7 +59,7 @@ func (b *Service) AddStream(maxMsgs, maxBytes uint64, age time.Duration, subject
 			return fmt.Errorf("%s validation failed: %w", s, err)
 		}
 
-		if match, _, found := b.streamSubjects.SymmetricSearch(s); found {
+		if match, found := b.streams.overlaps(subject); found {
 			return fmt.Errorf("%s already configured as %s", s, match)
 		}
 
@@ -101,18 +101,14 @@ func (b *Service) AddStream(maxMsgs, maxBytes uint64, age time.Duration, subject
 		return fmt.Errorf("AddConsumer failed: %w", err)
 	}
 
-	b.streams = append(b.streams, jsStream{
+	_, err = b.streams.add(&jsStream{
+		subjects:     slices.Clone(subjects),
 		cfgStream:    cfg,
 		cfgConsumer:  ccfg,
 		streamInfo:   si,
 		consumerInfo: ci,
 	})
-
-	for _, s := range subjects {
-		b.streamSubjects.Add(Subject(s), len(b.streams)-1)
-	}
-
-	return nil
+	return err
 }
 
 // RemoveStream will attempt to remove consumers based on a list of subjects.
@@ -138,29 +134,72 @@ func (b *Service) RemoveStream(subjects ...string) error {
 		return fmt.Errorf("DeleteConsumer failed: %w", err)
 	}
 
-	b.removeStreamsFromMap(subjects)
+	b.streams.remove(subjects...)
 	return nil
 }
 
-func (b *Service) removeStreamsFromMap(subjects []string) {
-	idsToDeleteSet := make(map[int]struct{}, len(subjects))
-	for _, subject := range subjects {
-		s := Subject(subject)
-		if ssIdx, ok := b.streamSubjects.Get(s); ok {
-			delete(b.streamSubjects, s)
-			idsToDeleteSet[ssIdx] = struct{}{}
-		}
+// Streams returns descriptions of all durable streams registered with AddStream.
+func (b *Service) Streams() []StreamInfo {
+	if b.js == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	streams := b.streams.list()
+	result := make([]StreamInfo, 0, len(streams))
+	for _, stream := range streams {
+		result = append(result, stream.describe())
+	}
+	return result
+}
+
+// DescribeStream returns the description of a durable stream that captures the subject.
+func (b *Service) DescribeStream(subject string) (StreamInfo, bool) {
+	if b.js == nil {
+		return StreamInfo{}, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream, ok := b.streams.search(subject)
+	if !ok {
+		return StreamInfo{}, false
 	}
+	return stream.describe(), true
+}
+
+// RefreshStream retrieves up to date stream and consumer information for a durable stream that captures the subject.
+func (b *Service) RefreshStream(subject string) (StreamInfo, error) {
+	if b.js == nil {
+		return StreamInfo{}, ErrNotAvailable
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	idsToDelete := make([]int, 0, len(idsToDeleteSet))
-	for id := range idsToDelete {
-		idsToDelete = append(idsToDelete, id)
+	stream, ok := b.streams.search(subject)
+	if !ok {
+		return StreamInfo{}, fmt.Errorf("%s: %w", subject, ErrNotAvailable)
 	}
-	slices.SortFunc(idsToDelete, func(a, b int) int { return b - a })
 
-	for id := range idsToDelete {
-		b.streams = slices.Delete(b.streams, id, id+1)
+	ctx, cancel := context.WithTimeout(b.Context, 10*time.Second)
+	defer cancel()
+
+	si, err := b.js.StreamInfo(stream.cfgStream.Name, nats.Context(ctx))
+	if err != nil {
+		return StreamInfo{}, fmt.Errorf("StreamInfo failed: %w", err)
 	}
+	ci, err := b.js.ConsumerInfo(stream.cfgStream.Name, stream.cfgConsumer.Durable, nats.Context(ctx))
+	if err != nil {
+		return StreamInfo{}, fmt.Errorf("ConsumerInfo failed: %w", err)
+	}
+	stream.streamInfo = si
+	stream.consumerInfo = ci
+
+	return stream.describe(), nil
 }
 
 func (b *Service) attemptJSConsume(handler nats.MsgHandler, subject string) (*nats.Subscription, error) {
@@ -168,15 +207,14 @@ func (b *Service) attemptJSConsume(handler nats.MsgHandler, subject string) (*na
 		return nil, ErrNotAvailable
 	}
 
-	var info jsStream
-	streamName, consumerName := "", ""
-	if _, id, ok := b.streamSubjects.Search(Subject(subject)); !ok {
+	b.mu.Lock()
+	info, ok := b.streams.search(subject)
+	b.mu.Unlock()
+	if !ok {
 		return nil, ErrNotAvailable
-	} else {
-		info = b.streams[id]
-		streamName = info.cfgStream.Name
-		consumerName = info.consumerInfo.Config.Durable
 	}
+	streamName := info.cfgStream.Name
+	consumerName := info.cfgConsumer.Durable
 
 	sub, err := b.js.PullSubscribe(streamName, consumerName, nats.ManualAck(), nats.Bind(streamName, consumerName))
 	if err != nil {
@@ -195,9 +233,8 @@ func (b *Service) attemptJSConsume(handler nats.MsgHandler, subject string) (*na
 			}
 
 			ctx, cancel := context.WithTimeout(b.Context, 5*time.Second)
-			defer cancel()
-
 			msgs, err := sub.Fetch(10, nats.Context(ctx))
+			cancel()
 			if err != nil {
 				if errors.Is(err, context.Canceled) {
 					return fmt.Errorf("context cancelled during pulling next message: %w", err)
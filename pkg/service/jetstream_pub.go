@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+)
+
+// PublishToStream will sign the message and publish it to a JetStream stream that captures a subject constructed from subject tokens.
+// Unlike PublishTo, this is a synchronous operation that does not involve publisher queue and returns the acknowledgement
+// received from the stream.
+//
+// Nats-Msg-Id header is derived from the subject and the message signature by default so that the stream can deduplicate
+// repeated publishes of the same payload to the same subject. It can be overridden by passing nats.MsgId option.
+// Optimistic concurrency can be achieved by passing nats.ExpectLastSequence, nats.ExpectLastSequencePerSubject,
+// or nats.ExpectLastMsgId options.
+//
+// PublishToStream will use PubNats connection.
+func (b *Service) PublishToStream(ctx context.Context, msg proto.Message, opts []nats.PubOpt, tokens ...string) (*nats.PubAck, error) {
+	payload, err := b.Codec.Encode(nil, msg)
+	if err != nil {
+		return nil, err
+	}
+	return b.PublishBufToStream(ctx, payload, opts, tokens...)
+}
+
+// PublishBufToStream is the same as PublishToStream, but for raw bytes.
+func (b *Service) PublishBufToStream(ctx context.Context, buf []byte, opts []nats.PubOpt, tokens ...string) (*nats.PubAck, error) {
+	if b.PubNats == nil {
+		return nil, ErrPubConnection
+	}
+	if b.pubJs == nil {
+		return nil, ErrNotAvailable
+	}
+
+	msg, err := b.makeMsg(buf, "", strings.Join(tokens, "."))
+	if err != nil {
+		return nil, err
+	}
+	if signature := msg.Header.Get("signature"); signature != "" {
+		msg.Header.Set(nats.MsgIdHdr, b.jsMakeHash(msg.Subject, signature))
+	}
+
+	pubOpts := make([]nats.PubOpt, 0, len(opts)+1)
+	pubOpts = append(pubOpts, nats.Context(ctx))
+	pubOpts = append(pubOpts, opts...)
+
+	ack, err := b.pubJs.PublishMsg(msg, pubOpts...)
+	if err != nil {
+		return nil, err
+	}
+	b.msg_out_counter.Add(1)
+	b.bytes_out_counter.Add(uint64(len(msg.Data)))
+	return ack, nil
+}
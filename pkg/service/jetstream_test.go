@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/pkg/options"
+)
+
+// fakeConn is a NATS connection that also provides a mocked JetStream context.
+// Messages published directly are recorded, unless pubErr or flushErr is set.
+type fakeConn struct {
+	options.NatsConn
+	*MockJetStreamer
+	published []*nats.Msg
+	pubErr    error
+	flushErr  error
+}
+
+func (c *fakeConn) PublishMsg(m *nats.Msg) error {
+	if c.pubErr != nil {
+		return c.pubErr
+	}
+	c.published = append(c.published, m)
+	return nil
+}
+
+func (c *fakeConn) Flush() error {
+	return c.flushErr
+}
+
+func newFakeConn(t *testing.T, js *fakeJetStream) *fakeConn {
+	jetStreamer := NewMockJetStreamer(t)
+	jetStreamer.EXPECT().JetStream(mock.Anything).Return(js, nil)
+	return &fakeConn{MockJetStreamer: jetStreamer}
+}
+
+// fakeJetStream implements only the parts of nats.JetStreamContext used by the Service.
+type fakeJetStream struct {
+	nats.JetStreamContext
+	published []*nats.Msg
+	pubOpts   [][]nats.PubOpt
+	seq       uint64
+	seen      map[string]uint64
+	buckets   map[string]*fakeKeyValue
+	objects   map[string]*fakeObjectStore
+	consumers map[string]*nats.ConsumerConfig
+}
+
+func newFakeJetStream() *fakeJetStream {
+	return &fakeJetStream{
+		seen:      make(map[string]uint64),
+		buckets:   make(map[string]*fakeKeyValue),
+		objects:   make(map[string]*fakeObjectStore),
+		consumers: make(map[string]*nats.ConsumerConfig),
+	}
+}
+
+func (js *fakeJetStream) AddStream(cfg *nats.StreamConfig, opts ...nats.JSOpt) (*nats.StreamInfo, error) {
+	return &nats.StreamInfo{Config: *cfg}, nil
+}
+
+func (js *fakeJetStream) StreamInfo(stream string, opts ...nats.JSOpt) (*nats.StreamInfo, error) {
+	return &nats.StreamInfo{Config: nats.StreamConfig{Name: stream}, State: nats.StreamState{Msgs: 42}}, nil
+}
+
+func (js *fakeJetStream) AddConsumer(stream string, cfg *nats.ConsumerConfig, opts ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	if _, ok := js.consumers[cfg.Durable]; ok {
+		return nil, nats.ErrConsumerNameAlreadyInUse
+	}
+	js.consumers[cfg.Durable] = cfg
+	return &nats.ConsumerInfo{Stream: stream, Name: cfg.Durable, Config: *cfg}, nil
+}
+
+func (js *fakeJetStream) UpdateConsumer(stream string, cfg *nats.ConsumerConfig, opts ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	js.consumers[cfg.Durable] = cfg
+	return &nats.ConsumerInfo{Stream: stream, Name: cfg.Durable, Config: *cfg}, nil
+}
+
+func (js *fakeJetStream) DeleteConsumer(stream, consumer string, opts ...nats.JSOpt) error {
+	if _, ok := js.consumers[consumer]; !ok {
+		return nats.ErrConsumerNotFound
+	}
+	delete(js.consumers, consumer)
+	return nil
+}
+
+func (js *fakeJetStream) ConsumerInfo(stream, consumer string, opts ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	cfg, ok := js.consumers[consumer]
+	if !ok {
+		return nil, nats.ErrConsumerNotFound
+	}
+	return &nats.ConsumerInfo{Stream: stream, Name: consumer, Config: *cfg, NumPending: 7}, nil
+}
+
+func (js *fakeJetStream) PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	js.pubOpts = append(js.pubOpts, opts)
+	if seq, ok := js.seen[m.Header.Get(nats.MsgIdHdr)]; ok {
+		return &nats.PubAck{Stream: "test", Sequence: seq, Duplicate: true}, nil
+	}
+	js.seq++
+	js.seen[m.Header.Get(nats.MsgIdHdr)] = js.seq
+	js.published = append(js.published, m)
+	return &nats.PubAck{Stream: "test", Sequence: js.seq}, nil
+}
+
+func makeJetStreamService(t *testing.T) (*Service, *fakeJetStream) {
+	js := newFakeJetStream()
+	b := &Service{}
+	err := b.Configure(
+		WithName("bar"),
+		WithPrefix("foo"),
+		WithNKeySeed(testSeed),
+		WithNats(newFakeConn(t, js)),
+	)
+	require.NoError(t, err)
+	return b, js
+}
+
+func TestService_PublishBufToStream(t *testing.T) {
+	b, js := makeJetStreamService(t)
+	ctx := context.Background()
+
+	ack, err := b.PublishBufToStream(ctx, []byte("lore ipsum"), nil, "foo", "bar")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), ack.Sequence)
+	assert.False(t, ack.Duplicate)
+	require.Len(t, js.published, 1)
+	assert.Equal(t, "foo.bar", js.published[0].Subject)
+	assert.NotEmpty(t, js.published[0].Header.Get(nats.MsgIdHdr))
+	assert.NotEmpty(t, js.published[0].Header.Get("signature"))
+
+	ack, err = b.PublishBufToStream(ctx, []byte("lore ipsum"), nil, "foo", "bar")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), ack.Sequence)
+	assert.True(t, ack.Duplicate)
+
+	// The same payload on another subject is not a duplicate
+	ack, err = b.PublishBufToStream(ctx, []byte("lore ipsum"), nil, "foo", "baz")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), ack.Sequence)
+	assert.False(t, ack.Duplicate)
+	require.Len(t, js.published, 2)
+	assert.Equal(t, "foo.baz", js.published[1].Subject)
+	assert.NotEqual(t, js.published[0].Header.Get(nats.MsgIdHdr), js.published[1].Header.Get(nats.MsgIdHdr))
+
+	ack, err = b.PublishBufToStream(ctx, []byte("dolor sit amet"), []nats.PubOpt{nats.ExpectLastSequence(2)}, "foo", "bar")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), ack.Sequence)
+	require.Len(t, js.pubOpts, 4)
+	assert.Len(t, js.pubOpts[3], 2)
+}
+
+func TestService_PublishBufToStreamNotAvailable(t *testing.T) {
+	b := &Service{}
+	b.Configure(
+		WithName("bar"),
+		WithPrefix("foo"),
+		WithNKeySeed(testSeed),
+	)
+
+	_, err := b.PublishBufToStream(context.Background(), []byte("lore ipsum"), nil, "foo", "bar")
+	assert.ErrorIs(t, err, ErrNotAvailable)
+}
+
+func TestService_StreamLifecycle(t *testing.T) {
+	b, js := makeJetStreamService(t)
+
+	require.NoError(t, b.AddStream(10, 1024, time.Hour, "foo.a", "foo.b"))
+	require.NoError(t, b.AddStream(10, 1024, time.Hour, "bar.>"))
+	assert.Len(t, js.consumers, 2)
+
+	err := b.AddStream(10, 1024, time.Hour, "foo.*")
+	assert.ErrorContains(t, err, "already configured")
+	err = b.AddStream(10, 1024, time.Hour, "baz.a", "baz.*")
+	assert.ErrorContains(t, err, "overlapping subjects")
+
+	streams := b.Streams()
+	require.Len(t, streams, 2)
+	assert.Equal(t, []string{"foo.a", "foo.b"}, streams[0].Subjects)
+	assert.Equal(t, []string{"bar.>"}, streams[1].Subjects)
+
+	info, ok := b.DescribeStream("bar.x.y")
+	require.True(t, ok)
+	assert.Equal(t, streams[1].ID, info.ID)
+
+	// The infos are copies that the caller may modify
+	require.NotNil(t, info.Stream)
+	require.NotNil(t, info.Consumer)
+	info.Stream.Config.Subjects[0] = "baz.>"
+	info.Stream.State.Msgs = 1
+	info.Consumer.Config.FilterSubject = "baz.>"
+	again, ok := b.DescribeStream("bar.x.y")
+	require.True(t, ok)
+	assert.Equal(t, []string{"bar.>"}, again.Stream.Config.Subjects)
+	assert.Equal(t, streams[1].Stream.State.Msgs, again.Stream.State.Msgs)
+	assert.Equal(t, streams[1].Consumer.Config.FilterSubject, again.Consumer.Config.FilterSubject)
+	assert.NotSame(t, again.Stream, b.Streams()[1].Stream)
+
+	// Removing the first stream must not affect the second one
+	require.NoError(t, b.RemoveStream("foo.a", "foo.b"))
+	assert.Len(t, js.consumers, 1)
+	_, ok = b.DescribeStream("foo.a")
+	assert.False(t, ok)
+	_, ok = b.DescribeStream("foo.b")
+	assert.False(t, ok)
+	info, ok = b.DescribeStream("bar.x")
+	require.True(t, ok)
+	assert.Equal(t, []string{"bar.>"}, info.Subjects)
+	require.Len(t, b.Streams(), 1)
+
+	err = b.RemoveStream("foo.a", "foo.b")
+	assert.ErrorIs(t, err, nats.ErrConsumerNotFound)
+
+	// Re-adding gets a new ID
+	require.NoError(t, b.AddStream(10, 1024, time.Hour, "foo.a", "foo.b"))
+	streams = b.Streams()
+	require.Len(t, streams, 2)
+	assert.Equal(t, []string{"bar.>"}, streams[0].Subjects)
+	assert.Equal(t, []string{"foo.a", "foo.b"}, streams[1].Subjects)
+	assert.NotEqual(t, info.ID, streams[1].ID)
+
+	info, err = b.RefreshStream("foo.b")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), info.Stream.State.Msgs)
+	assert.Equal(t, uint64(7), info.Consumer.NumPending)
+
+	_, err = b.RefreshStream("baz")
+	assert.ErrorIs(t, err, ErrNotAvailable)
+}
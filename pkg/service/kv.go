@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/synternet/data-layer-sdk/pkg/options"
+	"github.com/synternet/data-layer-sdk/x/synternet/store"
+	"google.golang.org/protobuf/proto"
+)
+
+// KV is a JetStream key-value bucket that stores values signed by the service identity.
+// Values are encoded using the configured Codec and wrapped into store.SignedValue so that
+// readers can verify the writer the same way as they verify published messages. The signature also covers
+// the bucket, the key and the timestamp of the value.
+type KV struct {
+	svc *Service
+	kv  nats.KeyValue
+}
+
+// KVEntry is a verified entry retrieved from a key-value bucket.
+type KVEntry struct {
+	Bucket    string
+	Key       string
+	Revision  uint64
+	Delta     uint64
+	Created   time.Time
+	Operation nats.KeyValueOp
+	// Identity of the writer. Empty if the value was not signed or the entry is a delete marker.
+	Identity string
+	// Timestamp when the value was signed by the writer.
+	Timestamp time.Time
+
+	data  []byte
+	codec options.Codec
+}
+
+// Data returns the raw encoded value.
+func (e *KVEntry) Data() []byte {
+	return e.data
+}
+
+// Decode unmarshals the value into msg using the codec of the service.
+func (e *KVEntry) Decode(msg proto.Message) error {
+	return e.codec.Decode(e.data, msg)
+}
+
+// KeyValue binds to a key-value bucket creating it if it does not exist.
+// If cfg is nil or cfg.Bucket is empty, then the bucket name will be "{prefix}-{name}".
+func (b *Service) KeyValue(cfg *nats.KeyValueConfig) (*KV, error) {
+	if b.js == nil {
+		return nil, ErrNotAvailable
+	}
+
+	var config nats.KeyValueConfig
+	if cfg != nil {
+		config = *cfg
+	}
+	if config.Bucket == "" {
+		config.Bucket = b.jsStreamName()
+	}
+
+	kv, err := b.js.KeyValue(config.Bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = b.js.CreateKeyValue(&config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key-value bucket %s: %w", config.Bucket, err)
+	}
+
+	return &KV{svc: b, kv: kv}, nil
+}
+
+// Bucket returns the name of the bucket.
+func (kv *KV) Bucket() string {
+	return kv.kv.Bucket()
+}
+
+// kvSigningPayload returns the bytes the value signature is computed over. The bucket, the key and the timestamp
+// are signed together with the data so that a signed value cannot be replayed under another key or bucket.
+func kvSigningPayload(bucket, key string, timestamp int64, data []byte) []byte {
+	buf := make([]byte, 0, len(bucket)+len(key)+10+len(data))
+	buf = append(buf, bucket...)
+	buf = append(buf, 0)
+	buf = append(buf, key...)
+	buf = append(buf, 0)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(timestamp))
+	return append(buf, data...)
+}
+
+func (kv *KV) encode(key string, msg proto.Message) ([]byte, error) {
+	payload, err := kv.svc.Codec.Encode(nil, msg)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().UnixNano()
+	signature, _, err := kv.svc.Sign(kvSigningPayload(kv.Bucket(), key, timestamp, payload))
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&store.SignedValue{
+		Data:      payload,
+		Identity:  kv.svc.Identity,
+		Signature: signature,
+		Timestamp: timestamp,
+	})
+}
+
+func (kv *KV) decode(entry nats.KeyValueEntry) (*KVEntry, error) {
+	result := &KVEntry{
+		Bucket:    entry.Bucket(),
+		Key:       entry.Key(),
+		Revision:  entry.Revision(),
+		Delta:     entry.Delta(),
+		Created:   entry.Created(),
+		Operation: entry.Operation(),
+		codec:     kv.svc.Codec,
+	}
+	if entry.Operation() != nats.KeyValuePut {
+		return result, nil
+	}
+
+	var value store.SignedValue
+	if err := proto.Unmarshal(entry.Value(), &value); err != nil {
+		return nil, fmt.Errorf("%s: %w", entry.Key(), err)
+	}
+	if err := kv.svc.verify(value.Identity, value.Signature, kvSigningPayload(entry.Bucket(), entry.Key(), value.Timestamp, value.Data)); err != nil {
+		return nil, fmt.Errorf("%s: %w", entry.Key(), err)
+	}
+	result.Identity = value.Identity
+	result.Timestamp = time.Unix(0, value.Timestamp)
+	result.data = value.Data
+	return result, nil
+}
+
+// Get returns the latest verified value for the key and decodes it into msg if msg is not nil.
+func (kv *KV) Get(key string, msg proto.Message) (*KVEntry, error) {
+	entry, err := kv.kv.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return kv.decodeInto(entry, msg)
+}
+
+// GetRevision is the same as Get, but returns a specific revision of the value.
+func (kv *KV) GetRevision(key string, revision uint64, msg proto.Message) (*KVEntry, error) {
+	entry, err := kv.kv.GetRevision(key, revision)
+	if err != nil {
+		return nil, err
+	}
+	return kv.decodeInto(entry, msg)
+}
+
+func (kv *KV) decodeInto(entry nats.KeyValueEntry, msg proto.Message) (*KVEntry, error) {
+	result, err := kv.decode(entry)
+	if err != nil {
+		return nil, err
+	}
+	if msg != nil {
+		if err := result.Decode(msg); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// Put will sign and place the new value for the key into the bucket.
+func (kv *KV) Put(key string, msg proto.Message) (uint64, error) {
+	value, err := kv.encode(key, msg)
+	if err != nil {
+		return 0, err
+	}
+	return kv.kv.Put(key, value)
+}
+
+// Create will sign and add the value for the key only if the key does not exist.
+func (kv *KV) Create(key string, msg proto.Message) (uint64, error) {
+	value, err := kv.encode(key, msg)
+	if err != nil {
+		return 0, err
+	}
+	return kv.kv.Create(key, value)
+}
+
+// Update will sign and update the value only if the latest revision of the key matches last.
+func (kv *KV) Update(key string, msg proto.Message, last uint64) (uint64, error) {
+	value, err := kv.encode(key, msg)
+	if err != nil {
+		return 0, err
+	}
+	return kv.kv.Update(key, value, last)
+}
+
+// Delete will place a delete marker and leave all revisions.
+func (kv *KV) Delete(key string, opts ...nats.DeleteOpt) error {
+	return kv.kv.Delete(key, opts...)
+}
+
+// History returns all verified historical values for the key.
+func (kv *KV) History(key string, opts ...nats.WatchOpt) ([]*KVEntry, error) {
+	entries, err := kv.kv.History(key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*KVEntry, 0, len(entries))
+	for _, entry := range entries {
+		decoded, err := kv.decode(entry)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, decoded)
+	}
+	return result, nil
+}
+
+// Watch sends verified updates to keys that match the keys argument which could include wildcards.
+// Entries that fail verification are logged and skipped. The channel is closed when the context is done
+// or the service is shutting down.
+func (kv *KV) Watch(ctx context.Context, keys string, opts ...nats.WatchOpt) (<-chan *KVEntry, error) {
+	watcher, err := kv.kv.Watch(keys, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *KVEntry, kv.svc.PublishQueueSize)
+	kv.svc.Group.Go(func() error {
+		defer close(ch)
+		defer watcher.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-kv.svc.Context.Done():
+				return nil
+			case entry, ok := <-watcher.Updates():
+				if !ok {
+					return nil
+				}
+				// nil entry marks that all initial values were received
+				if entry == nil {
+					continue
+				}
+				decoded, err := kv.decode(entry)
+				if err != nil {
+					kv.svc.Logger.Warn("key-value entry verification failed", "bucket", kv.Bucket(), "key", entry.Key(), "err", err)
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-kv.svc.Context.Done():
+					return nil
+				case ch <- decoded:
+				}
+			}
+		}
+	})
+
+	return ch, nil
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/x/synternet/store"
+	"github.com/synternet/data-layer-sdk/x/synternet/telemetry"
+	"google.golang.org/protobuf/proto"
+)
+
+type fakeEntry struct {
+	bucket   string
+	key      string
+	value    []byte
+	revision uint64
+	op       nats.KeyValueOp
+}
+
+func (e *fakeEntry) Bucket() string             { return e.bucket }
+func (e *fakeEntry) Key() string                { return e.key }
+func (e *fakeEntry) Value() []byte              { return e.value }
+func (e *fakeEntry) Revision() uint64           { return e.revision }
+func (e *fakeEntry) Created() time.Time         { return time.Time{} }
+func (e *fakeEntry) Delta() uint64              { return 0 }
+func (e *fakeEntry) Operation() nats.KeyValueOp { return e.op }
+
+type fakeWatcher struct {
+	ch chan nats.KeyValueEntry
+}
+
+func (w *fakeWatcher) Context() context.Context           { return context.Background() }
+func (w *fakeWatcher) Updates() <-chan nats.KeyValueEntry { return w.ch }
+func (w *fakeWatcher) Stop() error                        { return nil }
+
+// fakeKeyValue implements only the parts of nats.KeyValue used by KV.
+type fakeKeyValue struct {
+	nats.KeyValue
+	mu       sync.Mutex
+	bucket   string
+	revision uint64
+	entries  []*fakeEntry
+	watchers []*fakeWatcher
+}
+
+func (kv *fakeKeyValue) Bucket() string { return kv.bucket }
+
+func (kv *fakeKeyValue) last(key string) *fakeEntry {
+	for i := len(kv.entries) - 1; i >= 0; i-- {
+		if kv.entries[i].key == key {
+			return kv.entries[i]
+		}
+	}
+	return nil
+}
+
+func (kv *fakeKeyValue) add(key string, value []byte, op nats.KeyValueOp) uint64 {
+	kv.revision++
+	entry := &fakeEntry{bucket: kv.bucket, key: key, value: value, revision: kv.revision, op: op}
+	kv.entries = append(kv.entries, entry)
+	for _, w := range kv.watchers {
+		w.ch <- entry
+	}
+	return kv.revision
+}
+
+func (kv *fakeKeyValue) Get(key string) (nats.KeyValueEntry, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if e := kv.last(key); e != nil && e.op == nats.KeyValuePut {
+		return e, nil
+	}
+	return nil, nats.ErrKeyNotFound
+}
+
+func (kv *fakeKeyValue) Put(key string, value []byte) (uint64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.add(key, value, nats.KeyValuePut), nil
+}
+
+func (kv *fakeKeyValue) Create(key string, value []byte) (uint64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if e := kv.last(key); e != nil && e.op == nats.KeyValuePut {
+		return 0, nats.ErrKeyExists
+	}
+	return kv.add(key, value, nats.KeyValuePut), nil
+}
+
+func (kv *fakeKeyValue) Update(key string, value []byte, last uint64) (uint64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if e := kv.last(key); e == nil || e.revision != last {
+		return 0, nats.ErrKeyExists
+	}
+	return kv.add(key, value, nats.KeyValuePut), nil
+}
+
+func (kv *fakeKeyValue) Delete(key string, opts ...nats.DeleteOpt) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.add(key, nil, nats.KeyValueDelete)
+	return nil
+}
+
+func (kv *fakeKeyValue) History(key string, opts ...nats.WatchOpt) ([]nats.KeyValueEntry, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	var result []nats.KeyValueEntry
+	for _, e := range kv.entries {
+		if e.key == key {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func (kv *fakeKeyValue) Watch(keys string, opts ...nats.WatchOpt) (nats.KeyWatcher, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	w := &fakeWatcher{ch: make(chan nats.KeyValueEntry, 10)}
+	w.ch <- nil
+	kv.watchers = append(kv.watchers, w)
+	return w, nil
+}
+
+func (js *fakeJetStream) KeyValue(bucket string) (nats.KeyValue, error) {
+	if kv, ok := js.buckets[bucket]; ok {
+		return kv, nil
+	}
+	return nil, nats.ErrBucketNotFound
+}
+
+func (js *fakeJetStream) CreateKeyValue(cfg *nats.KeyValueConfig) (nats.KeyValue, error) {
+	kv := &fakeKeyValue{bucket: cfg.Bucket}
+	js.buckets[cfg.Bucket] = kv
+	return kv, nil
+}
+
+func TestKV_PutGet(t *testing.T) {
+	b, _ := makeJetStreamService(t)
+
+	kv, err := b.KeyValue(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "foo-bar", kv.Bucket())
+
+	rev, err := kv.Put("cursor", &telemetry.Ping{Nonce: "abc", Timestamp: 1})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), rev)
+
+	var ping telemetry.Ping
+	entry, err := kv.Get("cursor", &ping)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", ping.Nonce)
+	assert.Equal(t, b.Identity, entry.Identity)
+	assert.Equal(t, uint64(1), entry.Revision)
+
+	_, err = kv.Create("cursor", &telemetry.Ping{Nonce: "def"})
+	assert.ErrorIs(t, err, nats.ErrKeyExists)
+
+	_, err = kv.Update("cursor", &telemetry.Ping{Nonce: "def"}, 2)
+	assert.ErrorIs(t, err, nats.ErrKeyExists)
+
+	rev, err = kv.Update("cursor", &telemetry.Ping{Nonce: "def"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), rev)
+
+	require.NoError(t, kv.Delete("cursor"))
+	_, err = kv.Get("cursor", nil)
+	assert.ErrorIs(t, err, nats.ErrKeyNotFound)
+
+	history, err := kv.History("cursor")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.NoError(t, history[1].Decode(&ping))
+	assert.Equal(t, "def", ping.Nonce)
+	assert.Equal(t, nats.KeyValueDelete, history[2].Operation)
+}
+
+func TestKV_Verify(t *testing.T) {
+	b, js := makeJetStreamService(t)
+
+	kv, err := b.KeyValue(&nats.KeyValueConfig{Bucket: "state"})
+	require.NoError(t, err)
+
+	_, err = kv.Put("cursor", &telemetry.Ping{Nonce: "abc"})
+	require.NoError(t, err)
+
+	// Tamper with the stored value
+	raw := js.buckets["state"].entries[0]
+	var value store.SignedValue
+	require.NoError(t, proto.Unmarshal(raw.value, &value))
+	value.Data[3] = 'x'
+	raw.value, err = proto.Marshal(&value)
+	require.NoError(t, err)
+
+	_, err = kv.Get("cursor", nil)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestKV_VerifyReplay(t *testing.T) {
+	b, js := makeJetStreamService(t)
+
+	kv, err := b.KeyValue(&nats.KeyValueConfig{Bucket: "state"})
+	require.NoError(t, err)
+	other, err := b.KeyValue(&nats.KeyValueConfig{Bucket: "other"})
+	require.NoError(t, err)
+
+	_, err = kv.Put("cursor", &telemetry.Ping{Nonce: "abc"})
+	require.NoError(t, err)
+	signed := js.buckets["state"].entries[0].value
+
+	// The value is signed for its key and bucket
+	_, err = js.buckets["state"].Put("admin", signed)
+	require.NoError(t, err)
+	_, err = kv.Get("admin", nil)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = js.buckets["other"].Put("cursor", signed)
+	require.NoError(t, err)
+	_, err = other.Get("cursor", nil)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// The timestamp is signed too
+	var value store.SignedValue
+	require.NoError(t, proto.Unmarshal(signed, &value))
+	value.Timestamp++
+	tampered, err := proto.Marshal(&value)
+	require.NoError(t, err)
+	_, err = js.buckets["state"].Put("cursor", tampered)
+	require.NoError(t, err)
+	_, err = kv.Get("cursor", nil)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestKV_Watch(t *testing.T) {
+	b, _ := makeJetStreamService(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	kv, err := b.KeyValue(nil)
+	require.NoError(t, err)
+
+	ch, err := kv.Watch(ctx, "cursor")
+	require.NoError(t, err)
+
+	_, err = kv.Put("cursor", &telemetry.Ping{Nonce: "abc"})
+	require.NoError(t, err)
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("no update received")
+	case entry := <-ch:
+		var ping telemetry.Ping
+		require.NoError(t, entry.Decode(&ping))
+		assert.Equal(t, "abc", ping.Nonce)
+	}
+
+	cancel()
+	require.NoError(t, b.Close())
+}
@@ -18,6 +18,7 @@ type Message interface {
 	Nak(opts ...nats.AckOpt) error
 	NakWithDelay(delay time.Duration, opts ...nats.AckOpt) error
 	Respond(proto.Message) error
+	RespondWithHeader(proto.Message, nats.Header) error
 	Term(opts ...nats.AckOpt) error
 
 	Message() *nats.Msg
@@ -31,6 +32,8 @@ type Message interface {
 type (
 	MessageHandler func(msg Message)
 	ServiceHandler func(msg Message) (proto.Message, error)
+	// ServiceHeaderHandler is the same as ServiceHandler, but also returns the header that will be added to the response.
+	ServiceHeaderHandler func(msg Message) (proto.Message, nats.Header, error)
 )
 
 type natsMessage struct {
@@ -39,6 +42,7 @@ type natsMessage struct {
 	msgCounter   *atomic.Uint64
 	bytesCounter *atomic.Uint64
 	make         func([]byte, string, string) (*nats.Msg, error)
+	object       *objectPayload
 }
 
 func wrapMessage(codec options.Codec, msgCounter, bytesCounter *atomic.Uint64, maker func([]byte, string, string) (*nats.Msg, error), msg *nats.Msg) *natsMessage {
@@ -54,6 +58,12 @@ func (m natsMessage) Message() *nats.Msg {
 }
 
 func (m natsMessage) Respond(msg proto.Message) error {
+	return m.RespondWithHeader(msg, nil)
+}
+
+// RespondWithHeader is the same as Respond, but will also add the header to the response.
+// Identity and signature headers cannot be overridden.
+func (m natsMessage) RespondWithHeader(msg proto.Message, header nats.Header) error {
 	payload, err := m.codec.Encode(nil, msg)
 	if err != nil {
 		return err
@@ -63,6 +73,7 @@ func (m natsMessage) Respond(msg proto.Message) error {
 	if err != nil {
 		return err
 	}
+	mergeHeader(nmsg.Header, header)
 
 	err = m.RespondMsg(nmsg)
 	m.msgCounter.Add(1)
@@ -78,7 +89,15 @@ func (m natsMessage) Reply() string {
 	return m.Msg.Reply
 }
 
+// Data returns the payload of the message. For pointer messages this is the payload fetched from the object store,
+// which is nil if the pointer cannot be verified or the payload cannot be fetched. See Service.Verify.
 func (m natsMessage) Data() []byte {
+	if m.object != nil {
+		if m.object.resolve() != nil {
+			return nil
+		}
+		return m.object.data
+	}
 	return m.Msg.Data
 }
 
@@ -701,6 +701,53 @@ func (_c *MockMessage_Respond_Call) RunAndReturn(run func(protoreflect.ProtoMess
 	return _c
 }
 
+// RespondWithHeader provides a mock function with given fields: _a0, _a1
+func (_m *MockMessage) RespondWithHeader(_a0 protoreflect.ProtoMessage, _a1 nats.Header) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RespondWithHeader")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(protoreflect.ProtoMessage, nats.Header) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockMessage_RespondWithHeader_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RespondWithHeader'
+type MockMessage_RespondWithHeader_Call struct {
+	*mock.Call
+}
+
+// RespondWithHeader is a helper method to define mock.On call
+//   - _a0 protoreflect.ProtoMessage
+//   - _a1 nats.Header
+func (_e *MockMessage_Expecter) RespondWithHeader(_a0 interface{}, _a1 interface{}) *MockMessage_RespondWithHeader_Call {
+	return &MockMessage_RespondWithHeader_Call{Call: _e.mock.On("RespondWithHeader", _a0, _a1)}
+}
+
+func (_c *MockMessage_RespondWithHeader_Call) Run(run func(_a0 protoreflect.ProtoMessage, _a1 nats.Header)) *MockMessage_RespondWithHeader_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(protoreflect.ProtoMessage), args[1].(nats.Header))
+	})
+	return _c
+}
+
+func (_c *MockMessage_RespondWithHeader_Call) Return(_a0 error) *MockMessage_RespondWithHeader_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockMessage_RespondWithHeader_Call) RunAndReturn(run func(protoreflect.ProtoMessage, nats.Header) error) *MockMessage_RespondWithHeader_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Subject provides a mock function with given fields:
 func (_m *MockMessage) Subject() string {
 	ret := _m.Called()
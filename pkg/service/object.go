@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cosmos/btcutil/base58"
+	"github.com/nats-io/nats.go"
+	"github.com/synternet/data-layer-sdk/x/synternet/store"
+	"google.golang.org/protobuf/proto"
+)
+
+// ObjectHeader is set on pointer messages that refer to a payload stored in an object store bucket.
+const ObjectHeader = "object"
+
+var (
+	ErrObjectDigest = errors.New("object digest mismatch")
+	ErrObjectSize   = errors.New("object size mismatch")
+)
+
+// objectFetchTimeout limits fetching the payload of a pointer message from the object store.
+const objectFetchTimeout = 10 * time.Second
+
+// objectPayload holds a payload fetched from an object store for a pointer message.
+// The payload is fetched once on the first use, after the pointer message has been verified.
+type objectPayload struct {
+	pointer *store.ObjectPointer
+	// load verifies the pointer message and fetches the payload
+	load func() ([]byte, error)
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// resolve fetches the payload and checks that it matches the digest in the pointer.
+func (o *objectPayload) resolve() error {
+	o.once.Do(func() {
+		if o.data, o.err = o.load(); o.err == nil {
+			o.err = o.verify()
+		}
+		if o.err != nil {
+			o.data = nil
+		}
+	})
+	return o.err
+}
+
+// verify checks that the fetched payload matches the digest in the pointer.
+func (o *objectPayload) verify() error {
+	if uint64(len(o.data)) != o.pointer.Size {
+		return ErrObjectSize
+	}
+	digest := sha256.Sum256(o.data)
+	if !bytes.Equal(digest[:], o.pointer.Digest) {
+		return ErrObjectDigest
+	}
+	return nil
+}
+
+func (b *Service) objectBucket() string {
+	if b.ObjectBucket != "" {
+		return b.ObjectBucket
+	}
+	return fmt.Sprintf("%s-%s", b.Prefix, b.Name)
+}
+
+// objectStore binds to an object store bucket and caches it. The bucket is created if create is true.
+func (b *Service) objectStore(js nats.JetStreamContext, bucket string, create bool) (nats.ObjectStore, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if obs, ok := b.objectStores[bucket]; ok {
+		return obs, nil
+	}
+
+	obs, err := js.ObjectStore(bucket)
+	if create && errors.Is(err, nats.ErrStreamNotFound) {
+		obs, err = js.CreateObjectStore(&nats.ObjectStoreConfig{
+			Bucket: bucket,
+			TTL:    b.ObjectTTL,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("object store %s: %w", bucket, err)
+	}
+
+	if b.objectStores == nil {
+		b.objectStores = make(map[string]nats.ObjectStore)
+	}
+	b.objectStores[bucket] = obs
+	return obs, nil
+}
+
+// makeObjectMsg stores the payload in the object store and constructs a signed pointer message.
+func (b *Service) makeObjectMsg(buf []byte, replyTo, subject string) (*nats.Msg, error) {
+	if b.pubJs == nil {
+		return nil, fmt.Errorf("payload of %d bytes exceeds %d: %w", len(buf), b.ObjectThreshold, ErrNotAvailable)
+	}
+
+	bucket := b.objectBucket()
+	obs, err := b.objectStore(b.pubJs, bucket, true)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(buf)
+	pointer := &store.ObjectPointer{
+		Bucket: bucket,
+		Name:   base58.Encode(digest[:]),
+		Size:   uint64(len(buf)),
+		Digest: digest[:],
+	}
+	if _, err := obs.PutBytes(pointer.Name, buf); err != nil {
+		return nil, fmt.Errorf("object put failed: %w", err)
+	}
+
+	payload, err := proto.Marshal(pointer)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := b.makeMsg(payload, replyTo, subject)
+	if err != nil {
+		return nil, err
+	}
+	msg.Header.Set(ObjectHeader, bucket)
+	return msg, nil
+}
+
+// resolveObject prepares fetching the payload for a pointer message. The payload is fetched lazily by Verify or Data,
+// so that the subscription callback is not blocked, and only once the signature of the pointer has been verified.
+// The pointer must refer to the bucket named in the object header. Any errors are reported during verification.
+func (b *Service) resolveObject(msg *natsMessage) {
+	bucket := msg.Msg.Header.Get(ObjectHeader)
+	if bucket == "" {
+		return
+	}
+
+	object := &objectPayload{pointer: &store.ObjectPointer{}}
+	object.load = func() ([]byte, error) {
+		if err := b.verifyHeader(msg.Msg.Header, msg.Msg.Data); err != nil {
+			return nil, err
+		}
+		if err := proto.Unmarshal(msg.Msg.Data, object.pointer); err != nil {
+			return nil, fmt.Errorf("object pointer: %w", err)
+		}
+		if object.pointer.Bucket != bucket {
+			return nil, fmt.Errorf("object pointer: bucket %q does not match %q", object.pointer.Bucket, bucket)
+		}
+		if b.js == nil {
+			return nil, ErrNotAvailable
+		}
+		obs, err := b.objectStore(b.js, bucket, false)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(b.Context, objectFetchTimeout)
+		defer cancel()
+		return obs.GetBytes(object.pointer.Name, nats.Context(ctx))
+	}
+	msg.object = object
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/x/synternet/telemetry"
+)
+
+// fakeObjectStore implements only the parts of nats.ObjectStore used by the Service.
+type fakeObjectStore struct {
+	nats.ObjectStore
+	objects map[string][]byte
+	gets    int
+}
+
+func (o *fakeObjectStore) PutBytes(name string, data []byte, opts ...nats.ObjectOpt) (*nats.ObjectInfo, error) {
+	o.objects[name] = bytes.Clone(data)
+	return &nats.ObjectInfo{}, nil
+}
+
+func (o *fakeObjectStore) GetBytes(name string, opts ...nats.GetObjectOpt) ([]byte, error) {
+	o.gets++
+	data, ok := o.objects[name]
+	if !ok {
+		return nil, nats.ErrObjectNotFound
+	}
+	return data, nil
+}
+
+func (js *fakeJetStream) ObjectStore(bucket string) (nats.ObjectStore, error) {
+	if obs, ok := js.objects[bucket]; ok {
+		return obs, nil
+	}
+	return nil, nats.ErrStreamNotFound
+}
+
+func (js *fakeJetStream) CreateObjectStore(cfg *nats.ObjectStoreConfig) (nats.ObjectStore, error) {
+	obs := &fakeObjectStore{objects: make(map[string][]byte)}
+	js.objects[cfg.Bucket] = obs
+	return obs, nil
+}
+
+func receive(b *Service, msg *nats.Msg) *natsMessage {
+	var (
+		msg_out_counter   atomic.Uint64
+		bytes_out_counter atomic.Uint64
+	)
+	wrapped := wrapMessage(b.Codec, &msg_out_counter, &bytes_out_counter, b.makeMsg, msg)
+	b.resolveObject(wrapped)
+	return wrapped
+}
+
+func TestService_PublishObject(t *testing.T) {
+	b, js := makeJetStreamService(t)
+	b.ObjectThreshold = 16
+
+	err := b.PublishTo(&telemetry.Ping{Nonce: "a nonce that is long enough"}, "foo", "bar")
+	require.NoError(t, err)
+
+	msg := <-b.publishCh
+	assert.Equal(t, "foo-bar", msg.Header.Get(ObjectHeader))
+	require.Len(t, js.objects["foo-bar"].objects, 1)
+
+	wrapped := receive(b, msg)
+	var ping telemetry.Ping
+	_, err = b.Unmarshal(wrapped, &ping)
+	require.NoError(t, err)
+	assert.Equal(t, "a nonce that is long enough", ping.Nonce)
+
+	err = b.PublishTo(&telemetry.Ping{}, "foo", "bar")
+	require.NoError(t, err)
+	msg = <-b.publishCh
+	assert.Empty(t, msg.Header.Get(ObjectHeader))
+}
+
+func TestService_PublishObjectTampered(t *testing.T) {
+	b, js := makeJetStreamService(t)
+	b.ObjectThreshold = 16
+
+	err := b.PublishBufTo([]byte("lore ipsum dolor sit amet"), "foo", "bar")
+	require.NoError(t, err)
+	msg := <-b.publishCh
+
+	for name := range js.objects["foo-bar"].objects {
+		js.objects["foo-bar"].objects[name] = []byte("lore ipsum dolor sit amet!")
+	}
+	assert.ErrorIs(t, b.Verify(receive(b, msg)), ErrObjectSize)
+
+	for name := range js.objects["foo-bar"].objects {
+		js.objects["foo-bar"].objects[name] = []byte("lore ipsum dolor sit amen")
+	}
+	assert.ErrorIs(t, b.Verify(receive(b, msg)), ErrObjectDigest)
+
+	msg.Data[3] = 1
+	assert.Error(t, b.Verify(receive(b, msg)))
+}
+
+func TestService_PublishObjectLazy(t *testing.T) {
+	b, js := makeJetStreamService(t)
+	b.ObjectThreshold = 16
+
+	require.NoError(t, b.PublishBufTo([]byte("lore ipsum dolor sit amet"), "foo", "bar"))
+	msg := <-b.publishCh
+	obs := js.objects["foo-bar"]
+
+	// The payload is fetched once on the first use, not on receipt
+	wrapped := receive(b, msg)
+	assert.Zero(t, obs.gets)
+	assert.NoError(t, b.Verify(wrapped))
+	assert.Equal(t, []byte("lore ipsum dolor sit amet"), wrapped.Data())
+	assert.Equal(t, 1, obs.gets)
+
+	// Pointers from unknown publishers are not followed
+	other := &Service{}
+	require.NoError(t, other.Configure(WithName("baz"), WithPrefix("foo"), WithNats(newFakeConn(t, js))))
+	b.KnownPublicKeys = map[string]ed25519.PublicKey{other.Identity: other.PrivateKey.(ed25519.PrivateKey).Public().(ed25519.PublicKey)}
+	wrapped = receive(b, msg)
+	assert.ErrorIs(t, b.Verify(wrapped), ErrUnknownIdentity)
+	assert.Nil(t, wrapped.Data())
+	assert.Equal(t, 1, obs.gets)
+	b.KnownPublicKeys = nil
+
+	// The pointer must refer to the bucket in the header
+	msg.Header.Set(ObjectHeader, "other")
+	assert.ErrorContains(t, b.Verify(receive(b, msg)), "does not match")
+	assert.Equal(t, 1, obs.gets)
+}
@@ -105,6 +105,17 @@ func WithStreamName(name string) options.Option {
 	}
 }
 
+// WithObjectStore enables storing payloads larger than threshold bytes in a JetStream object store.
+// A small signed pointer message is published instead and subscribers fetch the payload transparently.
+// If an empty bucket is used, then bucket name will be "{prefix}-{name}".
+func WithObjectStore(threshold int, bucket string, ttl time.Duration) options.Option {
+	return func(o *options.Options) {
+		o.ObjectThreshold = threshold
+		o.ObjectBucket = bucket
+		o.ObjectTTL = ttl
+	}
+}
+
 // WithPrefix sets the prefix for the subject. The subject is in the form of {prefix}.{name}.
 // Subscriptions and publishing will use the subject constructed from prefix and name.
 func WithPrefix(prefix string) options.Option {
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// TransformFunc transforms a source message into a message that will be republished.
+// If it returns nil message, then the source message will be skipped.
+type TransformFunc func(msg Message) (proto.Message, error)
+
+// RepublisherConfig configures a Republisher.
+type RepublisherConfig struct {
+	// Name identifies the republisher. It is used to name the durable consumer and the progress key.
+	Name string
+	// Source subject to consume messages from.
+	Source string
+	// SourceStream is the name of an existing stream that captures Source subject.
+	// If empty, a stream "{prefix}-{name}-{Name}" capturing Source subject will be created.
+	SourceStream string
+	// Sourcing creates a local stream "{prefix}-{name}-{Name}" that sources SourceStream instead of
+	// consuming SourceStream directly.
+	Sourcing bool
+	// MaxAge of messages in the created stream.
+	MaxAge time.Duration
+	// Suffixes of the subject "{prefix}.{name}.{suffixes}" that messages will be republished to.
+	Suffixes []string
+	// Deduplicate publishes messages using PublishToStream with Nats-Msg-Id derived from the source sequence.
+	// The republished subject must be captured by a stream.
+	Deduplicate bool
+	// Transform is called for every source message.
+	Transform TransformFunc
+}
+
+// Republisher consumes a source subject durably, transforms messages and republishes them under
+// the service subject. The progress is stored in a key-value bucket so that republishing resumes
+// right after the last successfully republished source sequence. Messages are published synchronously,
+// bypassing the publish queue, so the progress is recorded only after the server has received them.
+type Republisher struct {
+	svc      *Service
+	cfg      RepublisherConfig
+	stream   string
+	consumer string
+	kv       *KV
+	cursor   atomic.Uint64
+}
+
+// NewRepublisher configures streams and the durable consumer for the republisher.
+func (b *Service) NewRepublisher(cfg RepublisherConfig) (*Republisher, error) {
+	if b.js == nil {
+		return nil, ErrNotAvailable
+	}
+	if cfg.Name == "" {
+		return nil, errors.New("republisher name must not be empty")
+	}
+	if cfg.Source == "" {
+		return nil, errors.New("source subject must not be empty")
+	}
+	if cfg.Transform == nil {
+		return nil, errors.New("transform must not be nil")
+	}
+	if cfg.Sourcing && cfg.SourceStream == "" {
+		return nil, errors.New("sourcing requires source stream")
+	}
+
+	r := &Republisher{
+		svc:      b,
+		cfg:      cfg,
+		stream:   cfg.SourceStream,
+		consumer: fmt.Sprintf("%s-%s", b.Identity, cfg.Name),
+	}
+
+	kv, err := b.KeyValue(nil)
+	if err != nil {
+		return nil, err
+	}
+	r.kv = kv
+
+	var cursor wrapperspb.UInt64Value
+	if _, err := kv.Get(r.progressKey(), &cursor); err != nil && !errors.Is(err, nats.ErrKeyNotFound) {
+		return nil, fmt.Errorf("republisher progress: %w", err)
+	}
+	r.cursor.Store(cursor.Value)
+
+	if err := r.setupStream(); err != nil {
+		return nil, err
+	}
+	if err := r.setupConsumer(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Republisher) progressKey() string {
+	return fmt.Sprintf("republish.%s", r.cfg.Name)
+}
+
+func (r *Republisher) setupStream() error {
+	if r.cfg.SourceStream != "" && !r.cfg.Sourcing {
+		return nil
+	}
+
+	cfg := &nats.StreamConfig{
+		Name:   fmt.Sprintf("%s-%s", r.svc.jsStreamName(), r.cfg.Name),
+		MaxAge: r.cfg.MaxAge,
+	}
+	if r.cfg.Sourcing {
+		cfg.Sources = []*nats.StreamSource{{Name: r.cfg.SourceStream, FilterSubject: r.cfg.Source}}
+	} else {
+		cfg.Subjects = []string{r.cfg.Source}
+	}
+
+	ctx, cancel := context.WithTimeout(r.svc.Context, 10*time.Second)
+	defer cancel()
+
+	if _, err := r.svc.js.AddStream(cfg, nats.Context(ctx)); err != nil {
+		return fmt.Errorf("AddStream failed: %w", err)
+	}
+	r.stream = cfg.Name
+	return nil
+}
+
+func (r *Republisher) setupConsumer() error {
+	ccfg := &nats.ConsumerConfig{
+		Durable:       r.consumer,
+		AckPolicy:     nats.AckExplicitPolicy,
+		DeliverPolicy: nats.DeliverAllPolicy,
+		FilterSubject: r.cfg.Source,
+	}
+	if cursor := r.cursor.Load(); cursor != 0 {
+		ccfg.DeliverPolicy = nats.DeliverByStartSequencePolicy
+		ccfg.OptStartSeq = cursor + 1
+	}
+
+	// Existing consumer keeps track of acknowledged messages itself.
+	_, err := r.svc.js.AddConsumer(r.stream, ccfg)
+	if err != nil && !errors.Is(err, nats.ErrConsumerNameAlreadyInUse) {
+		return fmt.Errorf("AddConsumer failed: %w", err)
+	}
+	return nil
+}
+
+// Cursor returns the last successfully republished source stream sequence.
+func (r *Republisher) Cursor() uint64 {
+	return r.cursor.Load()
+}
+
+// Start begins consuming the source stream in the service group.
+func (r *Republisher) Start() error {
+	sub, err := r.svc.js.PullSubscribe(r.cfg.Source, r.consumer, nats.ManualAck(), nats.Bind(r.stream, r.consumer))
+	if err != nil {
+		return err
+	}
+
+	r.svc.Group.Go(func() error {
+		r.svc.Logger.Info("Republisher loop start", "name", r.cfg.Name, "source", r.cfg.Source, "cursor", r.Cursor())
+		defer r.svc.Logger.Info("Republisher loop exit", "name", r.cfg.Name, "cursor", r.Cursor())
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case <-r.svc.Context.Done():
+				return nil
+			default:
+			}
+
+			ctx, cancel := context.WithTimeout(r.svc.Context, 5*time.Second)
+			msgs, err := sub.Fetch(10, nats.Context(ctx))
+			cancel()
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return nil
+				}
+				if errors.Is(err, context.DeadlineExceeded) {
+					continue
+				}
+				return fmt.Errorf("republisher %s pulling message failed: %w", r.cfg.Name, err)
+			}
+			batch := make([]Message, 0, len(msgs))
+			for _, msg := range msgs {
+				r.svc.msg_in_counter.Add(1)
+				r.svc.bytes_in_counter.Add(uint64(len(msg.Data)))
+				wrapped := wrapMessage(r.svc.Codec, &r.svc.msg_out_counter, &r.svc.bytes_out_counter, r.svc.makeMsg, msg)
+				r.svc.resolveObject(wrapped)
+				batch = append(batch, wrapped)
+			}
+			if err := r.handleBatch(batch); err != nil {
+				r.svc.Logger.Warn("republish failed", "name", r.cfg.Name, "err", err)
+			}
+		}
+	})
+
+	return nil
+}
+
+// handleBatch republishes the fetched messages in order. It stops at the first failure and naks the failed message
+// together with the rest of the batch, so that later messages cannot move the cursor past the failed one.
+func (r *Republisher) handleBatch(msgs []Message) error {
+	for i, msg := range msgs {
+		if err := r.handle(msg); err != nil {
+			for _, rest := range msgs[i:] {
+				rest.Nak()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// handle republishes a single source message, records the progress, and acknowledges the message.
+func (r *Republisher) handle(msg Message) error {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return err
+	}
+	seq := meta.Sequence.Stream
+
+	// Already republished before a restart
+	if seq <= r.cursor.Load() {
+		return msg.Ack()
+	}
+
+	out, err := r.cfg.Transform(msg)
+	if err != nil {
+		return fmt.Errorf("transform: %w", err)
+	}
+
+	if out != nil {
+		subject := r.svc.Subject(r.cfg.Suffixes...)
+		if r.cfg.Deduplicate {
+			opts := []nats.PubOpt{nats.MsgId(fmt.Sprintf("%s.%d", r.stream, seq))}
+			_, err = r.svc.PublishToStream(r.svc.Context, out, opts, subject)
+		} else {
+			// The progress must not be recorded before the message has reached the server
+			var payload []byte
+			if payload, err = r.svc.Codec.Encode(nil, out); err == nil {
+				err = r.svc.publishBufSync(payload, subject)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("publish: %w", err)
+		}
+	}
+
+	if _, err := r.kv.Put(r.progressKey(), wrapperspb.UInt64(seq)); err != nil {
+		return fmt.Errorf("progress: %w", err)
+	}
+	r.cursor.Store(seq)
+
+	return msg.Ack()
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/synternet/data-layer-sdk/x/synternet/telemetry"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func sourceMessage(t *testing.T, seq uint64, data string) *MockMessage {
+	msg := NewMockMessage(t)
+	msg.EXPECT().Metadata().Return(&nats.MsgMetadata{Sequence: nats.SequencePair{Stream: seq}}, nil)
+	msg.EXPECT().Ack().Return(nil)
+	msg.EXPECT().Data().Return([]byte(data)).Maybe()
+	return msg
+}
+
+func TestRepublisher_Resume(t *testing.T) {
+	b, js := makeJetStreamService(t)
+
+	transformed := 0
+	cfg := RepublisherConfig{
+		Name:     "tx",
+		Source:   "source.tx",
+		Suffixes: []string{"tx"},
+		Transform: func(msg Message) (proto.Message, error) {
+			transformed++
+			return &telemetry.Ping{Nonce: string(msg.Data())}, nil
+		},
+	}
+
+	r, err := b.NewRepublisher(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "foo-bar-tx", r.stream)
+	require.Contains(t, js.consumers, r.consumer)
+	assert.Equal(t, nats.DeliverAllPolicy, js.consumers[r.consumer].DeliverPolicy)
+
+	require.NoError(t, r.handle(sourceMessage(t, 1, "first")))
+	require.NoError(t, r.handle(sourceMessage(t, 2, "second")))
+	assert.Equal(t, uint64(2), r.Cursor())
+	assert.Equal(t, 2, transformed)
+
+	conn := b.PubNats.(*fakeConn)
+	require.Len(t, conn.published, 2)
+	assert.Equal(t, "foo.bar.tx", conn.published[0].Subject)
+	assert.Len(t, b.publishCh, 0, "republished messages must not be queued")
+
+	// Redelivered message is acknowledged without republishing
+	require.NoError(t, r.handle(sourceMessage(t, 2, "second")))
+	assert.Equal(t, 2, transformed)
+	assert.Len(t, conn.published, 2)
+
+	// Restart with a fresh consumer resumes right after the cursor
+	delete(js.consumers, r.consumer)
+	r, err = b.NewRepublisher(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), r.Cursor())
+	assert.Equal(t, nats.DeliverByStartSequencePolicy, js.consumers[r.consumer].DeliverPolicy)
+	assert.Equal(t, uint64(3), js.consumers[r.consumer].OptStartSeq)
+}
+
+func TestRepublisher_Deduplicate(t *testing.T) {
+	b, js := makeJetStreamService(t)
+
+	r, err := b.NewRepublisher(RepublisherConfig{
+		Name:         "tx",
+		Source:       "source.tx",
+		SourceStream: "source",
+		Sourcing:     true,
+		Deduplicate:  true,
+		Transform: func(msg Message) (proto.Message, error) {
+			return &telemetry.Ping{Nonce: string(msg.Data())}, nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "foo-bar-tx", r.stream)
+
+	require.NoError(t, r.handle(sourceMessage(t, 5, "first")))
+	require.Len(t, js.published, 1)
+	assert.Equal(t, "foo.bar", js.published[0].Subject)
+	require.Len(t, js.pubOpts[0], 2)
+}
+
+func TestRepublisher_Skip(t *testing.T) {
+	b, _ := makeJetStreamService(t)
+
+	r, err := b.NewRepublisher(RepublisherConfig{
+		Name:         "tx",
+		Source:       "source.tx",
+		SourceStream: "source",
+		Transform: func(msg Message) (proto.Message, error) {
+			return nil, nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "source", r.stream)
+
+	require.NoError(t, r.handle(sourceMessage(t, 5, "first")))
+	assert.Equal(t, uint64(5), r.Cursor())
+	assert.Empty(t, b.PubNats.(*fakeConn).published)
+}
+
+func TestRepublisher_PublishFailure(t *testing.T) {
+	b, _ := makeJetStreamService(t)
+	conn := b.PubNats.(*fakeConn)
+
+	r, err := b.NewRepublisher(RepublisherConfig{
+		Name:         "tx",
+		Source:       "source.tx",
+		SourceStream: "source",
+		Transform: func(msg Message) (proto.Message, error) {
+			return &telemetry.Ping{Nonce: string(msg.Data())}, nil
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, r.handle(sourceMessage(t, 1, "first")))
+
+	// Neither a failed publish nor a failed flush advances the cursor or acknowledges the message
+	for _, fail := range []*error{&conn.pubErr, &conn.flushErr} {
+		*fail = nats.ErrConnectionClosed
+		msg := NewMockMessage(t)
+		msg.EXPECT().Metadata().Return(&nats.MsgMetadata{Sequence: nats.SequencePair{Stream: 2}}, nil)
+		msg.EXPECT().Data().Return([]byte("second")).Maybe()
+		assert.ErrorIs(t, r.handle(msg), nats.ErrConnectionClosed)
+		assert.Equal(t, uint64(1), r.Cursor())
+
+		var cursor wrapperspb.UInt64Value
+		_, err = r.kv.Get(r.progressKey(), &cursor)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), cursor.Value)
+		*fail = nil
+	}
+}
+
+func TestRepublisher_BatchFailure(t *testing.T) {
+	b, _ := makeJetStreamService(t)
+	conn := b.PubNats.(*fakeConn)
+
+	failing := true
+	r, err := b.NewRepublisher(RepublisherConfig{
+		Name:         "tx",
+		Source:       "source.tx",
+		SourceStream: "source",
+		Transform: func(msg Message) (proto.Message, error) {
+			if string(msg.Data()) == "second" && failing {
+				return nil, errors.New("transform failed")
+			}
+			return &telemetry.Ping{Nonce: string(msg.Data())}, nil
+		},
+	})
+	require.NoError(t, err)
+
+	// The message following the failed one is not republished, and both are naked
+	pending := func(seq uint64, data string) *MockMessage {
+		msg := NewMockMessage(t)
+		msg.EXPECT().Metadata().Return(&nats.MsgMetadata{Sequence: nats.SequencePair{Stream: seq}}, nil).Maybe()
+		msg.EXPECT().Data().Return([]byte(data)).Maybe()
+		msg.EXPECT().Nak().Return(nil).Once()
+		return msg
+	}
+	err = r.handleBatch([]Message{sourceMessage(t, 1, "first"), pending(2, "second"), pending(3, "third")})
+	assert.ErrorContains(t, err, "transform failed")
+	assert.Equal(t, uint64(1), r.Cursor())
+	require.Len(t, conn.published, 1)
+
+	// The redelivered messages are republished in order
+	failing = false
+	require.NoError(t, r.handleBatch([]Message{sourceMessage(t, 2, "second"), sourceMessage(t, 3, "third")}))
+	assert.Equal(t, uint64(3), r.Cursor())
+	require.Len(t, conn.published, 3)
+}
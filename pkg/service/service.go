@@ -39,13 +39,6 @@ var (
 	ErrReqConnection    = errors.New("request NATS connection is nil")
 )
 
-type jsStream struct {
-	cfgStream    *nats.StreamConfig
-	cfgConsumer  *nats.ConsumerConfig
-	streamInfo   *nats.StreamInfo
-	consumerInfo *nats.ConsumerInfo
-}
-
 // Service is the base publisher structure. It must be embedded in the publisher to benefit from the
 // common implementation. Config method must be called on this embedded struct in order to
 // properly set it up.
@@ -65,8 +58,9 @@ type Service struct {
 
 	// Experimental feature
 	js             nats.JetStreamContext
-	streams        []jsStream
-	streamSubjects SubjectMap
+	pubJs          nats.JetStreamContext
+	streams        *streamRegistry
+	objectStores   map[string]nats.ObjectStore
 }
 
 // Configure must be called by the publisher implementation.
@@ -94,6 +88,13 @@ func (b *Service) Configure(opts ...options.Option) error {
 		b.Logger.Info("Service configured", "identity", b.Identity, "JetStream", b.js != nil)
 	}()
 
+	if js, ok := b.PubNats.(JetStreamer); ok {
+		b.pubJs, err = js.JetStream(nats.Context(b.Context))
+		if err != nil {
+			b.Logger.Error("Publishing JetStream failed", "err", err)
+		}
+	}
+
 	if b.SubNats == nil {
 		return nil
 	}
@@ -101,10 +102,10 @@ func (b *Service) Configure(opts ...options.Option) error {
 	if js, ok := b.SubNats.(JetStreamer); ok {
 		b.js, err = js.JetStream(nats.Context(b.Context))
 		if err != nil {
-			b.Logger.Error("JetStream failed", err)
+			b.Logger.Error("JetStream failed", "err", err)
 			return nil
 		}
-		b.streamSubjects = make(SubjectMap)
+		b.streams = newStreamRegistry()
 	}
 	return nil
 }
@@ -249,6 +250,16 @@ func (b *Service) makeMsg(payload []byte, replyTo, subject string) (*nats.Msg, e
 	return result, nil
 }
 
+// mergeHeader copies header values into dst without overriding headers set by makeMsg.
+func mergeHeader(dst, header nats.Header) {
+	for k, v := range header {
+		if _, ok := dst[k]; ok {
+			continue
+		}
+		dst[k] = v
+	}
+}
+
 // Unmarshal is a convenience function that first verifies any signatures in the message and unmarshals bytes into a message.
 func (b *Service) Unmarshal(nmsg Message, msg proto.Message) (nats.Header, error) {
 	// TODO check signatures
@@ -271,9 +282,33 @@ func (b *Service) Sign(msg []byte) (signature []byte, publicKey []byte, err erro
 }
 
 // Verify will marshal the unmarshalled payload back to bytes and verifies the signature with that.
+//
+// Pointer messages are signed over the pointer that holds the digest of the whole object. The pointer is verified
+// before the object is fetched, and the object is then checked against the digest.
 func (b *Service) Verify(nmsg Message) error {
-	identity := nmsg.Header().Get("identity")
-	signature := nmsg.Header().Get("signature")
+	if m, ok := nmsg.(*natsMessage); ok && m.object != nil {
+		return m.object.resolve()
+	}
+	return b.verifyHeader(nmsg.Header(), nmsg.Data())
+}
+
+// verifyHeader checks the signature of the data against the identity and signature headers.
+func (b *Service) verifyHeader(header nats.Header, data []byte) error {
+	identity := header.Get("identity")
+	signature := header.Get("signature")
+	if signature == "" {
+		return b.verify(identity, nil, data)
+	}
+
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	return b.verify(identity, signatureBytes, data)
+}
+
+// verify checks the signature of the data against the identity of the signer.
+func (b *Service) verify(identity string, signature []byte, data []byte) error {
 	var pkey ed25519.PublicKey
 
 	switch {
@@ -283,7 +318,7 @@ func (b *Service) Verify(nmsg Message) error {
 		} else {
 			pkey = key
 		}
-	case identity == "" || signature == "":
+	case identity == "" || len(signature) == 0:
 		return nil
 	default:
 		identityBytes := base58.Decode(identity)
@@ -293,11 +328,7 @@ func (b *Service) Verify(nmsg Message) error {
 		pkey = ed25519.PublicKey(identityBytes)
 	}
 
-	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
-	if err != nil {
-		return err
-	}
-	if !ed25519.Verify(pkey, nmsg.Data(), signatureBytes) {
+	if !ed25519.Verify(pkey, data, signature) {
 		return ErrInvalidSignature
 	}
 
@@ -3,6 +3,7 @@ package service
 import (
 	"strings"
 
+	"github.com/nats-io/nats.go"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -28,11 +29,14 @@ func (b *Service) PublishTo(msg proto.Message, tokens ...string) error {
 }
 
 // PublishBufTo is the same as PublishTo, but for raw bytes.
+//
+// If the object store is configured and the payload exceeds the threshold, the payload will be stored
+// in the object store and a signed pointer message will be published instead.
 func (b *Service) PublishBufTo(buf []byte, tokens ...string) error {
 	if b.PubNats == nil {
 		return ErrPubConnection
 	}
-	msg, err := b.makeMsg(buf, "", strings.Join(tokens, "."))
+	msg, err := b.makePubMsg(buf, strings.Join(tokens, "."))
 	if err != nil {
 		return err
 	}
@@ -45,3 +49,29 @@ func (b *Service) PublishBufTo(buf []byte, tokens ...string) error {
 	}
 	return nil
 }
+
+// makePubMsg makes a signed message, or a pointer message if the payload exceeds the object threshold.
+func (b *Service) makePubMsg(buf []byte, subject string) (*nats.Msg, error) {
+	if b.ObjectThreshold > 0 && len(buf) > b.ObjectThreshold {
+		return b.makeObjectMsg(buf, "", subject)
+	}
+	return b.makeMsg(buf, "", subject)
+}
+
+// publishBufSync publishes the payload bypassing the publish queue, and flushes the connection,
+// so that the message has reached the server once it returns without an error.
+func (b *Service) publishBufSync(buf []byte, subject string) error {
+	if b.PubNats == nil {
+		return ErrPubConnection
+	}
+	msg, err := b.makePubMsg(buf, subject)
+	if err != nil {
+		return err
+	}
+	if err := b.PubNats.PublishMsg(msg); err != nil {
+		return err
+	}
+	b.msg_out_counter.Add(1)
+	b.bytes_out_counter.Add(uint64(len(msg.Data)))
+	return b.PubNats.Flush()
+}
@@ -3,10 +3,15 @@ package service
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/synternet/data-layer-sdk/x/synternet/rpc"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -15,26 +20,80 @@ func (b *Service) RpcInbox(suffixes ...string) string {
 	return NewSubject(nats.NewInbox(), suffixes...).String()
 }
 
+// ErrorHeader is set on responses that carry rpc.Error instead of the response message. Its value is the status code.
+const ErrorHeader = "rpc-error"
+
+// NewRpcError converts an error into rpc.Error message and the headers that mark the response as an error.
+// Errors created with grpc/status package preserve their codes and details, other errors have codes.Unknown code.
+func NewRpcError(err error) (*rpc.Error, nats.Header) {
+	st := status.Convert(err)
+	if st.Code() == codes.OK {
+		st = status.New(codes.Unknown, st.Message())
+	}
+	header := nats.Header{}
+	header.Set(ErrorHeader, strconv.Itoa(int(st.Code())))
+	return &rpc.Error{
+		Error:   st.Message(),
+		Code:    int32(st.Code()),
+		Details: st.Proto().GetDetails(),
+	}, header
+}
+
+// StatusFromRpcError converts rpc.Error message into a status.
+// Errors without a code are reported with codes.Unknown code.
+func StatusFromRpcError(e *rpc.Error) *status.Status {
+	code := e.GetCode()
+	if code == int32(codes.OK) {
+		code = int32(codes.Unknown)
+	}
+	return status.FromProto(&spb.Status{
+		Code:    code,
+		Message: e.GetError(),
+		Details: e.GetDetails(),
+	})
+}
+
 // Serve is a convenience method to serve a service subject. It acts the same as Subscribe, but takes `ServiceHandler` instead, and will respond
 // either with Error type or response from the handler. Serve will use ReqNats connection.
 func (b *Service) Serve(handler ServiceHandler, suffixes ...string) (*nats.Subscription, error) {
-	return b.subscribeTo(
+	return b.ServeWithHeader(
+		func(msg Message) (proto.Message, nats.Header, error) {
+			resp, err := handler(msg)
+			return resp, nil, err
+		},
+		suffixes...,
+	)
+}
+
+// ServeWithHeader is the same as Serve, but the header returned by the handler will be added to the response.
+// The header is added to both successful and error responses.
+func (b *Service) ServeWithHeader(handler ServiceHeaderHandler, suffixes ...string) (*nats.Subscription, error) {
+	return b.QueueServeWithHeader(handler, "", suffixes...)
+}
+
+// QueueServeWithHeader is the same as ServeWithHeader, but subscribes to the queue group, so that replicas in the same
+// group share the requests. Empty queue name falls back to the QueueName option.
+func (b *Service) QueueServeWithHeader(handler ServiceHeaderHandler, queue string, suffixes ...string) (*nats.Subscription, error) {
+	return b.queueSubscribeTo(
 		b.ReqNats,
 		func(msg Message) {
-			resp, err := handler(msg)
+			resp, header, err := handler(msg)
 			if err != nil {
 				b.Logger.Error("service handler failed", "err", err, "suffixes", suffixes)
-				err1 := msg.Respond(&rpc.Error{Error: err.Error()})
+				rpcErr, errHeader := NewRpcError(err)
+				mergeHeader(errHeader, header)
+				err1 := msg.RespondWithHeader(rpcErr, errHeader)
 				if err1 != nil {
 					b.Logger.Error("service handler failed during error", "err", err, "err1", err1, "suffixes", suffixes)
 				}
 				return
 			}
-			err = msg.Respond(resp)
+			err = msg.RespondWithHeader(resp, header)
 			if err != nil {
 				b.Logger.Error("service handler failed", "err", err, "suffixes", suffixes)
 			}
 		},
+		queue,
 		b.Subject(suffixes...),
 	)
 }
@@ -51,6 +110,21 @@ func (b *Service) PublishToRpc(msg proto.Message, replyTo string, tokens ...stri
 
 // PublishBufToRpc is the same as PublishBufTo, but uses ReqNats.
 func (b *Service) PublishBufToRpc(buf []byte, replyTo string, tokens ...string) error {
+	return b.PublishBufToRpcWithHeader(buf, nil, replyTo, tokens...)
+}
+
+// PublishToRpcWithHeader is the same as PublishToRpc, but will also add the header to the message.
+// Identity and signature headers cannot be overridden.
+func (b *Service) PublishToRpcWithHeader(msg proto.Message, header nats.Header, replyTo string, tokens ...string) error {
+	payload, err := b.Codec.Encode(nil, msg)
+	if err != nil {
+		return err
+	}
+	return b.PublishBufToRpcWithHeader(payload, header, replyTo, tokens...)
+}
+
+// PublishBufToRpcWithHeader is the same as PublishToRpcWithHeader, but for raw bytes.
+func (b *Service) PublishBufToRpcWithHeader(buf []byte, header nats.Header, replyTo string, tokens ...string) error {
 	if b.ReqNats == nil {
 		return ErrPubConnection
 	}
@@ -58,28 +132,45 @@ func (b *Service) PublishBufToRpc(buf []byte, replyTo string, tokens ...string)
 	if err != nil {
 		return err
 	}
+	mergeHeader(msg.Header, header)
 
 	select {
 	case <-b.Context.Done():
-		b.Logger.Info("PublishBufTo cancelled", "err", b.Context.Err(), "queue_size", len(b.publishCh))
+		b.Logger.Info("PublishBufToRpc cancelled", "err", b.Context.Err(), "queue_size", len(b.publishRpcCh))
 		return b.Context.Err()
-	case b.publishCh <- msg:
+	case b.publishRpcCh <- msg:
 	}
 	return nil
 }
 
 // RequestFrom requests a reply from a subject using ReqNats connection. The subject will be constructed from tokens.
 // This a synchronous operation that does not involve publisher queue.
+//
+// If the remote handler failed, the returned error is a grpc/status error carrying the remote code and details.
 func (b *Service) RequestFrom(ctx context.Context, msg proto.Message, resp proto.Message, tokens ...string) (Message, error) {
+	return b.RequestFromWithHeader(ctx, msg, resp, nil, tokens...)
+}
+
+// RequestFromWithHeader is the same as RequestFrom, but will also add the header to the request.
+// Identity and signature headers cannot be overridden.
+func (b *Service) RequestFromWithHeader(ctx context.Context, msg proto.Message, resp proto.Message, header nats.Header, tokens ...string) (Message, error) {
 	payload, err := b.Codec.Encode(nil, msg)
 	if err != nil {
 		return nil, err
 	}
-	response, err := b.RequestBufFrom(ctx, payload, tokens...)
+	response, err := b.RequestBufFromWithHeader(ctx, payload, header, tokens...)
 	if err != nil {
 		return nil, err
 	}
 
+	if response.Header().Get(ErrorHeader) != "" {
+		var rpcErr rpc.Error
+		if _, err := b.Unmarshal(response, &rpcErr); err != nil {
+			return response, fmt.Errorf("unmarshal failed: %w", err)
+		}
+		return response, StatusFromRpcError(&rpcErr).Err()
+	}
+
 	if resp != nil {
 		_, err := b.Unmarshal(response, resp)
 		if err != nil {
@@ -93,6 +184,11 @@ func (b *Service) RequestFrom(ctx context.Context, msg proto.Message, resp proto
 // RequestBufFrom requests a reply from a subject using ReqNats connection.
 // This a synchronous operation that does not involve publisher queue.
 func (b *Service) RequestBufFrom(ctx context.Context, buf []byte, tokens ...string) (Message, error) {
+	return b.RequestBufFromWithHeader(ctx, buf, nil, tokens...)
+}
+
+// RequestBufFromWithHeader is the same as RequestBufFrom, but will also add the header to the request.
+func (b *Service) RequestBufFromWithHeader(ctx context.Context, buf []byte, header nats.Header, tokens ...string) (Message, error) {
 	if b.ReqNats == nil {
 		return nil, ErrReqConnection
 	}
@@ -101,6 +197,7 @@ func (b *Service) RequestBufFrom(ctx context.Context, buf []byte, tokens ...stri
 	if err != nil {
 		return nil, err
 	}
+	mergeHeader(msg.Header, header)
 
 	ret, err := b.ReqNats.RequestMsgWithContext(ctx, msg)
 	if err != nil {
@@ -129,3 +226,151 @@ func (b *Service) RespondBuf(msg Message, buf []byte) error {
 
 	return msg.Message().RespondMsg(reply)
 }
+
+// RequestManyOptions configures RequestMany. Zero options collect responses until the context is done.
+type RequestManyOptions struct {
+	// MaxResponses stops the request once this many responses are received. Zero means no limit.
+	MaxResponses int
+	// StallTimeout stops the request once no response arrives within this duration after the previous one.
+	// The wait for the first response is limited by the context only. Zero means no limit.
+	StallTimeout time.Duration
+	// Sentinel stops the request once it returns true for a response. The sentinel response is not delivered.
+	// See EmptySentinel for the common convention of ending the responses with an empty message.
+	Sentinel func(msg Message) bool
+	// Response is the type of the responses. Every response is decoded into a new message of this type.
+	// Nil Response leaves the payload undecoded.
+	Response proto.Message
+	// Header is added to the request. Identity and signature headers cannot be overridden.
+	Header nats.Header
+}
+
+// Response is a single response received by RequestMany.
+type Response struct {
+	Message
+	// Payload is the decoded response. It is nil if RequestManyOptions.Response is not set or Err is set.
+	Payload proto.Message
+	// Err is set if the response could not be decoded, or if the remote handler failed. In the latter case
+	// it is a grpc/status error carrying the remote code and details.
+	Err error
+}
+
+// EmptySentinel is a RequestManyOptions.Sentinel that stops the request on a response with empty payload.
+func EmptySentinel(msg Message) bool {
+	return len(msg.Data()) == 0 && msg.Header().Get(ErrorHeader) == ""
+}
+
+// RequestMany sends a request to a subject constructed from tokens and collects the responses from all responders,
+// unlike RequestFrom that returns the first response only. It uses ReqNats connection.
+//
+// The responses are delivered on the returned channel, which is closed once the context is done, or one of the stop
+// conditions in the options is met. Responses that fail signature verification are dropped.
+// The caller must either drain the channel or cancel the context.
+func (b *Service) RequestMany(ctx context.Context, msg proto.Message, opts RequestManyOptions, tokens ...string) (<-chan Response, error) {
+	if b.ReqNats == nil {
+		return nil, ErrReqConnection
+	}
+	payload, err := b.Codec.Encode(nil, msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := b.makeMsg(payload, b.RpcInbox(), strings.Join(tokens, "."))
+	if err != nil {
+		return nil, err
+	}
+	mergeHeader(req.Header, opts.Header)
+
+	ctx, cancel := context.WithCancel(ctx)
+	inbox := make(chan *nats.Msg, 64)
+	sub, err := b.ReqNats.Subscribe(req.Reply, func(m *nats.Msg) {
+		select {
+		case <-ctx.Done():
+		case inbox <- m:
+		}
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := b.ReqNats.PublishMsg(req); err != nil {
+		cancel()
+		sub.Unsubscribe()
+		return nil, err
+	}
+
+	out := make(chan Response)
+	go func() {
+		defer close(out)
+		defer sub.Unsubscribe()
+		defer cancel()
+		b.collectResponses(ctx, inbox, out, opts)
+	}()
+	return out, nil
+}
+
+// collectResponses delivers the responses until the context is done or one of the stop conditions is met.
+func (b *Service) collectResponses(ctx context.Context, inbox <-chan *nats.Msg, out chan<- Response, opts RequestManyOptions) {
+	var stall <-chan time.Time
+	received := 0
+	for {
+		var m *nats.Msg
+		select {
+		case <-ctx.Done():
+			return
+		case <-stall:
+			return
+		case m = <-inbox:
+		}
+		// NATS reports that nobody listens on the subject with a status message
+		if len(m.Data) == 0 && m.Header.Get("Status") == "503" {
+			return
+		}
+
+		// Unverified responses neither count as responses nor stop the request, so that forged ones cannot hide the real responders
+		msg := wrapMessage(b.Codec, &b.msg_out_counter, &b.bytes_out_counter, b.makeMsg, m)
+		if err := b.Verify(msg); err != nil {
+			b.Logger.Warn("RequestMany dropped response", "err", err, "subject", m.Subject, "identity", m.Header.Get("identity"))
+			continue
+		}
+		if opts.Sentinel != nil && opts.Sentinel(msg) {
+			return
+		}
+		if opts.StallTimeout > 0 {
+			stall = time.After(opts.StallTimeout)
+		}
+
+		resp := b.decodeResponse(msg, opts.Response)
+		select {
+		case <-ctx.Done():
+			return
+		case out <- resp:
+		}
+		received++
+		if opts.MaxResponses > 0 && received >= opts.MaxResponses {
+			return
+		}
+	}
+}
+
+// decodeResponse decodes the verified response into a new message of the prototype type.
+func (b *Service) decodeResponse(msg Message, prototype proto.Message) Response {
+	resp := Response{Message: msg}
+	if msg.Header().Get(ErrorHeader) != "" {
+		var rpcErr rpc.Error
+		if err := b.Codec.Decode(msg.Data(), &rpcErr); err != nil {
+			resp.Err = fmt.Errorf("unmarshal failed: %w", err)
+			return resp
+		}
+		resp.Err = StatusFromRpcError(&rpcErr).Err()
+		return resp
+	}
+	if prototype == nil {
+		return resp
+	}
+	payload := prototype.ProtoReflect().New().Interface()
+	if err := b.Codec.Decode(msg.Data(), payload); err != nil {
+		resp.Err = fmt.Errorf("unmarshal failed: %w", err)
+		return resp
+	}
+	resp.Payload = payload
+	return resp
+}
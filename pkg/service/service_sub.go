@@ -21,7 +21,17 @@ func (b *Service) SubscribeTo(handler MessageHandler, tokens ...string) (*nats.S
 	return b.subscribeTo(b.SubNats, handler, tokens...)
 }
 
+// QueueSubscribeTo is the same as SubscribeTo, but subscribes to the queue group. Every message is delivered to only
+// one subscriber in the group. Empty queue name falls back to the QueueName option.
+func (b *Service) QueueSubscribeTo(handler MessageHandler, queue string, tokens ...string) (*nats.Subscription, error) {
+	return b.queueSubscribeTo(b.SubNats, handler, queue, tokens...)
+}
+
 func (b *Service) subscribeTo(nc options.NatsConn, handler MessageHandler, tokens ...string) (*nats.Subscription, error) {
+	return b.queueSubscribeTo(nc, handler, "", tokens...)
+}
+
+func (b *Service) queueSubscribeTo(nc options.NatsConn, handler MessageHandler, queue string, tokens ...string) (*nats.Subscription, error) {
 	if nc == nil {
 		return nil, ErrSubConnection
 	}
@@ -32,6 +42,7 @@ func (b *Service) subscribeTo(nc options.NatsConn, handler MessageHandler, token
 		b.msg_in_counter.Add(1)
 		b.bytes_in_counter.Add(uint64(len(msg.Data)))
 		wrapped := wrapMessage(b.Codec, &b.msg_out_counter, &b.bytes_out_counter, b.makeMsg, msg)
+		b.resolveObject(wrapped)
 		handler(wrapped)
 	}
 
@@ -41,8 +52,11 @@ func (b *Service) subscribeTo(nc options.NatsConn, handler MessageHandler, token
 		return sub, nil
 	}
 
-	if b.QueueName != "" {
-		return nc.QueueSubscribe(subject, b.QueueName, natsHandler)
+	if queue == "" {
+		queue = b.QueueName
+	}
+	if queue != "" {
+		return nc.QueueSubscribe(subject, queue, natsHandler)
 	}
 	return nc.Subscribe(subject, natsHandler)
 }
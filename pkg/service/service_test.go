@@ -1,10 +1,22 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/synternet/data-layer-sdk/pkg/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 const (
@@ -209,3 +221,244 @@ func TestBase_Verify(t *testing.T) {
 		})
 	}
 }
+
+func TestService_PublishToRpcWithHeader(t *testing.T) {
+	b, _ := makeJetStreamService(t)
+
+	header := nats.Header{
+		"identity": {"forged"},
+		"rpc-seq":  {"1"},
+	}
+	err := b.PublishBufToRpcWithHeader([]byte("lore ipsum"), header, "_INBOX.reply", "foo", "bar")
+	if err != nil {
+		t.Fatal("failure: ", err.Error())
+	}
+	if len(b.publishCh) != 0 {
+		t.Error("rpc message published to a publish queue")
+	}
+
+	msg := <-b.publishRpcCh
+	if msg.Header.Get("rpc-seq") != "1" {
+		t.Errorf("header not set: %v", msg.Header)
+	}
+	if msg.Header.Get("identity") != b.Identity {
+		t.Errorf("identity overridden: %v", msg.Header)
+	}
+	if msg.Reply != "_INBOX.reply" || msg.Subject != "foo.bar" {
+		t.Errorf("wrong subjects: %s %s", msg.Subject, msg.Reply)
+	}
+}
+
+func TestNewRpcError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{"plain error", errors.New("failed"), codes.Unknown, "failed"},
+		{"status error", status.Error(codes.NotFound, "missing"), codes.NotFound, "missing"},
+		{"wrapped status error", fmt.Errorf("wrapped: %w", status.Error(codes.Aborted, "aborted")), codes.Aborted, "wrapped: rpc error: code = Aborted desc = aborted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpcErr, header := NewRpcError(tt.err)
+			if header.Get(ErrorHeader) != strconv.Itoa(int(tt.code)) {
+				t.Errorf("error header = %v, want %v", header.Get(ErrorHeader), tt.code)
+			}
+			st := StatusFromRpcError(rpcErr)
+			if st.Code() != tt.code || st.Message() != tt.message {
+				t.Errorf("StatusFromRpcError() = %v, want %v %v", st, tt.code, tt.message)
+			}
+		})
+	}
+}
+
+// queueConn records subscriptions and their queue groups.
+type queueConn struct {
+	options.NatsConn
+	queues map[string]string
+}
+
+func (c *queueConn) Subscribe(subj string, cb nats.MsgHandler) (*nats.Subscription, error) {
+	c.queues[subj] = ""
+	return &nats.Subscription{}, nil
+}
+
+func (c *queueConn) QueueSubscribe(subj, queue string, cb nats.MsgHandler) (*nats.Subscription, error) {
+	c.queues[subj] = queue
+	return &nats.Subscription{}, nil
+}
+
+func TestService_QueueServeWithHeader(t *testing.T) {
+	conn := &queueConn{queues: make(map[string]string)}
+	b := &Service{}
+	err := b.Configure(
+		WithName("bar"),
+		WithPrefix("foo"),
+		WithNKeySeed(testSeed),
+		WithNats(conn),
+	)
+	if err != nil {
+		t.Fatal("failure: ", err.Error())
+	}
+	handler := func(msg Message) (proto.Message, nats.Header, error) { return nil, nil, nil }
+
+	tests := []struct {
+		name        string
+		globalQueue string
+		queue       string
+		want        string
+	}{
+		{"no queue", "", "", ""},
+		{"queue", "", "workers", "workers"},
+		{"global queue", "global", "", "global"},
+		{"override global queue", "global", "workers", "workers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.QueueName = tt.globalQueue
+			if _, err := b.QueueServeWithHeader(handler, tt.queue, "method"); err != nil {
+				t.Fatal("failure: ", err.Error())
+			}
+			if got := conn.queues["foo.bar.method"]; got != tt.want {
+				t.Errorf("QueueServeWithHeader() queue = %q, want %q", got, tt.want)
+			}
+			if _, err := b.QueueSubscribeTo(func(Message) {}, tt.queue, "foo", "stream"); err != nil {
+				t.Fatal("failure: ", err.Error())
+			}
+			if got := conn.queues["foo.stream"]; got != tt.want {
+				t.Errorf("QueueSubscribeTo() queue = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// requestManyConn delivers every request to the responders, and their responses to the inbox subscription.
+type requestManyConn struct {
+	options.NatsConn
+	mu        sync.Mutex
+	inboxes   map[string]nats.MsgHandler
+	responder func(req *nats.Msg) []*nats.Msg
+	delay     time.Duration
+}
+
+func (c *requestManyConn) Subscribe(subj string, cb nats.MsgHandler) (*nats.Subscription, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inboxes[subj] = cb
+	return &nats.Subscription{}, nil
+}
+
+func (c *requestManyConn) PublishMsg(m *nats.Msg) error {
+	c.mu.Lock()
+	cb := c.inboxes[m.Reply]
+	c.mu.Unlock()
+	go func() {
+		for i, resp := range c.responder(m) {
+			if i > 0 {
+				time.Sleep(c.delay)
+			}
+			resp.Subject = m.Reply
+			cb(resp)
+		}
+	}()
+	return nil
+}
+
+func TestService_RequestMany(t *testing.T) {
+	responder := &Service{}
+	if err := responder.Configure(WithName("responder"), WithPrefix("foo"), WithNKeySeed(testSeed)); err != nil {
+		t.Fatal("failure: ", err.Error())
+	}
+	reply := func(value string) *nats.Msg {
+		payload, _ := responder.Codec.Encode(nil, wrapperspb.String(value))
+		msg, err := responder.makeMsg(payload, "", "")
+		if err != nil {
+			t.Fatal("failure: ", err.Error())
+		}
+		return msg
+	}
+	empty := func() *nats.Msg {
+		msg, _ := responder.makeMsg(nil, "", "")
+		return msg
+	}
+	tampered := func() *nats.Msg {
+		msg := reply("a")
+		msg.Data, _ = responder.Codec.Encode(nil, wrapperspb.String("tampered"))
+		return msg
+	}
+	remoteError := func() *nats.Msg {
+		rpcErr, header := NewRpcError(status.Error(codes.NotFound, "no block"))
+		payload, _ := responder.Codec.Encode(nil, rpcErr)
+		msg, _ := responder.makeMsg(payload, "", "")
+		mergeHeader(msg.Header, header)
+		return msg
+	}
+	forgedEmpty := func() *nats.Msg {
+		return &nats.Msg{Header: nats.Header{"identity": []string{responder.Identity}}}
+	}
+	noResponders := func() *nats.Msg {
+		return &nats.Msg{Header: nats.Header{"Status": []string{"503"}}}
+	}
+
+	tests := []struct {
+		name      string
+		responses []*nats.Msg
+		delay     time.Duration
+		opts      RequestManyOptions
+		want      []string
+		wantCodes []codes.Code
+	}{
+		{"all responders", []*nats.Msg{reply("a"), reply("b"), reply("c")}, 0, RequestManyOptions{}, []string{"a", "b", "c"}, nil},
+		{"max responses", []*nats.Msg{reply("a"), reply("b"), reply("c")}, 0, RequestManyOptions{MaxResponses: 2}, []string{"a", "b"}, nil},
+		{"stall timeout", []*nats.Msg{reply("a"), reply("b")}, time.Millisecond * 100, RequestManyOptions{StallTimeout: time.Millisecond * 20}, []string{"a"}, nil},
+		{"sentinel", []*nats.Msg{reply("a"), empty(), reply("c")}, 0, RequestManyOptions{Sentinel: EmptySentinel}, []string{"a"}, nil},
+		{"unverified sentinel ignored", []*nats.Msg{reply("a"), forgedEmpty(), reply("c")}, 0, RequestManyOptions{Sentinel: EmptySentinel, MaxResponses: 2}, []string{"a", "c"}, nil},
+		{"no responders", []*nats.Msg{noResponders()}, 0, RequestManyOptions{}, nil, nil},
+		{"unverified dropped", []*nats.Msg{tampered(), reply("b")}, 0, RequestManyOptions{}, []string{"b"}, nil},
+		{"remote error", []*nats.Msg{remoteError(), reply("b")}, 0, RequestManyOptions{}, []string{"b"}, []codes.Code{codes.NotFound}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+			defer cancel()
+
+			conn := &requestManyConn{
+				inboxes:   make(map[string]nats.MsgHandler),
+				responder: func(*nats.Msg) []*nats.Msg { return tt.responses },
+				delay:     tt.delay,
+			}
+			b := &Service{}
+			if err := b.Configure(WithName("bar"), WithPrefix("foo"), WithNats(conn), WithKnownIdentities(responder.Identity)); err != nil {
+				t.Fatal("failure: ", err.Error())
+			}
+			tt.opts.Response = &wrapperspb.StringValue{}
+
+			start := time.Now()
+			responses, err := b.RequestMany(ctx, wrapperspb.String("block"), tt.opts, "foo", "query")
+			if err != nil {
+				t.Fatal("failure: ", err.Error())
+			}
+			var got []string
+			var gotCodes []codes.Code
+			for resp := range responses {
+				if resp.Err != nil {
+					gotCodes = append(gotCodes, status.Code(resp.Err))
+					continue
+				}
+				got = append(got, resp.Payload.(*wrapperspb.StringValue).GetValue())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("RequestMany() = %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(gotCodes, tt.wantCodes) {
+				t.Errorf("RequestMany() codes = %v, want %v", gotCodes, tt.wantCodes)
+			}
+			stopsEarly := tt.opts.MaxResponses > 0 || tt.opts.StallTimeout > 0 || tt.opts.Sentinel != nil || tt.want == nil
+			if elapsed := time.Since(start); stopsEarly && elapsed > time.Millisecond*200 {
+				t.Errorf("RequestMany() took %v, want to stop before the deadline", elapsed)
+			}
+		})
+	}
+}
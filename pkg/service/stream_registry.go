@@ -0,0 +1,138 @@
+package service
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/nats-io/nats.go"
+)
+
+type jsStream struct {
+	id           int
+	subjects     []string
+	cfgStream    *nats.StreamConfig
+	cfgConsumer  *nats.ConsumerConfig
+	streamInfo   *nats.StreamInfo
+	consumerInfo *nats.ConsumerInfo
+}
+
+// StreamInfo describes a durable stream registered with AddStream. The infos are copies owned by the caller.
+type StreamInfo struct {
+	ID       int
+	Subjects []string
+	Stream   *nats.StreamInfo
+	Consumer *nats.ConsumerInfo
+}
+
+func (s *jsStream) describe() StreamInfo {
+	return StreamInfo{
+		ID:       s.id,
+		Subjects: slices.Clone(s.subjects),
+		Stream:   cloneInfo(s.streamInfo),
+		Consumer: cloneInfo(s.consumerInfo),
+	}
+}
+
+// cloneInfo returns a deep copy of a JetStream API info through its JSON representation,
+// so that callers cannot modify the infos kept by the registry. The infos are received as JSON,
+// so the round trip does not fail in practice; a shallow copy is returned if it does.
+func cloneInfo[T any](info *T) *T {
+	if info == nil {
+		return nil
+	}
+	var clone T
+	data, err := json.Marshal(info)
+	if err == nil {
+		err = json.Unmarshal(data, &clone)
+	}
+	if err != nil {
+		clone = *info
+	}
+	return &clone
+}
+
+// streamRegistry keeps track of durable streams registered with AddStream.
+// Streams are identified by stable IDs so that removing a stream does not invalidate other streams.
+type streamRegistry struct {
+	nextId   int
+	streams  map[int]*jsStream
+	subjects SubjectMap
+}
+
+func newStreamRegistry() *streamRegistry {
+	return &streamRegistry{
+		streams:  make(map[int]*jsStream),
+		subjects: make(SubjectMap),
+	}
+}
+
+// add registers a stream under all of its subjects and returns its ID.
+func (r *streamRegistry) add(stream *jsStream) (int, error) {
+	for _, s := range stream.subjects {
+		if err := Subject(s).Validate(); err != nil {
+			return 0, err
+		}
+	}
+
+	id := r.nextId
+	r.nextId++
+	stream.id = id
+	r.streams[id] = stream
+	for _, s := range stream.subjects {
+		r.subjects.Add(Subject(s), id)
+	}
+	return id, nil
+}
+
+// remove unregisters streams that were registered with any of the subjects.
+// All subjects of those streams are removed. It returns the removed streams.
+func (r *streamRegistry) remove(subjects ...string) []*jsStream {
+	var removed []*jsStream
+	for _, subject := range subjects {
+		id, ok := r.subjects.Get(Subject(subject))
+		if !ok {
+			continue
+		}
+		stream, ok := r.streams[id]
+		if !ok {
+			continue
+		}
+		for _, s := range stream.subjects {
+			delete(r.subjects, Subject(s))
+		}
+		delete(r.streams, id)
+		removed = append(removed, stream)
+	}
+	return removed
+}
+
+// get returns a stream by its ID.
+func (r *streamRegistry) get(id int) (*jsStream, bool) {
+	stream, ok := r.streams[id]
+	return stream, ok
+}
+
+// search returns a stream that captures the subject.
+func (r *streamRegistry) search(subject string) (*jsStream, bool) {
+	_, id, ok := r.subjects.Search(Subject(subject))
+	if !ok {
+		return nil, false
+	}
+	return r.get(id)
+}
+
+// overlaps returns a registered subject that symmetrically matches the subject.
+func (r *streamRegistry) overlaps(subject string) (Subject, bool) {
+	match, _, found := r.subjects.SymmetricSearch(Subject(subject))
+	return match, found
+}
+
+// list returns all registered streams ordered by their IDs.
+func (r *streamRegistry) list() []*jsStream {
+	result := make([]*jsStream, 0, len(r.streams))
+	for _, stream := range r.streams {
+		result = append(result, stream)
+	}
+	slices.SortFunc(result, func(a, b *jsStream) int { return a.id - b.id })
+	return result
+}
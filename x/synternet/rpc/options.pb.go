@@ -11,6 +11,7 @@ import (
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
 	reflect "reflect"
+	sync "sync"
 	unsafe "unsafe"
 )
 
@@ -21,6 +22,138 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Type of a subject variable.
+type VariableType int32
+
+const (
+	// Any single subject token, the same as VARIABLE_TYPE_STRING.
+	VariableType_VARIABLE_TYPE_UNSPECIFIED VariableType = 0
+	// Any single subject token.
+	VariableType_VARIABLE_TYPE_STRING VariableType = 1
+	// Signed decimal integer.
+	VariableType_VARIABLE_TYPE_INT VariableType = 2
+	// Unsigned decimal integer.
+	VariableType_VARIABLE_TYPE_UINT VariableType = 3
+	// Hexadecimal string with an optional 0x prefix.
+	VariableType_VARIABLE_TYPE_HEX VariableType = 4
+)
+
+// Enum value maps for VariableType.
+var (
+	VariableType_name = map[int32]string{
+		0: "VARIABLE_TYPE_UNSPECIFIED",
+		1: "VARIABLE_TYPE_STRING",
+		2: "VARIABLE_TYPE_INT",
+		3: "VARIABLE_TYPE_UINT",
+		4: "VARIABLE_TYPE_HEX",
+	}
+	VariableType_value = map[string]int32{
+		"VARIABLE_TYPE_UNSPECIFIED": 0,
+		"VARIABLE_TYPE_STRING":      1,
+		"VARIABLE_TYPE_INT":         2,
+		"VARIABLE_TYPE_UINT":        3,
+		"VARIABLE_TYPE_HEX":         4,
+	}
+)
+
+func (x VariableType) Enum() *VariableType {
+	p := new(VariableType)
+	*p = x
+	return p
+}
+
+func (x VariableType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (VariableType) Descriptor() protoreflect.EnumDescriptor {
+	return file_synternet_rpc_options_proto_enumTypes[0].Descriptor()
+}
+
+func (VariableType) Type() protoreflect.EnumType {
+	return &file_synternet_rpc_options_proto_enumTypes[0]
+}
+
+func (x VariableType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use VariableType.Descriptor instead.
+func (VariableType) EnumDescriptor() ([]byte, []int) {
+	return file_synternet_rpc_options_proto_rawDescGZIP(), []int{0}
+}
+
+// Schema of a subject variable, e.g. `{id}`. Values of variables are validated against the schema.
+type SubjectVariable struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the variable without braces.
+	Name string       `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type VariableType `protobuf:"varint,2,opt,name=type,proto3,enum=synternet.rpc.VariableType" json:"type,omitempty"`
+	// Optional regular expression that the whole value must match.
+	Pattern       string `protobuf:"bytes,3,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	Description   string `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubjectVariable) Reset() {
+	*x = SubjectVariable{}
+	mi := &file_synternet_rpc_options_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubjectVariable) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubjectVariable) ProtoMessage() {}
+
+func (x *SubjectVariable) ProtoReflect() protoreflect.Message {
+	mi := &file_synternet_rpc_options_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubjectVariable.ProtoReflect.Descriptor instead.
+func (*SubjectVariable) Descriptor() ([]byte, []int) {
+	return file_synternet_rpc_options_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubjectVariable) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SubjectVariable) GetType() VariableType {
+	if x != nil {
+		return x.Type
+	}
+	return VariableType_VARIABLE_TYPE_UNSPECIFIED
+}
+
+func (x *SubjectVariable) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *SubjectVariable) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
 var file_synternet_rpc_options_proto_extTypes = []protoimpl.ExtensionInfo{
 	{
 		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
@@ -46,6 +179,46 @@ var file_synternet_rpc_options_proto_extTypes = []protoimpl.ExtensionInfo{
 		Tag:           "varint,50003,opt,name=disable_inputs",
 		Filename:      "synternet/rpc/options.proto",
 	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50004,
+		Name:          "synternet.rpc.queue_group",
+		Tag:           "bytes,50004,opt,name=queue_group",
+		Filename:      "synternet/rpc/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50005,
+		Name:          "synternet.rpc.method_queue_group",
+		Tag:           "bytes,50005,opt,name=method_queue_group",
+		Filename:      "synternet/rpc/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: ([]*SubjectVariable)(nil),
+		Field:         50006,
+		Name:          "synternet.rpc.subject_variables",
+		Tag:           "bytes,50006,rep,name=subject_variables",
+		Filename:      "synternet/rpc/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: ([]*SubjectVariable)(nil),
+		Field:         50007,
+		Name:          "synternet.rpc.method_subject_variables",
+		Tag:           "bytes,50007,rep,name=method_subject_variables",
+		Filename:      "synternet/rpc/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50008,
+		Name:          "synternet.rpc.bind_variable",
+		Tag:           "bytes,50008,opt,name=bind_variable",
+		Filename:      "synternet/rpc/options.proto",
+	},
 }
 
 // Extension fields to descriptorpb.ServiceOptions.
@@ -54,6 +227,14 @@ var (
 	//
 	// optional string subject_prefix = 50001;
 	E_SubjectPrefix = &file_synternet_rpc_options_proto_extTypes[0]
+	// Optional queue group for all methods in a service. Replicas in the same queue group share the requests.
+	//
+	// optional string queue_group = 50004;
+	E_QueueGroup = &file_synternet_rpc_options_proto_extTypes[3]
+	// Optional schemas of the variables used in the subjects of a service.
+	//
+	// repeated synternet.rpc.SubjectVariable subject_variables = 50006;
+	E_SubjectVariables = &file_synternet_rpc_options_proto_extTypes[5]
 )
 
 // Extension fields to descriptorpb.MethodOptions.
@@ -66,6 +247,24 @@ var (
 	//
 	// optional bool disable_inputs = 50003;
 	E_DisableInputs = &file_synternet_rpc_options_proto_extTypes[2]
+	// Optional queue group of a method that overrides the service queue group.
+	//
+	// optional string method_queue_group = 50005;
+	E_MethodQueueGroup = &file_synternet_rpc_options_proto_extTypes[4]
+	// Optional schemas of the variables used in the subject of a method. They override the service schemas.
+	//
+	// repeated synternet.rpc.SubjectVariable method_subject_variables = 50007;
+	E_MethodSubjectVariables = &file_synternet_rpc_options_proto_extTypes[6]
+)
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// Optional name of the subject variable bound to a request field. Unary calls take the value of the variable
+	// from the field, if the field is set. The server sets the field to the value from the subject, and rejects
+	// the request if the field is set to a different value. Only string and integer fields can be bound.
+	//
+	// optional string bind_variable = 50008;
+	E_BindVariable = &file_synternet_rpc_options_proto_extTypes[7]
 )
 
 var File_synternet_rpc_options_proto protoreflect.FileDescriptor
@@ -75,47 +274,119 @@ var file_synternet_rpc_options_proto_rawDesc = string([]byte{
 	0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0d, 0x73,
 	0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x72, 0x70, 0x63, 0x1a, 0x20, 0x67, 0x6f,
 	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65,
-	0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3a, 0x48,
-	0x0a, 0x0e, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78,
-	0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
-	0x75, 0x66, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e,
-	0x73, 0x18, 0xd1, 0x86, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x75, 0x62, 0x6a, 0x65,
-	0x63, 0x74, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x3a, 0x47, 0x0a, 0x0e, 0x73, 0x75, 0x62, 0x6a,
-	0x65, 0x63, 0x74, 0x5f, 0x73, 0x75, 0x66, 0x66, 0x69, 0x78, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74,
-	0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd2, 0x86, 0x03, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x0d, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x53, 0x75, 0x66, 0x66, 0x69,
-	0x78, 0x3a, 0x47, 0x0a, 0x0e, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x69, 0x6e, 0x70,
-	0x75, 0x74, 0x73, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69,
-	0x6f, 0x6e, 0x73, 0x18, 0xd3, 0x86, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x64, 0x69, 0x73,
-	0x61, 0x62, 0x6c, 0x65, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x42, 0xab, 0x01, 0x0a, 0x11, 0x63,
-	0x6f, 0x6d, 0x2e, 0x73, 0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x72, 0x70, 0x63,
-	0x42, 0x0c, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01,
-	0x5a, 0x33, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x79, 0x6e,
-	0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x2d, 0x6c, 0x61, 0x79, 0x65,
-	0x72, 0x2d, 0x73, 0x64, 0x6b, 0x2f, 0x78, 0x2f, 0x73, 0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65,
-	0x74, 0x2f, 0x72, 0x70, 0x63, 0xa2, 0x02, 0x03, 0x53, 0x52, 0x58, 0xaa, 0x02, 0x0d, 0x53, 0x79,
-	0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x52, 0x70, 0x63, 0xca, 0x02, 0x0d, 0x53, 0x79,
-	0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x5c, 0x52, 0x70, 0x63, 0xe2, 0x02, 0x19, 0x53, 0x79,
-	0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x5c, 0x52, 0x70, 0x63, 0x5c, 0x47, 0x50, 0x42, 0x4d,
-	0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x0e, 0x53, 0x79, 0x6e, 0x74, 0x65, 0x72,
-	0x6e, 0x65, 0x74, 0x3a, 0x3a, 0x52, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x92,
+	0x01, 0x0a, 0x0f, 0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62,
+	0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x2f, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x1b, 0x2e, 0x73, 0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74,
+	0x2e, 0x72, 0x70, 0x63, 0x2e, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x54, 0x79, 0x70,
+	0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x74, 0x74, 0x65,
+	0x72, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x61, 0x74, 0x74, 0x65, 0x72,
+	0x6e, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x2a, 0x8d, 0x01, 0x0a, 0x0c, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65,
+	0x54, 0x79, 0x70, 0x65, 0x12, 0x1d, 0x0a, 0x19, 0x56, 0x41, 0x52, 0x49, 0x41, 0x42, 0x4c, 0x45,
+	0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45,
+	0x44, 0x10, 0x00, 0x12, 0x18, 0x0a, 0x14, 0x56, 0x41, 0x52, 0x49, 0x41, 0x42, 0x4c, 0x45, 0x5f,
+	0x54, 0x59, 0x50, 0x45, 0x5f, 0x53, 0x54, 0x52, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x15, 0x0a,
+	0x11, 0x56, 0x41, 0x52, 0x49, 0x41, 0x42, 0x4c, 0x45, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x49,
+	0x4e, 0x54, 0x10, 0x02, 0x12, 0x16, 0x0a, 0x12, 0x56, 0x41, 0x52, 0x49, 0x41, 0x42, 0x4c, 0x45,
+	0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x49, 0x4e, 0x54, 0x10, 0x03, 0x12, 0x15, 0x0a, 0x11,
+	0x56, 0x41, 0x52, 0x49, 0x41, 0x42, 0x4c, 0x45, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x48, 0x45,
+	0x58, 0x10, 0x04, 0x3a, 0x48, 0x0a, 0x0e, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x70,
+	0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd1, 0x86, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
+	0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x3a, 0x47, 0x0a,
+	0x0e, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x73, 0x75, 0x66, 0x66, 0x69, 0x78, 0x12,
+	0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
+	0xd2, 0x86, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74,
+	0x53, 0x75, 0x66, 0x66, 0x69, 0x78, 0x3a, 0x47, 0x0a, 0x0e, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c,
+	0x65, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f,
+	0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd3, 0x86, 0x03, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0d, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x3a,
+	0x42, 0x0a, 0x0b, 0x71, 0x75, 0x65, 0x75, 0x65, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x1f,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
+	0xd4, 0x86, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x71, 0x75, 0x65, 0x75, 0x65, 0x47, 0x72,
+	0x6f, 0x75, 0x70, 0x3a, 0x4e, 0x0a, 0x12, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x5f, 0x71, 0x75,
+	0x65, 0x75, 0x65, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68,
+	0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd5, 0x86, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x10, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x51, 0x75, 0x65, 0x75, 0x65, 0x47, 0x72,
+	0x6f, 0x75, 0x70, 0x3a, 0x6e, 0x0a, 0x11, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x76,
+	0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd6, 0x86, 0x03, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1e, 0x2e, 0x73, 0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x72, 0x70,
+	0x63, 0x2e, 0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c,
+	0x65, 0x52, 0x10, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62,
+	0x6c, 0x65, 0x73, 0x3a, 0x7a, 0x0a, 0x18, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x5f, 0x73, 0x75,
+	0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x12,
+	0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
+	0xd7, 0x86, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x73, 0x79, 0x6e, 0x74, 0x65, 0x72,
+	0x6e, 0x65, 0x74, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x56,
+	0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x16, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x53,
+	0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x56, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x3a,
+	0x44, 0x0a, 0x0d, 0x62, 0x69, 0x6e, 0x64, 0x5f, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65,
+	0x12, 0x1d, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
+	0xd8, 0x86, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x62, 0x69, 0x6e, 0x64, 0x56, 0x61, 0x72,
+	0x69, 0x61, 0x62, 0x6c, 0x65, 0x42, 0xab, 0x01, 0x0a, 0x11, 0x63, 0x6f, 0x6d, 0x2e, 0x73, 0x79,
+	0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x72, 0x70, 0x63, 0x42, 0x0c, 0x4f, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x33, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65,
+	0x74, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x2d, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x2d, 0x73, 0x64, 0x6b,
+	0x2f, 0x78, 0x2f, 0x73, 0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2f, 0x72, 0x70, 0x63,
+	0xa2, 0x02, 0x03, 0x53, 0x52, 0x58, 0xaa, 0x02, 0x0d, 0x53, 0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e,
+	0x65, 0x74, 0x2e, 0x52, 0x70, 0x63, 0xca, 0x02, 0x0d, 0x53, 0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e,
+	0x65, 0x74, 0x5c, 0x52, 0x70, 0x63, 0xe2, 0x02, 0x19, 0x53, 0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e,
+	0x65, 0x74, 0x5c, 0x52, 0x70, 0x63, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61,
+	0x74, 0x61, 0xea, 0x02, 0x0e, 0x53, 0x79, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x3a, 0x3a,
+	0x52, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 })
 
+var (
+	file_synternet_rpc_options_proto_rawDescOnce sync.Once
+	file_synternet_rpc_options_proto_rawDescData []byte
+)
+
+func file_synternet_rpc_options_proto_rawDescGZIP() []byte {
+	file_synternet_rpc_options_proto_rawDescOnce.Do(func() {
+		file_synternet_rpc_options_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_synternet_rpc_options_proto_rawDesc), len(file_synternet_rpc_options_proto_rawDesc)))
+	})
+	return file_synternet_rpc_options_proto_rawDescData
+}
+
+var file_synternet_rpc_options_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_synternet_rpc_options_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
 var file_synternet_rpc_options_proto_goTypes = []any{
-	(*descriptorpb.ServiceOptions)(nil), // 0: google.protobuf.ServiceOptions
-	(*descriptorpb.MethodOptions)(nil),  // 1: google.protobuf.MethodOptions
+	(VariableType)(0),                   // 0: synternet.rpc.VariableType
+	(*SubjectVariable)(nil),             // 1: synternet.rpc.SubjectVariable
+	(*descriptorpb.ServiceOptions)(nil), // 2: google.protobuf.ServiceOptions
+	(*descriptorpb.MethodOptions)(nil),  // 3: google.protobuf.MethodOptions
+	(*descriptorpb.FieldOptions)(nil),   // 4: google.protobuf.FieldOptions
 }
 var file_synternet_rpc_options_proto_depIdxs = []int32{
-	0, // 0: synternet.rpc.subject_prefix:extendee -> google.protobuf.ServiceOptions
-	1, // 1: synternet.rpc.subject_suffix:extendee -> google.protobuf.MethodOptions
-	1, // 2: synternet.rpc.disable_inputs:extendee -> google.protobuf.MethodOptions
-	3, // [3:3] is the sub-list for method output_type
-	3, // [3:3] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	0, // [0:3] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: synternet.rpc.SubjectVariable.type:type_name -> synternet.rpc.VariableType
+	2,  // 1: synternet.rpc.subject_prefix:extendee -> google.protobuf.ServiceOptions
+	3,  // 2: synternet.rpc.subject_suffix:extendee -> google.protobuf.MethodOptions
+	3,  // 3: synternet.rpc.disable_inputs:extendee -> google.protobuf.MethodOptions
+	2,  // 4: synternet.rpc.queue_group:extendee -> google.protobuf.ServiceOptions
+	3,  // 5: synternet.rpc.method_queue_group:extendee -> google.protobuf.MethodOptions
+	2,  // 6: synternet.rpc.subject_variables:extendee -> google.protobuf.ServiceOptions
+	3,  // 7: synternet.rpc.method_subject_variables:extendee -> google.protobuf.MethodOptions
+	4,  // 8: synternet.rpc.bind_variable:extendee -> google.protobuf.FieldOptions
+	1,  // 9: synternet.rpc.subject_variables:type_name -> synternet.rpc.SubjectVariable
+	1,  // 10: synternet.rpc.method_subject_variables:type_name -> synternet.rpc.SubjectVariable
+	11, // [11:11] is the sub-list for method output_type
+	11, // [11:11] is the sub-list for method input_type
+	9,  // [9:11] is the sub-list for extension type_name
+	1,  // [1:9] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_synternet_rpc_options_proto_init() }
@@ -128,13 +399,15 @@ func file_synternet_rpc_options_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_synternet_rpc_options_proto_rawDesc), len(file_synternet_rpc_options_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   0,
-			NumExtensions: 3,
+			NumEnums:      1,
+			NumMessages:   1,
+			NumExtensions: 8,
 			NumServices:   0,
 		},
 		GoTypes:           file_synternet_rpc_options_proto_goTypes,
 		DependencyIndexes: file_synternet_rpc_options_proto_depIdxs,
+		EnumInfos:         file_synternet_rpc_options_proto_enumTypes,
+		MessageInfos:      file_synternet_rpc_options_proto_msgTypes,
 		ExtensionInfos:    file_synternet_rpc_options_proto_extTypes,
 	}.Build()
 	File_synternet_rpc_options_proto = out.File
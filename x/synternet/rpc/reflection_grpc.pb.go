@@ -0,0 +1,125 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: synternet/rpc/reflection.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Reflection_ListServices_FullMethodName = "/synternet.rpc.Reflection/ListServices"
+)
+
+// ReflectionClient is the client API for Reflection service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Reflection describes the services served by a publisher. It is registered automatically by the service registrar.
+type ReflectionClient interface {
+	// ListServices returns the served services, their methods and subjects, together with the file descriptors.
+	ListServices(ctx context.Context, in *ListServicesRequest, opts ...grpc.CallOption) (*ListServicesResponse, error)
+}
+
+type reflectionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReflectionClient(cc grpc.ClientConnInterface) ReflectionClient {
+	return &reflectionClient{cc}
+}
+
+func (c *reflectionClient) ListServices(ctx context.Context, in *ListServicesRequest, opts ...grpc.CallOption) (*ListServicesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListServicesResponse)
+	err := c.cc.Invoke(ctx, Reflection_ListServices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReflectionServer is the server API for Reflection service.
+// All implementations should embed UnimplementedReflectionServer
+// for forward compatibility.
+//
+// Reflection describes the services served by a publisher. It is registered automatically by the service registrar.
+type ReflectionServer interface {
+	// ListServices returns the served services, their methods and subjects, together with the file descriptors.
+	ListServices(context.Context, *ListServicesRequest) (*ListServicesResponse, error)
+}
+
+// UnimplementedReflectionServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedReflectionServer struct{}
+
+func (UnimplementedReflectionServer) ListServices(context.Context, *ListServicesRequest) (*ListServicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServices not implemented")
+}
+func (UnimplementedReflectionServer) testEmbeddedByValue() {}
+
+// UnsafeReflectionServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReflectionServer will
+// result in compilation errors.
+type UnsafeReflectionServer interface {
+	mustEmbedUnimplementedReflectionServer()
+}
+
+func RegisterReflectionServer(s grpc.ServiceRegistrar, srv ReflectionServer) {
+	// If the following call pancis, it indicates UnimplementedReflectionServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Reflection_ServiceDesc, srv)
+}
+
+func _Reflection_ListServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReflectionServer).ListServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reflection_ListServices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReflectionServer).ListServices(ctx, req.(*ListServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Reflection_ServiceDesc is the grpc.ServiceDesc for Reflection service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Reflection_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "synternet.rpc.Reflection",
+	HandlerType: (*ReflectionServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListServices",
+			Handler:    _Reflection_ListServices_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "synternet/rpc/reflection.proto",
+}
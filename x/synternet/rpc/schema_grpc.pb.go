@@ -0,0 +1,127 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: synternet/rpc/schema.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SchemaRegistry_ListSchemas_FullMethodName = "/synternet.rpc.SchemaRegistry/ListSchemas"
+)
+
+// SchemaRegistryClient is the client API for SchemaRegistry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SchemaRegistry advertises the types of the messages a publisher publishes on its subjects, so that subscribers
+// can decode them without compiling the Protobuf files in.
+type SchemaRegistryClient interface {
+	// ListSchemas returns the schemas of the subjects matching the request.
+	ListSchemas(ctx context.Context, in *ListSchemasRequest, opts ...grpc.CallOption) (*ListSchemasResponse, error)
+}
+
+type schemaRegistryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSchemaRegistryClient(cc grpc.ClientConnInterface) SchemaRegistryClient {
+	return &schemaRegistryClient{cc}
+}
+
+func (c *schemaRegistryClient) ListSchemas(ctx context.Context, in *ListSchemasRequest, opts ...grpc.CallOption) (*ListSchemasResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSchemasResponse)
+	err := c.cc.Invoke(ctx, SchemaRegistry_ListSchemas_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SchemaRegistryServer is the server API for SchemaRegistry service.
+// All implementations should embed UnimplementedSchemaRegistryServer
+// for forward compatibility.
+//
+// SchemaRegistry advertises the types of the messages a publisher publishes on its subjects, so that subscribers
+// can decode them without compiling the Protobuf files in.
+type SchemaRegistryServer interface {
+	// ListSchemas returns the schemas of the subjects matching the request.
+	ListSchemas(context.Context, *ListSchemasRequest) (*ListSchemasResponse, error)
+}
+
+// UnimplementedSchemaRegistryServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSchemaRegistryServer struct{}
+
+func (UnimplementedSchemaRegistryServer) ListSchemas(context.Context, *ListSchemasRequest) (*ListSchemasResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSchemas not implemented")
+}
+func (UnimplementedSchemaRegistryServer) testEmbeddedByValue() {}
+
+// UnsafeSchemaRegistryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SchemaRegistryServer will
+// result in compilation errors.
+type UnsafeSchemaRegistryServer interface {
+	mustEmbedUnimplementedSchemaRegistryServer()
+}
+
+func RegisterSchemaRegistryServer(s grpc.ServiceRegistrar, srv SchemaRegistryServer) {
+	// If the following call pancis, it indicates UnimplementedSchemaRegistryServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SchemaRegistry_ServiceDesc, srv)
+}
+
+func _SchemaRegistry_ListSchemas_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSchemasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchemaRegistryServer).ListSchemas(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SchemaRegistry_ListSchemas_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchemaRegistryServer).ListSchemas(ctx, req.(*ListSchemasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SchemaRegistry_ServiceDesc is the grpc.ServiceDesc for SchemaRegistry service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SchemaRegistry_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "synternet.rpc.SchemaRegistry",
+	HandlerType: (*SchemaRegistryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListSchemas",
+			Handler:    _SchemaRegistry_ListSchemas_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "synternet/rpc/schema.proto",
+}